@@ -0,0 +1,313 @@
+package fastcache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Item is a single key-value pair with its own TTL for batch operations.
+// A TTL of 0 means the cache's DefaultTTL is used.
+type Item struct {
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+// groupByShard groups indexes into keys by the shard they map to in layout
+func (c *Cache) groupByShard(layout *shardLayout, indexes []int, keyAt func(i int) string) map[*Shard][]int {
+	groups := make(map[*Shard][]int)
+	for _, i := range indexes {
+		shard := layout.shards[c.shardIndexIn(keyAt(i), len(layout.shards))]
+		groups[shard] = append(groups[shard], i)
+	}
+	return groups
+}
+
+// seq returns the indexes 0..n-1
+func seq(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+// SetItems stores multiple items, each with its own TTL. Items are grouped by
+// shard so each shard lock is taken once per batch. If an item is rejected
+// under RejectOnFull, the remaining items are still stored and the first
+// error is returned.
+func (c *Cache) SetItems(items []Item) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("set_items", "", ErrCacheClosed)
+	}
+
+	// Validate keys and prepare values before taking any shard locks
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		if err := c.validateKey(item.Key); err != nil {
+			return opError("set_items", item.Key, err)
+		}
+		value, err := c.prepareValue(item.Value)
+		if err != nil {
+			return opError("set_items", item.Key, err)
+		}
+		values[i] = value
+	}
+
+	grew := false
+	var firstErr error
+	pending := seq(len(items))
+	for len(pending) > 0 {
+		layout := c.currentLayout()
+		groups := c.groupByShard(layout, pending, func(i int) string { return items[i].Key })
+
+		// Groups whose keys were moved by a concurrent ReshardTo are regrouped
+		pending = pending[:0]
+		for shard, indexes := range groups {
+			shard.mu.Lock()
+			if c.currentLayout() != layout {
+				shard.mu.Unlock()
+				pending = append(pending, indexes...)
+				continue
+			}
+			for _, i := range indexes {
+				item := items[i]
+				sizeDiff, err := c.setLocked(shard, item.Key, values[i], c.expiryFor(item.TTL))
+				if err != nil && firstErr == nil {
+					firstErr = opError("set_items", item.Key, err)
+				}
+				if sizeDiff > 0 {
+					grew = true
+				}
+			}
+			shard.mu.Unlock()
+		}
+	}
+
+	// Trigger eviction once for the whole batch (outside of locks)
+	if grew {
+		c.triggerEviction()
+	}
+	return firstErr
+}
+
+// SetBatch stores every item in items with the same optional TTL and returns
+// the keys that could not be stored, mapped to their errors. An empty map
+// means every item was stored. Unlike SetItems, a failing item never hides
+// the outcome of the others. Items are grouped by shard so each shard lock is
+// taken once per batch; if the cache is closed partway through, the items not
+// yet written fail with ErrCacheClosed.
+func (c *Cache) SetBatch(items map[string]interface{}, ttl ...time.Duration) map[string]error {
+	failed := make(map[string]error)
+	expiry := c.expiryFor(firstTTL(ttl))
+
+	// Validate keys and prepare values before taking any shard locks
+	keys := make([]string, 0, len(items))
+	values := make(map[string]interface{}, len(items))
+	for key, item := range items {
+		if err := c.validateKey(key); err != nil {
+			failed[key] = opError("set_batch", key, err)
+			continue
+		}
+		value, err := c.prepareValue(item)
+		if err != nil {
+			failed[key] = opError("set_batch", key, err)
+			continue
+		}
+		keys = append(keys, key)
+		values[key] = value
+	}
+
+	grew := false
+	pending := seq(len(keys))
+	for len(pending) > 0 {
+		layout := c.currentLayout()
+		groups := c.groupByShard(layout, pending, func(i int) string { return keys[i] })
+
+		// Groups whose keys were moved by a concurrent ReshardTo are regrouped
+		pending = pending[:0]
+		for shard, indexes := range groups {
+			if atomic.LoadInt32(&c.closed) == 1 {
+				for _, i := range indexes {
+					failed[keys[i]] = opError("set_batch", keys[i], ErrCacheClosed)
+				}
+				continue
+			}
+
+			shard.mu.Lock()
+			if c.currentLayout() != layout {
+				shard.mu.Unlock()
+				pending = append(pending, indexes...)
+				continue
+			}
+			for _, i := range indexes {
+				key := keys[i]
+				sizeDiff, err := c.setLocked(shard, key, values[key], expiry)
+				if err != nil {
+					failed[key] = opError("set_batch", key, err)
+				}
+				if sizeDiff > 0 {
+					grew = true
+				}
+			}
+			shard.mu.Unlock()
+		}
+	}
+
+	// Trigger eviction once for the whole batch (outside of locks)
+	if grew {
+		c.triggerEviction()
+	}
+	return failed
+}
+
+// WarmUp pre-populates the cache using concurrency workers that pull items from
+// produce until it returns ok=false or ctx is canceled. Calls to produce are
+// serialized, so it does not need to be goroutine-safe, and none are made once
+// ctx is done; items already produced are stored and left in place. It returns
+// ctx.Err() if the context was canceled, or the first Set error encountered.
+func (c *Cache) WarmUp(ctx context.Context, concurrency int, produce func() (key string, value interface{}, ttl time.Duration, ok bool)) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		produceMu sync.Mutex
+		done      bool
+		errOnce   sync.Once
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+
+	next := func() (string, interface{}, time.Duration, bool) {
+		produceMu.Lock()
+		defer produceMu.Unlock()
+		// Workers waiting here when ctx is canceled must not produce more
+		if done || ctx.Err() != nil {
+			return "", nil, 0, false
+		}
+		key, value, ttl, ok := produce()
+		if !ok {
+			done = true
+		}
+		return key, value, ttl, ok
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				key, value, ttl, ok := next()
+				if !ok {
+					return
+				}
+				if err := c.Set(key, value, ttl); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// GetMulti retrieves multiple keys at once, returning only the keys that were
+// found. Keys are grouped by shard so each shard lock is taken once per batch.
+// Each key is otherwise handled like Get, including RefreshOnReadThreshold.
+func (c *Cache) GetMulti(keys []string) map[string]interface{} {
+	results, _, _ := c.GetMultiStats(keys)
+	return results
+}
+
+// GetMultiStats is GetMulti that also reports how many of the requested keys
+// hit and missed in this batch. Global hit/miss counters are updated as usual.
+func (c *Cache) GetMultiStats(keys []string) (results map[string]interface{}, hits int, misses int) {
+	results = make(map[string]interface{}, len(keys))
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return results, 0, len(keys)
+	}
+
+	entries := make([]*Entry, len(keys))
+	payloads := make([]payload, len(keys))
+	expiries := make([]int64, len(keys))
+	lifetimes := make([]int64, len(keys))
+	pending := seq(len(keys))
+	for len(pending) > 0 {
+		layout := c.currentLayout()
+		groups := c.groupByShard(layout, pending, func(i int) string { return keys[i] })
+
+		// Groups whose keys were moved by a concurrent ReshardTo are regrouped
+		pending = pending[:0]
+		for shard, indexes := range groups {
+			shard.mu.RLock()
+			if c.currentLayout() != layout {
+				shard.mu.RUnlock()
+				pending = append(pending, indexes...)
+				continue
+			}
+			for _, i := range indexes {
+				shard.touch(keys[i])
+				if entry, exists := shard.data[keys[i]]; exists {
+					entries[i] = entry
+					payloads[i] = entry.payload
+					expiries[i], lifetimes[i] = entry.expiry, entry.lifetime
+				}
+			}
+			shard.mu.RUnlock()
+
+			var promote []*Entry
+			for _, i := range indexes {
+				key := keys[i]
+				entry := entries[i]
+				if entry != nil && !c.accessEntry(shard, entry, expiries[i], lifetimes[i], c.now()) {
+					entry = nil
+				}
+
+				if entry == nil {
+					if value, ok := c.miss(shard, key); ok {
+						results[key] = value
+						hits++
+					} else {
+						misses++
+					}
+					continue
+				}
+
+				value, ok := c.loadValue(payloads[i].get())
+				if !ok {
+					c.recordMiss(shard, key)
+					misses++
+					continue
+				}
+
+				c.recordHit(shard, key)
+				results[key] = value
+				hits++
+				if c.config.EvictionPolicy.promotesOnAccess() && c.shouldPromote(entry) {
+					promote = append(promote, entry)
+				}
+			}
+
+			if len(promote) > 0 {
+				shard.mu.Lock()
+				for _, entry := range promote {
+					if shard.data[entry.key] == entry {
+						shard.promote(entry)
+					}
+				}
+				shard.mu.Unlock()
+			}
+		}
+	}
+
+	return results, hits, misses
+}
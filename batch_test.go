@@ -0,0 +1,260 @@
+package fastcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetItems(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	cache := New(config)
+	defer cache.Close()
+
+	items := []Item{
+		{Key: "token:short", Value: "a", TTL: 50 * time.Millisecond},
+		{Key: "token:medium", Value: "b", TTL: 150 * time.Millisecond},
+		{Key: "token:forever", Value: "c"},
+	}
+	for i := 0; i < 100; i++ {
+		items = append(items, Item{Key: fmt.Sprintf("bulk_%d", i), Value: i})
+	}
+
+	if err := cache.SetItems(items); err != nil {
+		t.Fatalf("SetItems failed: %v", err)
+	}
+
+	if stats := cache.GetStats(); stats.TotalEntries != int64(len(items)) {
+		t.Fatalf("Expected %d entries, got %d", len(items), stats.TotalEntries)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, exists := cache.Get("token:short"); exists {
+		t.Error("token:short should have expired")
+	}
+	if _, exists := cache.Get("token:medium"); !exists {
+		t.Error("token:medium should not have expired yet")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, exists := cache.Get("token:medium"); exists {
+		t.Error("token:medium should have expired")
+	}
+	if value, exists := cache.Get("token:forever"); !exists || value.(string) != "c" {
+		t.Errorf("token:forever should not expire, got %v, %v", value, exists)
+	}
+}
+
+func TestSetItemsClosed(t *testing.T) {
+	cache := New(DefaultConfig())
+	cache.Close()
+
+	err := cache.SetItems([]Item{{Key: "k", Value: "v"}})
+	var opErr ErrOperationFailed
+	if !errors.Is(err, ErrCacheClosed) || !errors.As(err, &opErr) || opErr.Operation != "set_items" {
+		t.Errorf("Expected set_items ErrCacheClosed, got %v", err)
+	}
+}
+
+func TestSetBatch(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxEntrySize = 1024
+	cache := New(config)
+	defer cache.Close()
+
+	items := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("bulk_%d", i)] = i
+	}
+	items["oversized"] = strings.Repeat("x", 2048)
+
+	failed := cache.SetBatch(items, time.Minute)
+	if len(failed) != 1 {
+		t.Fatalf("Expected only the oversized key to fail, got %v", failed)
+	}
+	var tooLarge ErrEntryTooLarge
+	if err := failed["oversized"]; !errors.As(err, &tooLarge) || tooLarge.Max != config.MaxEntrySize {
+		t.Errorf("Expected ErrEntryTooLarge for oversized, got %v", err)
+	}
+
+	if stats := cache.GetStats(); stats.TotalEntries != 100 {
+		t.Errorf("Expected 100 entries stored, got %d", stats.TotalEntries)
+	}
+	if _, exists := cache.Get("oversized"); exists {
+		t.Error("Expected oversized item not to be stored")
+	}
+	if err := cache.Set("oversized", items["oversized"]); !errors.As(err, &tooLarge) {
+		t.Errorf("Expected Set to enforce MaxEntrySize too, got %v", err)
+	}
+
+	cache.Close()
+	failed = cache.SetBatch(map[string]interface{}{"a": 1, "b": 2})
+	if len(failed) != 2 || !errors.Is(failed["a"], ErrCacheClosed) {
+		t.Errorf("Expected every key to fail with ErrCacheClosed, got %v", failed)
+	}
+}
+
+func TestShardKeyFunc(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 64
+	config.ShardKeyFunc = func(key string) string {
+		if i := strings.LastIndexByte(key, ':'); i >= 0 {
+			return key[:i]
+		}
+		return key
+	}
+	cache := New(config)
+	defer cache.Close()
+
+	keys := []string{"order:42:items", "order:42:total", "order:42:status", "order:42:customer"}
+	for _, key := range keys {
+		_ = cache.Set(key, key)
+		if cache.ShardIndex(key) != cache.ShardIndex(keys[0]) {
+			t.Errorf("Expected %s on the same shard as %s", key, keys[0])
+		}
+	}
+
+	// Other orders still spread over the shards
+	shards := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		shards[cache.ShardIndex(fmt.Sprintf("order:%d:items", i))] = true
+	}
+	if len(shards) < 10 {
+		t.Errorf("Expected different orders to spread over shards, got %d shards", len(shards))
+	}
+
+	// GetMulti locks each group of keys sharing a shard once
+	groups := cache.groupByShard(cache.currentLayout(), seq(len(keys)), func(i int) string { return keys[i] })
+	if len(groups) != 1 {
+		t.Errorf("Expected a single shard lock for colocated keys, got %d", len(groups))
+	}
+	if results := cache.GetMulti(keys); len(results) != len(keys) {
+		t.Errorf("Expected %d results, got %d", len(keys), len(results))
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	const total = 10000
+	i := 0
+	produce := func() (string, interface{}, time.Duration, bool) {
+		if i >= total {
+			return "", nil, 0, false
+		}
+		key := fmt.Sprintf("warm_key_%d", i)
+		i++
+		return key, i, 0, true
+	}
+
+	if err := cache.WarmUp(context.Background(), 8, produce); err != nil {
+		t.Fatalf("WarmUp failed: %v", err)
+	}
+
+	for j := 0; j < total; j++ {
+		if _, exists := cache.Get(fmt.Sprintf("warm_key_%d", j)); !exists {
+			t.Fatalf("warm_key_%d not found after WarmUp", j)
+		}
+	}
+}
+
+func TestWarmUpCanceled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	produced := 0
+	produce := func() (string, interface{}, time.Duration, bool) {
+		produced++
+		if produced == 100 {
+			cancel()
+		}
+		return fmt.Sprintf("endless_key_%d", produced), produced, 0, true
+	}
+
+	err := cache.WarmUp(ctx, 8, produce)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	if produced != 100 {
+		t.Errorf("WarmUp kept producing after cancellation: %d items", produced)
+	}
+	if n := cache.Len(); n != produced {
+		t.Errorf("Expected the %d produced items to stay loaded, got %d", produced, n)
+	}
+}
+
+func TestGetMultiStats(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("present_%d", i), i)
+	}
+	cache.Set("expiring", "gone", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	keys := []string{"expiring"}
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("present_%d", i), fmt.Sprintf("absent_%d", i))
+	}
+
+	before := cache.GetStats()
+	results, hits, misses := cache.GetMultiStats(keys)
+
+	if hits != 10 || misses != 11 {
+		t.Errorf("Expected 10 hits and 11 misses, got %d and %d", hits, misses)
+	}
+	if len(results) != 10 {
+		t.Fatalf("Expected 10 results, got %d", len(results))
+	}
+	for i := 0; i < 10; i++ {
+		if value := results[fmt.Sprintf("present_%d", i)]; value != i {
+			t.Errorf("Expected present_%d=%d, got %v", i, i, value)
+		}
+	}
+
+	after := cache.GetStats()
+	if after.HitCount-before.HitCount != 10 || after.MissCount-before.MissCount != 11 {
+		t.Errorf("Global counters should advance by the batch split, got +%d hits, +%d misses",
+			after.HitCount-before.HitCount, after.MissCount-before.MissCount)
+	}
+
+	if results := cache.GetMulti([]string{"present_1", "absent_1"}); len(results) != 1 || results["present_1"] != 1 {
+		t.Errorf("GetMulti returned %v", results)
+	}
+}
+
+func TestGetMultiRefreshOnRead(t *testing.T) {
+	config := DefaultConfig()
+	config.RefreshOnReadThreshold = 0.25
+	config.DisableBackgroundCleanup = true
+	cache, clock := newWithFakeClock(config)
+	defer cache.Close()
+
+	_ = cache.Set("hot", "value", time.Minute)
+	_ = cache.Set("cold", "value", time.Minute)
+
+	// A batch read in the last quarter of the lifetime extends it like Get
+	clock.Advance(50 * time.Second)
+	if results := cache.GetMulti([]string{"hot"}); len(results) != 1 {
+		t.Fatalf("Expected hot entry before its TTL, got %v", results)
+	}
+
+	clock.Advance(20 * time.Second)
+	results := cache.GetMulti([]string{"hot", "cold"})
+	if _, ok := results["hot"]; !ok {
+		t.Error("Expected hot entry to survive past its original TTL")
+	}
+	if _, ok := results["cold"]; ok {
+		t.Error("Expected cold entry to expire at its original TTL")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -368,3 +369,437 @@ func BenchmarkQPS(b *testing.B) {
 	qps := float64(b.N) / elapsed.Seconds()
 	b.ReportMetric(qps, "ops/sec")
 }
+
+// Benchmark Set latency under memory pressure with inline vs async eviction
+func BenchmarkEvictionInline(b *testing.B) {
+	benchmarkEvictionMode(b, false)
+}
+
+func BenchmarkEvictionAsync(b *testing.B) {
+	benchmarkEvictionMode(b, true)
+}
+
+func benchmarkEvictionMode(b *testing.B, async bool) {
+	config := &Config{
+		MaxMemoryBytes:           1024 * 1024, // 1MB limit
+		ShardCount:               64,
+		DefaultTTL:               0,
+		CleanupInterval:          time.Second,
+		AsyncEviction:            async,
+		EvictionOvershootPercent: 25,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	// Fill cache to trigger eviction
+	largeValue := make([]byte, 512)
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("evict_key_%d", i)
+		_ = cache.Set(key, largeValue)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := fmt.Sprintf("pressure_key_%d_%d", rand.Int63(), i)
+			_ = cache.Set(key, largeValue)
+			i++
+		}
+	})
+}
+
+// Benchmark write throughput under eviction for each policy
+func BenchmarkPolicyLRU(b *testing.B) {
+	benchmarkPolicy(b, PolicyLRU)
+}
+
+func BenchmarkPolicyRandom(b *testing.B) {
+	benchmarkPolicy(b, PolicyRandom)
+}
+
+func BenchmarkPolicyTinyLFU(b *testing.B) {
+	benchmarkPolicy(b, PolicyTinyLFU)
+}
+
+func benchmarkPolicy(b *testing.B, policy EvictionPolicy) {
+	config := &Config{
+		MaxMemoryBytes:  4 * 1024 * 1024, // 4MB limit
+		ShardCount:      256,
+		DefaultTTL:      0,
+		CleanupInterval: time.Minute,
+		EvictionPolicy:  policy,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := fmt.Sprintf("policy_key_%d_%d", rand.Int63(), i)
+			_ = cache.Set(key, key)
+			i++
+		}
+	})
+}
+
+// Benchmark read-heavy traffic with strict vs lazy LRU promotion
+func BenchmarkReadHeavyStrictLRU(b *testing.B) {
+	benchmarkReadHeavy(b, 0)
+}
+
+func BenchmarkReadHeavyLazyLRU(b *testing.B) {
+	benchmarkReadHeavy(b, time.Second)
+}
+
+func benchmarkReadHeavy(b *testing.B, promotionInterval time.Duration) {
+	config := DefaultConfig()
+	config.LRUPromotionInterval = promotionInterval
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 10000; i++ {
+		_ = cache.Set(fmt.Sprintf("read_key_%d", i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if rand.Float32() < 0.05 {
+				key := fmt.Sprintf("read_key_%d", rand.Intn(10000))
+				_ = cache.Set(key, key)
+			} else {
+				key := fmt.Sprintf("read_key_%d", rand.Intn(10000))
+				cache.Get(key)
+			}
+		}
+	})
+}
+
+// Benchmark parallel Gets with cache-wide vs per-shard-only hit counters
+func BenchmarkGetParallelGlobalStats(b *testing.B) {
+	benchmarkGetStats(b, false)
+}
+
+func BenchmarkGetParallelShardedStats(b *testing.B) {
+	benchmarkGetStats(b, true)
+}
+
+func benchmarkGetStats(b *testing.B, shardedOnly bool) {
+	config := DefaultConfig()
+	config.LRUPromotionInterval = time.Second // keep promotion out of the measurement
+	config.ShardedStatsOnly = shardedOnly
+	cache := New(config)
+	defer cache.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("stats_key_%d", i)
+		_ = cache.Set(keys[i], i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.Intn(len(keys))
+		for pb.Next() {
+			cache.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+// Benchmark many goroutines Setting the same key and value, with and
+// without coalescing
+func BenchmarkSetSameKey(b *testing.B) {
+	benchmarkSetSameKey(b, false)
+}
+
+func BenchmarkSetSameKeyCoalesced(b *testing.B) {
+	benchmarkSetSameKey(b, true)
+}
+
+func benchmarkSetSameKey(b *testing.B, coalesce bool) {
+	config := DefaultConfig()
+	config.CoalesceSets = coalesce
+	cache := New(config)
+	defer cache.Close()
+
+	value := []byte("freshly computed value")
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cache.Set("hot_key", value)
+		}
+	})
+}
+
+// Benchmark the []byte fast path against the generic interface path
+func BenchmarkGetGenericBytes(b *testing.B) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bytes_key_%d", i)
+		_ = cache.Set(keys[i], []byte(keys[i]))
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			value, _ := cache.Get(keys[i%len(keys)])
+			_ = value.([]byte)
+			i++
+		}
+	})
+}
+
+func BenchmarkGetBytes(b *testing.B) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bytes_key_%d", i)
+		_ = cache.SetBytes(keys[i], []byte(keys[i]))
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = cache.GetBytes(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkSetGenericBytes(b *testing.B) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	value := []byte("bench_value")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cache.Set("bytes_key", value)
+	}
+}
+
+func BenchmarkSetBytes(b *testing.B) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	value := []byte("bench_value")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cache.SetBytes("bytes_key", value)
+	}
+}
+
+func BenchmarkPolicySizeAware(b *testing.B) {
+	benchmarkPolicy(b, PolicySizeAware)
+}
+
+// Benchmark clearing a large, full cache one shard at a time vs in parallel
+func BenchmarkClearSerial(b *testing.B) {
+	benchmarkClear(b, 1)
+}
+
+func BenchmarkClearParallel(b *testing.B) {
+	benchmarkClear(b, runtime.GOMAXPROCS(0))
+}
+
+func benchmarkClear(b *testing.B, workers int) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 1024 * 1024 * 1024 // 1GB, so nothing is evicted
+	config.ShardCount = 1024
+	cache := New(config)
+	defer cache.Close()
+
+	const entries = 200000
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("clear_key_%d", i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for _, key := range keys {
+			_ = cache.Set(key, i)
+		}
+		b.StartTimer()
+
+		cache.clearShards(workers)
+	}
+}
+
+// Benchmark reclaiming a cache full of expired entries with one cleanup
+// worker vs one per CPU
+func BenchmarkCleanupSerial(b *testing.B) {
+	benchmarkCleanup(b, 1)
+}
+
+func BenchmarkCleanupParallel(b *testing.B) {
+	benchmarkCleanup(b, runtime.GOMAXPROCS(0))
+}
+
+func benchmarkCleanup(b *testing.B, workers int) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 1024 * 1024 * 1024 // 1GB, so nothing is evicted
+	config.ShardCount = 2048
+	config.DisableBackgroundCleanup = true
+	cache := New(config)
+	defer cache.Close()
+
+	items := make([]Item, 200000)
+	for i := range items {
+		items[i] = Item{Key: fmt.Sprintf("churn_key_%d", i), Value: i, TTL: time.Millisecond}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		_ = cache.SetItems(items)
+		time.Sleep(2 * time.Millisecond)
+		b.StartTimer()
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				cache.cleanupStripe(w, workers, 0)
+			}(w)
+		}
+		wg.Wait()
+	}
+}
+
+// Benchmark loading a known number of entries into a fresh cache with and
+// without pre-sized shard maps
+func BenchmarkBulkLoad(b *testing.B) {
+	benchmarkBulkLoad(b, 0)
+}
+
+func BenchmarkBulkLoadPresized(b *testing.B) {
+	benchmarkBulkLoad(b, bulkLoadEntries)
+}
+
+const bulkLoadEntries = 100000
+
+func benchmarkBulkLoad(b *testing.B, expected int64) {
+	keys := make([]string, bulkLoadEntries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bulk_key_%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		config := DefaultConfig()
+		config.ShardCount = 64
+		config.ExpectedEntries = expected
+		config.DisableBackgroundCleanup = true
+		cache := New(config)
+		for _, key := range keys {
+			_ = cache.Set(key, i)
+		}
+		cache.Close()
+	}
+}
+
+// Benchmark reads by a composite struct key through KeyedCache vs formatting
+// the key into a string for Cache
+func BenchmarkStructKeySprintf(b *testing.B) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for i := 0; i < 10000; i++ {
+		_ = cache.Set(fmt.Sprintf("%d:%d", i%16, i), i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := userKey{tenant: uint32(i % 16), id: uint64(i % 10000)}
+			cache.Get(fmt.Sprintf("%d:%d", k.tenant, k.id))
+			i++
+		}
+	})
+}
+
+func BenchmarkStructKeyKeyed(b *testing.B) {
+	cache := NewKeyed[userKey, int](DefaultConfig(), hashUserKey)
+
+	for i := 0; i < 10000; i++ {
+		cache.Set(userKey{tenant: uint32(i % 16), id: uint64(i)}, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cache.Get(userKey{tenant: uint32(i % 16), id: uint64(i % 10000)})
+			i++
+		}
+	})
+}
+
+func BenchmarkPolicyFIFO(b *testing.B) {
+	benchmarkPolicy(b, PolicyFIFO)
+}
+
+func BenchmarkPolicyGDSF(b *testing.B) {
+	benchmarkPolicy(b, PolicyGDSF)
+}
+
+// sizedRecord is a struct value whose true size SizeSamplingRate measures
+type sizedRecord struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+}
+
+func newSizedRecord(i int) *sizedRecord {
+	return &sizedRecord{
+		Name:  fmt.Sprintf("record_%d", i),
+		Tags:  []string{"alpha", "beta", "gamma"},
+		Attrs: map[string]int{"x": i, "y": i * 2},
+	}
+}
+
+func BenchmarkSetSizeSampled(b *testing.B) {
+	benchmarkSetSizing(b, 0.01)
+}
+
+func BenchmarkSetSizeExact(b *testing.B) {
+	benchmarkSetSizing(b, 1)
+}
+
+func benchmarkSetSizing(b *testing.B, rate float64) {
+	config := DefaultConfig()
+	config.SizeSamplingRate = rate
+	cache := New(config)
+	defer cache.Close()
+
+	value := newSizedRecord(1)
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("record_key_%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cache.Set(keys[i%len(keys)], value)
+	}
+}
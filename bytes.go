@@ -0,0 +1,107 @@
+package fastcache
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// bytesType is the type of values stored by SetBytes
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// SetBytes stores a byte slice without boxing it in an interface. Size accounting
+// is exact for the key and value. Values stored this way are not compressed.
+func (c *Cache) SetBytes(key string, value []byte, ttl ...time.Duration) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("set_bytes", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return opError("set_bytes", key, err)
+	}
+	if c.config.RejectNilValues && value == nil {
+		return opError("set_bytes", key, ErrInvalidValue)
+	}
+	if err := c.checkValueType(bytesType); err != nil {
+		return opError("set_bytes", key, err)
+	}
+
+	expiry := c.expiryFor(firstTTL(ttl))
+	value = c.isolateBytes(value)
+	size := int64(len(key)+len(value)) + 64
+
+	shard := c.lockShard(key)
+	sizeDiff, err := c.putLocked(shard, key, payload{bytes: value, isBytes: true}, size, expiry)
+	queued := err == nil && c.queueSet(key, value, expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return opError("set_bytes", key, err)
+	}
+	if queued {
+		c.deliverReplication()
+	}
+
+	// Trigger eviction if needed (outside of lock to avoid deadlock)
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
+
+// GetBytes retrieves a byte slice without a type assertion. It also returns
+// []byte values stored through Set. Any other value type is reported as a miss.
+func (c *Cache) GetBytes(key string) ([]byte, bool) {
+	shard, entry, p := c.lookup(key)
+	if shard == nil {
+		return nil, false
+	}
+	if entry == nil {
+		value, ok := c.miss(shard, key)
+		b, isBytes := value.([]byte)
+		return b, ok && isBytes
+	}
+
+	if p.isBytes {
+		c.recordHit(shard, key)
+		return c.isolateBytes(p.bytes), true
+	}
+
+	value, ok := c.loadValue(p.value)
+	b, isBytes := value.([]byte)
+	if !ok || !isBytes {
+		c.recordMiss(shard, key)
+		return nil, false
+	}
+
+	c.recordHit(shard, key)
+	return b, true
+}
+
+// GetOrSetBytes returns the cached bytes for key, or calls loader to produce
+// them on a miss and stores them with the TTL it returns. Concurrent callers
+// for the same key share a single loader call. Loader errors are returned to
+// every waiting caller and never cached.
+func (c *Cache) GetOrSetBytes(key string, loader func() ([]byte, time.Duration, error)) ([]byte, error) {
+	if value, ok := c.GetBytes(key); ok {
+		return value, nil
+	}
+	if c.isClosed() {
+		return nil, opError("get_or_set_bytes", key, ErrCacheClosed)
+	}
+
+	value, _, err := c.byteFlights.do(key, func() (interface{}, bool, error) {
+		value, ttl, err := loader()
+		if err != nil {
+			return nil, false, err
+		}
+		if err := c.SetBytes(key, value, ttl); err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// With CopyValues, every waiter gets its own copy
+	return c.isolateBytes(value.([]byte)), nil
+}
@@ -0,0 +1,138 @@
+package fastcache
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetGetBytes(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	if err := cache.SetBytes("raw", []byte("payload")); err != nil {
+		t.Fatalf("SetBytes failed: %v", err)
+	}
+
+	b, exists := cache.GetBytes("raw")
+	if !exists || !bytes.Equal(b, []byte("payload")) {
+		t.Errorf("Expected 'payload', got %q, %v", b, exists)
+	}
+
+	// Bytes stored through SetBytes are visible through Get
+	value, exists := cache.Get("raw")
+	if !exists || !bytes.Equal(value.([]byte), []byte("payload")) {
+		t.Errorf("Expected Get to return 'payload', got %v, %v", value, exists)
+	}
+
+	// Bytes stored through Set are visible through GetBytes
+	_ = cache.Set("generic", []byte("boxed"))
+	if b, exists := cache.GetBytes("generic"); !exists || string(b) != "boxed" {
+		t.Errorf("Expected 'boxed', got %q, %v", b, exists)
+	}
+
+	// Non-byte values are misses for GetBytes
+	_ = cache.Set("string", "not bytes")
+	if _, exists := cache.GetBytes("string"); exists {
+		t.Error("Expected GetBytes miss for a string value")
+	}
+
+	// Exact size accounting: 3 (key) + 7 (value) + 64 (overhead)
+	if size := cache.getShard("raw").data["raw"].size; size != 74 {
+		t.Errorf("Expected size 74, got %d", size)
+	}
+
+	stats := cache.GetStats()
+	if stats.HitCount != 3 || stats.MissCount != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %d and %d", stats.HitCount, stats.MissCount)
+	}
+}
+
+func TestGetOrSetBytes(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	body := []byte("<html>origin response</html>")
+	var calls int32
+	loader := func() ([]byte, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond) // slow origin
+		return body, time.Minute, nil
+	}
+
+	const readers = 50
+	var wg sync.WaitGroup
+	results := make([][]byte, readers)
+	errs := make([]error, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.GetOrSetBytes("/index.html", loader)
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", n)
+	}
+	for i := 0; i < readers; i++ {
+		if errs[i] != nil || !bytes.Equal(results[i], body) {
+			t.Fatalf("Reader %d: expected %q, got %q (err=%v)", i, body, results[i], errs[i])
+		}
+	}
+
+	// Later reads are served from the cache
+	if got, err := cache.GetOrSetBytes("/index.html", loader); err != nil || !bytes.Equal(got, body) {
+		t.Errorf("Expected cached %q, got %q (err=%v)", body, got, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected a cached hit not to call the loader, got %d calls", n)
+	}
+}
+
+func TestGetOrSetBytesError(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	errOrigin := errors.New("origin down")
+	if _, err := cache.GetOrSetBytes("/down", func() ([]byte, time.Duration, error) {
+		return nil, 0, errOrigin
+	}); !errors.Is(err, errOrigin) {
+		t.Errorf("Expected loader error, got %v", err)
+	}
+
+	// Errors are not cached
+	got, err := cache.GetOrSetBytes("/down", func() ([]byte, time.Duration, error) {
+		return []byte("recovered"), 0, nil
+	})
+	if err != nil || string(got) != "recovered" {
+		t.Errorf("Expected loader to be retried after an error, got %q (err=%v)", got, err)
+	}
+}
+
+func TestGetOrSetBytesPanic(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected the loader panic to propagate")
+			}
+		}()
+		_, _ = cache.GetOrSetBytes("key", func() ([]byte, time.Duration, error) {
+			panic("backend exploded")
+		})
+	}()
+
+	value, err := cache.GetOrSetBytes("key", func() ([]byte, time.Duration, error) {
+		return []byte("recovered"), 0, nil
+	})
+	if err != nil || string(value) != "recovered" {
+		t.Errorf("Expected a fresh load after the panic, got %q, %v", value, err)
+	}
+}
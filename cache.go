@@ -4,25 +4,62 @@ package fastcache
 
 import (
 	"container/list"
+	crand "crypto/rand"
+	"encoding/binary"
 	"hash/fnv"
+	"reflect"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
+// payload holds an entry's stored value. Values set through SetBytes are kept
+// in bytes rather than value to avoid interface boxing.
+type payload struct {
+	value   interface{}
+	bytes   []byte
+	isBytes bool
+}
+
+// get returns the stored value as an interface
+func (p payload) get() interface{} {
+	if p.isBytes {
+		return p.bytes
+	}
+	return p.value
+}
+
 // Entry represents a single cache entry
 type Entry struct {
-	key      string
-	value    interface{}
+	key string
+	payload
 	size     int64
 	expiry   int64 // Unix timestamp in nanoseconds
+	lifetime int64 // TTL the entry was written with, in nanoseconds; set only for RefreshOnReadThreshold
+	written  int64 // Unix timestamp in nanoseconds of the last write; set only for TrackEntryAge
 	listNode *list.Element
+	tags     []string
+	indexes  map[string]string // index name -> value, set by SetWithIndex
+
+	probation bool // listNode is in the shard's probation list, for PolicyTinyLFU
+	pinned    bool // never chosen as an eviction victim; guarded by the shard lock
+
+	priority  float64 // GDSF priority, for PolicyGDSF
+	heapIndex int     // position in the shard's gdsfQueue, for PolicyGDSF
+
+	dirty uint64 // write sequence number not yet flushed by write-behind; zero when clean
+
+	lastPromoted int64 // Unix timestamp in nanoseconds, used by lazy LRU and EvictionSamples
+	accessCount  int64 // number of Get hits, updated atomically
+	lastAccess   int64 // Unix timestamp in nanoseconds of the last Get hit
 }
 
-// isExpired checks if the entry has expired
-func (e *Entry) isExpired() bool {
-	return e.expiry > 0 && time.Now().UnixNano() > e.expiry
+// isExpired checks if the entry has expired as of now, a Unix timestamp in
+// nanoseconds
+func (e *Entry) isExpired(now int64) bool {
+	return e.expiry > 0 && now > e.expiry
 }
 
 // Shard represents a single shard of the cache
@@ -33,223 +70,1106 @@ type Shard struct {
 	size      int64
 	hitCount  int64
 	missCount int64
+
+	// probation holds entries not yet accessed since insertion, and sketch
+	// tracks access frequencies; both are only used by PolicyTinyLFU
+	probation *list.List
+	sketch    *frequencySketch
+
+	// capacity is the initial size hint for data, from Config.ExpectedEntries
+	capacity int
+
+	// sizeWindow is how many of the least recently used entries PolicySizeAware
+	// compares when picking a victim; zero for other policies
+	sizeWindow int
+
+	// gdsf orders entries by priority; only used by PolicyGDSF
+	gdsf *gdsfQueue
 }
 
 // newShard creates a new shard
-func newShard() *Shard {
-	return &Shard{
-		data:    make(map[string]*Entry),
-		lruList: list.New(),
+func newShard(config *Config) *Shard {
+	capacity := 0
+	if config.ExpectedEntries > 0 {
+		capacity = int(config.ExpectedEntries / int64(config.ShardCount))
+	}
+	shard := &Shard{
+		data:     make(map[string]*Entry, capacity),
+		capacity: capacity,
+	}
+	if config.EvictionPolicy.usesList() {
+		shard.lruList = list.New()
+	}
+	if config.EvictionPolicy == PolicyTinyLFU {
+		shard.probation = list.New()
+		shard.sketch = newFrequencySketch(config.ShardCount)
+	}
+	if config.EvictionPolicy == PolicySizeAware {
+		shard.sizeWindow = sizeAwareWindow
+	}
+	if config.EvictionPolicy == PolicyGDSF {
+		shard.gdsf = &gdsfQueue{}
 	}
+	return shard
 }
 
 // Cache is the main cache structure
 type Cache struct {
-	config    *Config
-	shards    []*Shard
-	totalSize int64
-	totalHits int64
-	totalMiss int64
-	closed    int32
-	stopCh    chan struct{}
-	wg        sync.WaitGroup
+	config      *Config
+	clock       func() int64 // current time in Unix nanoseconds for expiry, wallClock outside tests
+	seed        uint32       // hash salt, HashSeed or processHashSeed
+	layout      atomic.Value // *shardLayout, replaced by ReshardTo
+	reshardMu   sync.Mutex   // serializes ReshardTo
+	totalSize   int64
+	totalHits   int64
+	totalMiss   int64
+	evictions   int64 // entries evicted under memory pressure
+	expirations int64 // expired entries removed
+	closed      int32
+	paused      int32           // PauseEviction calls not yet resumed
+	evictFrom   uint32          // rotating first shard of eviction passes
+	pressedAt   int64           // last OnMemoryPressure call, Unix nanoseconds
+	prefixes    sync.Map        // prefix -> *prefixCounter, when PrefixDelimiter is set
+	rates       *rateRing       // hit/miss samples, when EnableRateStats is set
+	latency     *latencyTracker // operation latencies, when TrackLatency is set
+	logs        *eventLogger    // rate-limited Logger, when Logger is set
+	filter      *missFilter     // keys ever written, when EnableMissFilter is set
+	sizes       *sizeSampler    // measured value sizes, when SizeSamplingRate is set
+	tags        tagIndex
+	indexes     valueIndex
+	pendingSets sync.Map    // key -> *pendingSet, when CoalesceSets is set
+	flights     flightGroup // GetOrCompute loads
+	byteFlights flightGroup // GetOrSetBytes loads
+	breaker     loaderBreaker
+	negatives   sync.Map // key -> negative-cache expiry for GetOrCompute
+	refreshes   sync.Map // key -> struct{}, GetStale refreshes in progress
+	keyLocks    [keyLockStripes]sync.Mutex
+	replicas    replicaSet
+	writeBehind int32  // running StartWriteBehind flushers
+	writeSeq    uint64 // last write sequence number handed out for write-behind
+	stopCh      chan struct{}
+	evictCh     chan struct{}
+	callbacks   chan evictedValue // queued OnEvict calls, when EvictCallbackAsync is set
+	spawnSlots  chan struct{}     // running spawned goroutines, when MaxBackgroundGoroutines is set
+	dropped     int64             // OnEvict calls dropped because callbacks was full
+	wg          sync.WaitGroup
+	startMu     sync.Mutex // orders StartWriteBehind and StartStatsReporter against Close
 }
 
-// New creates a new cache instance
+// Cacher is the core cache interface implemented by *Cache. Depend on it to
+// swap in fakes or alternative implementations in tests.
+type Cacher interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl ...time.Duration) error
+	Delete(key string) bool
+	Clear()
+	Close() error
+}
+
+var _ Cacher = (*Cache)(nil)
+
+// NewSized creates a cache limited to maxBytes, deriving the shard count from
+// the budget and the CPU count and using DefaultConfig for everything else.
+// A non-positive maxBytes uses DefaultConfig's budget.
+func NewSized(maxBytes int64) *Cache {
+	return New(SizedConfig(maxBytes))
+}
+
+// New creates a new cache instance. Missing or non-positive MaxMemoryBytes,
+// ShardCount and CleanupInterval fall back to DefaultConfig values; the
+// caller's config is not modified. Use NewWithError to reject such configs.
 func New(config *Config) *Cache {
+	return newCache(config, wallClock)
+}
+
+// wallClock returns the current wall-clock time in Unix nanoseconds
+func wallClock() int64 {
+	return time.Now().UnixNano()
+}
+
+// newCache implements New, reading the time for expiry from clock
+func newCache(config *Config, clock func() int64) *Cache {
 	if config == nil {
 		config = DefaultConfig()
 	}
+	config = config.withDefaults()
 
 	cache := &Cache{
 		config: config,
-		shards: make([]*Shard, config.ShardCount),
+		clock:  clock,
+		seed:   config.HashSeed,
 		stopCh: make(chan struct{}),
 	}
+	if cache.seed == 0 {
+		cache.seed = processHashSeed
+	}
 
 	// Initialize shards
-	for i := 0; i < config.ShardCount; i++ {
-		cache.shards[i] = newShard()
+	shards := make([]*Shard, config.ShardCount)
+	for i := range shards {
+		shards[i] = newShard(config)
 	}
+	cache.layout.Store(&shardLayout{shards: shards})
 
-	// Start background cleanup goroutine
-	cache.wg.Add(1)
-	go cache.cleanupRoutine()
+	if config.TrackLatency {
+		cache.latency = &latencyTracker{}
+	}
+	cache.logs = newEventLogger(config.Logger)
+	if config.EnableMissFilter {
+		cache.filter = newMissFilter(config.ExpectedEntries)
+	}
+	if config.SizeSamplingRate > 0 {
+		cache.sizes = newSizeSampler(config.SizeSamplingRate)
+	}
+
+	if config.MaxBackgroundGoroutines > 0 {
+		cache.spawnSlots = make(chan struct{}, config.MaxBackgroundGoroutines)
+	}
+
+	// Start background cleanup goroutines
+	if !config.DisableBackgroundCleanup {
+		workers := config.CleanupParallelism
+		if workers < 1 {
+			workers = 1
+		}
+		for w := 0; w < workers; w++ {
+			cache.wg.Add(1)
+			go cache.cleanupRoutine(w, workers)
+		}
+	}
+
+	// Start background rate sampling goroutine
+	if config.EnableRateStats {
+		cache.rates = newRateRing()
+		cache.sampleRates() // a baseline for rates read before the first tick
+		cache.wg.Add(1)
+		go cache.rateSampleRoutine()
+	}
+
+	// Start background eviction goroutine
+	if config.AsyncEviction {
+		cache.evictCh = make(chan struct{}, 1)
+		cache.wg.Add(1)
+		go cache.evictionRoutine()
+	}
+
+	// Start background eviction callback goroutine
+	if config.EvictCallbackAsync && config.OnEvict != nil {
+		cache.callbacks = make(chan evictedValue, evictCallbackQueueSize)
+		cache.wg.Add(1)
+		go cache.evictCallbackRoutine()
+	}
 
 	return cache
 }
 
-// hash returns the hash of a key
+// NewWithError creates a new cache instance after validating config, returning
+// ErrInvalidConfig instead of substituting defaults for missing fields
+func NewWithError(config *Config) (*Cache, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return New(config), nil
+}
+
+// processHashSeed salts the key hash of caches without a HashSeed, so shard
+// placement cannot be predicted from outside the process
+var processHashSeed = newHashSeed()
+
+// newHashSeed returns a random non-zero hash seed
+func newHashSeed() uint32 {
+	var b [4]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		binary.LittleEndian.PutUint32(b[:], uint32(time.Now().UnixNano()))
+	}
+	if seed := binary.LittleEndian.Uint32(b[:]); seed != 0 {
+		return seed
+	}
+	return 1
+}
+
+// hash returns the hash of a key, salted with the cache's seed. The low bits
+// of FNV-1a depend only on the low bits of its state, so shard indexes taken
+// from them would vary with only a few bits of the seed; the murmur3
+// finalizer mixes every bit of the salted hash into them, keeping the
+// distribution even.
 func (c *Cache) hash(key string) uint32 {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], c.seed)
 	h := fnv.New32a()
+	h.Write(b[:])
 	h.Write([]byte(key))
-	return h.Sum32()
+
+	x := h.Sum32()
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
+
+// shardLayout is the set of shards keys are currently distributed over
+type shardLayout struct {
+	shards []*Shard
+}
+
+// currentLayout returns the active shard layout
+func (c *Cache) currentLayout() *shardLayout {
+	return c.layout.Load().(*shardLayout)
+}
+
+// allShards returns the active shards
+func (c *Cache) allShards() []*Shard {
+	return c.currentLayout().shards
 }
 
 // getShard returns the appropriate shard for a key
 func (c *Cache) getShard(key string) *Shard {
-	return c.shards[c.hash(key)%uint32(c.config.ShardCount)]
+	shards := c.allShards()
+	return shards[c.shardIndexIn(key, len(shards))]
+}
+
+// ShardIndex returns the index of the shard key maps to. Placement is stable
+// for a given shard count within a process, and across processes for a given
+// HashSeed.
+func (c *Cache) ShardIndex(key string) int {
+	return c.shardIndexIn(key, len(c.allShards()))
+}
+
+// shardIndexIn returns the index key maps to among n shards
+func (c *Cache) shardIndexIn(key string, n int) int {
+	if c.config.ShardKeyFunc != nil {
+		key = c.config.ShardKeyFunc(key)
+	}
+	if c.config.ConsistentHashing {
+		return jumpHash(uint64(c.hash(key)), n)
+	}
+	return int(c.hash(key) % uint32(n))
+}
+
+// lockShard write-locks and returns the shard for key, retrying if ReshardTo
+// moved the key while waiting for the lock
+func (c *Cache) lockShard(key string) *Shard {
+	for {
+		layout := c.currentLayout()
+		shard := layout.shards[c.shardIndexIn(key, len(layout.shards))]
+		shard.mu.Lock()
+		if c.currentLayout() == layout {
+			return shard
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// rlockShard read-locks and returns the shard for key, retrying if ReshardTo
+// moved the key while waiting for the lock
+func (c *Cache) rlockShard(key string) *Shard {
+	for {
+		layout := c.currentLayout()
+		shard := layout.shards[c.shardIndexIn(key, len(layout.shards))]
+		shard.mu.RLock()
+		if c.currentLayout() == layout {
+			return shard
+		}
+		shard.mu.RUnlock()
+	}
 }
 
 // calculateSize estimates the memory size of a key-value pair
 func calculateSize(key string, value interface{}) int64 {
-	size := int64(len(key))
+	// Add overhead for Entry struct and list node
+	return int64(len(key)) + valueSize(value) + 64
+}
 
+// valueSize estimates the memory used by a value
+func valueSize(value interface{}) int64 {
 	switch v := value.(type) {
 	case string:
-		size += int64(len(v))
+		return int64(len(v))
 	case []byte:
-		size += int64(len(v))
+		return int64(len(v))
+	case *compressedValue:
+		return int64(len(v.data))
+	case *encodedValue:
+		return v.size()
 	case int, int32, int64, uint, uint32, uint64:
-		size += 8
+		return 8
 	case float32, float64:
-		size += 8
+		return 8
 	case bool:
-		size += 1
+		return 1
+	case []interface{}:
+		// Charge lists, such as those built by Append, for their items
+		size := int64(unsafe.Sizeof(v))
+		for _, item := range v {
+			size += int64(unsafe.Sizeof(item)) + valueSize(item)
+		}
+		return size
 	default:
 		// Rough estimate for other types
-		size += int64(unsafe.Sizeof(v))
+		return int64(unsafe.Sizeof(v))
 	}
-
-	// Add overhead for Entry struct and list node
-	size += 64
-
-	return size
 }
 
 // Set stores a key-value pair with optional TTL
 func (c *Cache) Set(key string, value interface{}, ttl ...time.Duration) error {
+	if c.config.CoalesceSets {
+		return c.coalesceSet(key, value, firstTTL(ttl))
+	}
+	return c.setTTL(key, value, firstTTL(ttl))
+}
+
+// setTTL implements Set
+func (c *Cache) setTTL(key string, value interface{}, ttl time.Duration) error {
+	_, err := c.set("set", key, value, c.expiryFor(ttl))
+	return err
+}
+
+// SetReport is like Set but also returns how many entries were evicted to
+// make room for the write, so callers can detect an undersized cache where
+// they write. With AsyncEviction, eviction left to the background goroutine
+// is not counted.
+func (c *Cache) SetReport(key string, value interface{}, ttl ...time.Duration) (evicted int, err error) {
+	return c.set("set_report", key, value, c.expiryFor(firstTTL(ttl)))
+}
+
+// SetExpireAt stores a key-value pair that expires at the absolute time at,
+// e.g. an hour boundary shared by several nodes, rather than after a TTL.
+// MaxTTL still caps how far ahead at may be. An at that is not in the future
+// is treated as immediate expiry: nothing is stored and any existing entry
+// for key is removed.
+func (c *Cache) SetExpireAt(key string, value interface{}, at time.Time) error {
+	if at.UnixNano() <= c.now() {
+		if c.isClosed() {
+			return opError("set_expire_at", key, ErrCacheClosed)
+		}
+		if err := c.validateKey(key); err != nil {
+			return opError("set_expire_at", key, err)
+		}
+		c.Delete(key)
+		return nil
+	}
+	_, err := c.set("set_expire_at", key, value, c.clampExpiry(at.UnixNano()))
+	return err
+}
+
+// set implements Set, SetReport and SetExpireAt, returning the number of
+// entries evicted inline for the write
+func (c *Cache) set(op, key string, value interface{}, expiry int64) (int, error) {
+	return c.setWith(op, key, value, expiry, true, nil)
+}
+
+// setWith implements set, forwarding the write to replicas if replicate is
+// set and calling beforeWrite, if not nil, with the shard write lock held
+// just before the value is written
+func (c *Cache) setWith(op, key string, value interface{}, expiry int64, replicate bool, beforeWrite func()) (int, error) {
+	if c.latency != nil {
+		defer c.latency.set.observe(time.Now())
+	}
 	if atomic.LoadInt32(&c.closed) == 1 {
-		return ErrCacheClosed
+		return 0, opError(op, key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return 0, opError(op, key, err)
 	}
 
-	shard := c.getShard(key)
-	size := calculateSize(key, value)
+	prepared, err := c.prepareValue(value)
+	if err != nil {
+		return 0, opError(op, key, err)
+	}
 
-	var expiry int64
-	if len(ttl) > 0 && ttl[0] > 0 {
-		expiry = time.Now().Add(ttl[0]).UnixNano()
-	} else if c.config.DefaultTTL > 0 {
-		expiry = time.Now().Add(c.config.DefaultTTL).UnixNano()
+	shard := c.lockShard(key)
+	if beforeWrite != nil {
+		beforeWrite()
+	}
+	sizeDiff, err := c.setLocked(shard, key, prepared, expiry)
+	queued := err == nil && replicate && c.queueSet(key, value, expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return 0, opError(op, key, err)
+	}
+	if queued {
+		c.deliverReplication()
 	}
 
-	shard.mu.Lock()
+	// Trigger eviction if needed (outside of lock to avoid deadlock)
+	if sizeDiff > 0 {
+		return c.triggerEviction(), nil
+	}
+	return 0, nil
+}
+
+// firstTTL returns the optional TTL argument, or 0 when none was given
+func firstTTL(ttl []time.Duration) time.Duration {
+	if len(ttl) > 0 {
+		return ttl[0]
+	}
+	return 0
+}
+
+// expiryFor returns the absolute expiry for a TTL, falling back to DefaultTTL
+// when ttl is not positive and clamping to MaxTTL. Zero means the entry never
+// expires.
+func (c *Cache) expiryFor(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+	if limit := c.config.MaxTTL; limit > 0 && (ttl <= 0 || ttl > limit) {
+		ttl = limit
+	}
+	if ttl > 0 {
+		return c.now() + int64(ttl)
+	}
+	return 0
+}
+
+// clampExpiry limits an absolute expiry to MaxTTL from now
+func (c *Cache) clampExpiry(expiry int64) int64 {
+	if c.config.MaxTTL <= 0 {
+		return expiry
+	}
+	limit := c.now() + int64(c.config.MaxTTL)
+	if expiry == 0 || expiry > limit {
+		return limit
+	}
+	return expiry
+}
+
+// isolate returns a private copy of byte-slice values when CopyValues is enabled
+func (c *Cache) isolate(value interface{}) interface{} {
+	if !c.config.CopyValues {
+		return value
+	}
+	if b, ok := value.([]byte); ok {
+		return c.isolateBytes(b)
+	}
+	return value
+}
+
+// isolateBytes returns a private copy of b when CopyValues is enabled
+func (c *Cache) isolateBytes(b []byte) []byte {
+	if !c.config.CopyValues || b == nil {
+		return b
+	}
+	dup := make([]byte, len(b))
+	copy(dup, b)
+	return dup
+}
+
+// prepareValue converts a caller's value into its stored form. It is called
+// before taking the shard lock since encoding, copying and compressing can be
+// expensive. It only fails if the configured Codec cannot encode the value.
+func (c *Cache) prepareValue(value interface{}) (interface{}, error) {
+	if c.config.RejectNilValues && isNil(value) {
+		return nil, ErrInvalidValue
+	}
+	if err := c.checkValueType(reflect.TypeOf(value)); err != nil {
+		return nil, err
+	}
+	if c.config.Codec != nil {
+		return c.encode(value)
+	}
+	return c.compress(c.isolate(value)), nil
+}
+
+// isNil reports whether value is nil or a typed nil
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// validateKey rejects empty keys and keys longer than MaxKeyLength
+func (c *Cache) validateKey(key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	if limit := c.config.MaxKeyLength; limit > 0 && len(key) > limit {
+		return ErrKeyTooLong{Length: len(key), Max: limit}
+	}
+	return nil
+}
+
+// checkValueType returns ErrValueType if values of type t may not be stored
+func (c *Cache) checkValueType(t reflect.Type) error {
+	expected := c.config.ValueType
+	if expected == nil || (t != nil && t.AssignableTo(expected)) {
+		return nil
+	}
+	return ErrValueType{Expected: expected, Actual: t}
+}
+
+// loadValue converts a stored value back into the form returned to callers
+func (c *Cache) loadValue(value interface{}) (interface{}, bool) {
+	if ev, ok := value.(*encodedValue); ok {
+		// Decoding always produces a fresh copy
+		return c.decode(ev)
+	}
+	if _, ok := value.(*compressedValue); ok {
+		// Decompression always produces a fresh copy
+		return c.decompress(value)
+	}
+	return c.isolate(value), true
+}
+
+// setLocked inserts or updates an entry with an already prepared value and
+// returns the change in size. The caller must hold the shard's write lock.
+func (c *Cache) setLocked(shard *Shard, key string, value interface{}, expiry int64) (int64, error) {
+	return c.putLocked(shard, key, payload{value: value}, c.entrySize(key, value), expiry)
+}
+
+// putLocked inserts or updates an entry with the given payload and size and
+// returns the change in size. With RejectOnFull it returns
+// ErrMemoryLimitExceeded and leaves the entry untouched if the write would
+// exceed MaxMemoryBytes; entries over maxEntrySize fail with ErrEntryTooLarge.
+// The caller must hold the shard's write lock.
+func (c *Cache) putLocked(shard *Shard, key string, p payload, size int64, expiry int64) (int64, error) {
+	if limit := c.maxEntrySize(); size > limit {
+		return 0, ErrEntryTooLarge{Size: size, Max: limit}
+	}
+	shard.touch(key)
+	if c.filter != nil {
+		c.filter.add(key)
+	}
+	existing, exists := shard.data[key]
+
+	// An expired entry is replaced by a fresh insert rather than updated in place,
+	// so a pending lazy delete of the expired entry cannot remove the new value
+	if exists && existing.isExpired(c.now()) {
+		c.removeLocked(shard, existing)
+		atomic.AddInt64(&c.expirations, 1)
+		exists = false
+	}
 
 	// Check if key already exists
-	if existing, exists := shard.data[key]; exists {
+	if exists {
 		// Update existing entry
 		oldSize := existing.size
-		existing.value = value
+		sizeDiff := size - oldSize
+		if !c.reserve(sizeDiff) {
+			c.logRejected(key, sizeDiff)
+			return 0, ErrMemoryLimitExceeded
+		}
+
+		c.untag(existing)
+		c.unindex(existing)
+		existing.payload = p
 		existing.size = size
 		existing.expiry = expiry
+		existing.lifetime = c.lifetimeFor(expiry)
+		existing.written = c.writtenAt()
+		existing.dirty = c.nextDirty()
 
 		// Move to front of LRU list
-		shard.lruList.MoveToFront(existing.listNode)
+		if c.config.EvictionPolicy.promotesOnAccess() {
+			shard.promote(existing)
+			c.markPromoted(existing)
+		}
 
 		// Update size counters
-		sizeDiff := size - oldSize
-		atomic.AddInt64(&c.totalSize, sizeDiff)
 		atomic.AddInt64(&shard.size, sizeDiff)
+		return sizeDiff, nil
+	}
 
-		shard.mu.Unlock()
-
-		// Check for eviction after updating
-		if sizeDiff > 0 {
-			c.evictIfNeeded()
-		}
-		return nil
+	if !c.reserve(size) {
+		c.logRejected(key, size)
+		return 0, ErrMemoryLimitExceeded
 	}
 
 	// Create new entry
 	entry := &Entry{
-		key:    key,
-		value:  value,
-		size:   size,
-		expiry: expiry,
+		key:      key,
+		payload:  p,
+		size:     size,
+		expiry:   expiry,
+		lifetime: c.lifetimeFor(expiry),
+		written:  c.writtenAt(),
+		dirty:    c.nextDirty(),
 	}
 
-	entry.listNode = shard.lruList.PushFront(entry)
+	shard.link(entry)
+	c.markPromoted(entry)
 	shard.data[key] = entry
 
-	atomic.AddInt64(&c.totalSize, size)
 	atomic.AddInt64(&shard.size, size)
+	return size, nil
+}
 
-	shard.mu.Unlock()
+// maxEntrySize returns the largest entry size a write may store: MaxEntrySize
+// when set, and never more than MaxMemoryBytes, since a larger entry would
+// evict the whole cache and still not fit
+func (c *Cache) maxEntrySize() int64 {
+	if limit := c.config.MaxEntrySize; limit > 0 && limit < c.config.MaxMemoryBytes {
+		return limit
+	}
+	return c.config.MaxMemoryBytes
+}
 
-	// Trigger eviction if needed (outside of lock to avoid deadlock)
-	c.evictIfNeeded()
-	return nil
+// logRejected logs a write refused by RejectOnFull
+func (c *Cache) logRejected(key string, size int64) {
+	c.logs.log(logRejectedWrite, LogLevelWarn, "write rejected: memory limit exceeded",
+		"key", key, "size", size, "used", atomic.LoadInt64(&c.totalSize), "limit", c.config.MaxMemoryBytes)
 }
 
-// Get retrieves a value by key
+// lifetimeFor returns the TTL an entry expiring at expiry is being written
+// with, which RefreshOnReadThreshold extends it by. It is zero when refresh on
+// read is disabled or the entry never expires.
+func (c *Cache) lifetimeFor(expiry int64) int64 {
+	if c.config.RefreshOnReadThreshold <= 0 || expiry == 0 {
+		return 0
+	}
+	return expiry - c.now()
+}
+
+// writtenAt returns the write timestamp to record on an entry, or zero when
+// TrackEntryAge is off
+func (c *Cache) writtenAt() int64 {
+	if !c.config.TrackEntryAge {
+		return 0
+	}
+	return c.now()
+}
+
+// reserve adds delta to the total size. With RejectOnFull, growth that would
+// exceed MaxMemoryBytes is refused and reserve returns false.
+func (c *Cache) reserve(delta int64) bool {
+	if !c.config.RejectOnFull || delta <= 0 {
+		atomic.AddInt64(&c.totalSize, delta)
+		return true
+	}
+
+	for {
+		current := atomic.LoadInt64(&c.totalSize)
+		if current+delta > c.config.MaxMemoryBytes {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&c.totalSize, current, current+delta) {
+			return true
+		}
+	}
+}
+
+// Get retrieves a value by key. A stored nil value is returned as (nil, true),
+// so check the bool rather than the value to detect a miss, or set
+// RejectNilValues to keep nil out of the cache.
 func (c *Cache) Get(key string) (interface{}, bool) {
-	if atomic.LoadInt32(&c.closed) == 1 {
+	if c.latency != nil {
+		defer c.latency.get.observe(time.Now())
+	}
+	shard, entry, p := c.lookup(key)
+	if shard == nil {
 		return nil, false
 	}
+	if entry == nil {
+		return c.miss(shard, key)
+	}
 
-	shard := c.getShard(key)
+	value, ok := c.loadValue(p.get())
+	if !ok {
+		c.recordMiss(shard, key)
+		return nil, false
+	}
 
-	shard.mu.RLock()
+	c.recordHit(shard, key)
+	return value, true
+}
+
+// GetOrDefault returns the value for key, or def if it is missing or expired.
+// A lookup that returns def still counts as a miss.
+func (c *Cache) GetOrDefault(key string, def interface{}) interface{} {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	return def
+}
+
+// GetE is like Get but distinguishes why no value was returned: it reports
+// ErrCacheClosed when the cache is closed and ErrKeyNotFound on a miss
+func (c *Cache) GetE(key string) (interface{}, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, ErrCacheClosed
+	}
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Has reports whether key is present and not expired. Unlike Get it does not
+// count a hit or miss, update the entry's recency or access frequency, or
+// consult the overflow store.
+func (c *Cache) Has(key string) bool {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false
+	}
+
+	shard := c.rlockShard(key)
 	entry, exists := shard.data[key]
+	present := exists && !entry.isExpired(c.now())
+	shard.mu.RUnlock()
+	return present
+}
+
+// lookup finds the live entry for key, promotes it, and returns it with a
+// snapshot of its payload taken under the shard lock. The entry is nil on a
+// miss, and the shard is nil when the cache is closed. Hits and misses are
+// recorded by the caller.
+func (c *Cache) lookup(key string) (*Shard, *Entry, payload) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, nil, payload{}
+	}
+
+	// A key that was never written cannot be in any shard
+	if c.filter != nil && !c.filter.mayContain(key) {
+		return c.getShard(key), nil, payload{}
+	}
+
+	shard := c.rlockShard(key)
+	shard.touch(key)
+
+	entry, exists := shard.data[key]
+	var p payload
+	var expiry, lifetime int64
+	if exists {
+		p = entry.payload
+		expiry, lifetime = entry.expiry, entry.lifetime
+	}
 	shard.mu.RUnlock()
 
 	if !exists {
-		atomic.AddInt64(&shard.missCount, 1)
-		atomic.AddInt64(&c.totalMiss, 1)
-		return nil, false
+		return shard, nil, payload{}
 	}
 
-	if entry.isExpired() {
-		// Remove expired entry asynchronously to avoid blocking
-		go c.Delete(key)
-		atomic.AddInt64(&shard.missCount, 1)
-		atomic.AddInt64(&c.totalMiss, 1)
-		return nil, false
+	if !c.accessEntry(shard, entry, expiry, lifetime, c.now()) {
+		return shard, nil, payload{}
 	}
 
 	// Update LRU order
-	shard.mu.Lock()
-	shard.lruList.MoveToFront(entry.listNode)
-	shard.mu.Unlock()
+	if c.config.EvictionPolicy.promotesOnAccess() && c.shouldPromote(entry) {
+		shard.mu.Lock()
+		if shard.data[key] == entry {
+			shard.promote(entry)
+		}
+		shard.mu.Unlock()
+	}
+
+	return shard, entry, p
+}
+
+// accessEntry applies the effects of a read finding entry, whose expiry and
+// lifetime were read under the shard lock, and reports whether the entry is
+// live. An expired entry is removed in the background unless it is kept for
+// stale reads; a live one has its access recorded and its TTL extended when
+// read in the last part of its lifetime. LRU promotion is left to the caller.
+func (c *Cache) accessEntry(shard *Shard, entry *Entry, expiry, lifetime, now int64) bool {
+	if expiry > 0 && now > expiry {
+		// Remove expired entry asynchronously to avoid blocking
+		if !c.retainsStale(expiry, now) {
+			c.spawn(func() { c.expireEntry(shard, entry) })
+		}
+		return false
+	}
+
+	entry.recordAccess()
+
+	// Extend the TTL of an entry read in the last part of its lifetime
+	if lifetime > 0 && float64(expiry-now) < c.config.RefreshOnReadThreshold*float64(lifetime) {
+		shard.mu.Lock()
+		if shard.data[entry.key] == entry && entry.expiry == expiry {
+			entry.expiry += lifetime
+		}
+		shard.mu.Unlock()
+	}
+	return true
+}
+
+// miss handles a Get miss, falling back to the overflow store when configured
+func (c *Cache) miss(shard *Shard, key string) (interface{}, bool) {
+	if value, ok := c.promoteFromOverflow(key); ok {
+		c.recordHit(shard, key)
+		return value, true
+	}
+
+	c.recordMiss(shard, key)
+	return nil, false
+}
 
-	atomic.AddInt64(&shard.hitCount, 1)
-	atomic.AddInt64(&c.totalHits, 1)
-	return entry.value, true
+// shouldPromote reports whether a read should move entry to the front of the
+// LRU list. With lazy LRU, an entry is promoted at most once per
+// LRUPromotionInterval, and only by the first reader to claim the slot.
+func (c *Cache) shouldPromote(entry *Entry) bool {
+	interval := int64(c.config.LRUPromotionInterval)
+	if interval <= 0 {
+		c.markPromoted(entry)
+		return true
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&entry.lastPromoted)
+	if now-last < interval {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&entry.lastPromoted, last, now)
+}
+
+// markPromoted records that entry was just moved to the front of the LRU list
+func (c *Cache) markPromoted(entry *Entry) {
+	if c.config.LRUPromotionInterval > 0 || c.samplesEviction() {
+		atomic.StoreInt64(&entry.lastPromoted, time.Now().UnixNano())
+	}
+}
+
+// now returns the current time in Unix nanoseconds as used for expiry
+func (c *Cache) now() int64 {
+	return c.clock()
+}
+
+// isClosed reports whether the cache has been closed
+func (c *Cache) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
 }
 
 // Delete removes a key from the cache
 func (c *Cache) Delete(key string) bool {
+	if c.latency != nil {
+		defer c.latency.delete.observe(time.Now())
+	}
 	if atomic.LoadInt32(&c.closed) == 1 {
 		return false
 	}
 
-	shard := c.getShard(key)
+	return c.delete(key, true)
+}
 
+// delete removes key and reports whether it was present, forwarding the
+// removal to replicas if replicate is set
+func (c *Cache) delete(key string, replicate bool) bool {
+	shard := c.lockShard(key)
+	entry, exists := shard.data[key]
+	if !exists {
+		shard.mu.Unlock()
+		return false
+	}
+
+	c.removeLocked(shard, entry)
+	queued := replicate && c.queueEvent(CacheEvent{Type: EventDelete, Key: key})
+	shard.mu.Unlock()
+
+	if queued {
+		c.deliverReplication()
+	}
+	return true
+}
+
+// expireEntry removes an expired entry only if it is still the one stored
+// under its key, so a stale delete never removes a value written after it was
+// scheduled
+func (c *Cache) expireEntry(shard *Shard, entry *Entry) bool {
 	shard.mu.Lock()
 	defer shard.mu.Unlock()
 
-	entry, exists := shard.data[key]
-	if !exists {
+	if shard.data[entry.key] != entry {
 		return false
 	}
 
-	delete(shard.data, key)
-	shard.lruList.Remove(entry.listNode)
+	c.removeLocked(shard, entry)
+	atomic.AddInt64(&c.expirations, 1)
+	return true
+}
+
+// removeLocked removes an entry from a shard and updates size counters.
+// The caller must hold the shard's write lock.
+func (c *Cache) removeLocked(shard *Shard, entry *Entry) {
+	c.untag(entry)
+	c.unindex(entry)
+	delete(shard.data, entry.key)
+	shard.unlink(entry)
 	atomic.AddInt64(&c.totalSize, -entry.size)
 	atomic.AddInt64(&shard.size, -entry.size)
-
-	return true
 }
 
-// evictIfNeeded removes old entries if memory limit is exceeded
-func (c *Cache) evictIfNeeded() {
+// triggerEviction evicts inline, or signals the eviction goroutine when async
+// eviction is enabled. Inline eviction is still used as a backstop once memory
+// exceeds the configured overshoot tolerance. It returns the number of entries
+// evicted inline.
+func (c *Cache) triggerEviction() int {
+	c.checkMemoryPressure()
+
+	if c.evictCh == nil {
+		return c.evictIfNeeded()
+	}
+
 	currentSize := atomic.LoadInt64(&c.totalSize)
 	if currentSize <= c.config.MaxMemoryBytes {
+		return 0
+	}
+
+	overshoot := c.config.MaxMemoryBytes * int64(c.config.EvictionOvershootPercent) / 100
+	if currentSize > c.config.MaxMemoryBytes+overshoot {
+		return c.evictIfNeeded()
+	}
+
+	// Signal the eviction goroutine without blocking; a pending signal is enough
+	select {
+	case c.evictCh <- struct{}{}:
+	default:
+	}
+	return 0
+}
+
+// memoryPressureInterval is the minimum time between OnMemoryPressure calls
+const memoryPressureInterval = time.Second
+
+// pressureThreshold returns MemoryPressureThresholdPercent, or its default
+func (c *Cache) pressureThreshold() int {
+	if c.config.MemoryPressureThresholdPercent == 0 {
+		return 90
+	}
+	return c.config.MemoryPressureThresholdPercent
+}
+
+// checkMemoryPressure invokes OnMemoryPressure when usage is above the
+// high-water mark, at most once per memoryPressureInterval
+func (c *Cache) checkMemoryPressure() {
+	if c.config.OnMemoryPressure == nil {
 		return
 	}
 
+	used := atomic.LoadInt64(&c.totalSize)
+	if used*100 < c.config.MaxMemoryBytes*int64(c.pressureThreshold()) {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.pressedAt)
+	if now-last < int64(memoryPressureInterval) || !atomic.CompareAndSwapInt64(&c.pressedAt, last, now) {
+		return
+	}
+
+	c.config.OnMemoryPressure(used, c.config.MaxMemoryBytes)
+}
+
+// evictionRoutine evicts entries in the background until memory is back under the limit
+func (c *Cache) evictionRoutine() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-c.evictCh:
+			for atomic.LoadInt64(&c.totalSize) > c.config.MaxMemoryBytes && !c.evictionPaused() {
+				if c.evictIfNeeded() == 0 && c.evictRoundRobin(c.evictionTarget()) == 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// evictToTarget evicts until memory is back under the eviction target,
+// spreading evictions evenly over the shards, and returns the number of
+// entries evicted
+func (c *Cache) evictToTarget() int {
+	if atomic.LoadInt64(&c.totalSize) <= c.config.MaxMemoryBytes || c.evictionPaused() {
+		return 0
+	}
+
+	target := c.evictionTarget()
+	if c.samplesEviction() {
+		return c.logEvicted(c.evictSampled(target))
+	}
+	evicted := 0
+	for atomic.LoadInt64(&c.totalSize) > target {
+		n := c.evictRoundRobin(target)
+		if n == 0 {
+			break
+		}
+		evicted += n
+	}
+	return c.logEvicted(evicted)
+}
+
+// PauseEviction suspends eviction under memory pressure, e.g. while bulk
+// loading a dataset that temporarily exceeds MaxMemoryBytes before the old
+// entries are deleted. Writes keep succeeding past the limit unless
+// RejectOnFull is set. Calls nest: eviction stays paused until each
+// PauseEviction is matched by a ResumeEviction.
+func (c *Cache) PauseEviction() {
+	atomic.AddInt32(&c.paused, 1)
+}
+
+// ResumeEviction undoes one PauseEviction. When the last pause is lifted it
+// evicts least recently used entries evenly across shards until memory is
+// back under the limit, and returns the number of entries evicted. Calling
+// it while eviction is not paused only evicts.
+func (c *Cache) ResumeEviction() int {
+	for {
+		paused := atomic.LoadInt32(&c.paused)
+		if paused == 0 {
+			break
+		}
+		if atomic.CompareAndSwapInt32(&c.paused, paused, paused-1) {
+			if paused > 1 {
+				return 0
+			}
+			break
+		}
+	}
+	return c.evictToTarget()
+}
+
+// evictionPaused reports whether PauseEviction is in effect
+func (c *Cache) evictionPaused() bool {
+	return atomic.LoadInt32(&c.paused) > 0
+}
+
+// evictRoundRobin evicts one entry from every shard until memory is at or below
+// target. It is used when evictIfNeeded's subset of shards has nothing left to
+// evict, and to reach the low-water mark.
+func (c *Cache) evictRoundRobin(target int64) int {
+	evicted := 0
+	shards := c.allShards()
+	start := c.evictionStart(len(shards))
+	for i := range shards {
+		if atomic.LoadInt64(&c.totalSize) <= target {
+			break
+		}
+		evicted += c.evictFromShard(shards[(start+i)%len(shards)], 1)
+	}
+	return evicted
+}
+
+// evictionStart returns the shard an eviction pass over n shards starts at.
+// It rotates between passes, so passes that stop early do not keep taking
+// entries from the low-index shards.
+func (c *Cache) evictionStart(n int) int {
+	return int(atomic.AddUint32(&c.evictFrom, 1) % uint32(n))
+}
+
+// evictIfNeeded removes old entries if memory limit is exceeded and returns
+// the number of entries evicted
+func (c *Cache) evictIfNeeded() int {
+	currentSize := atomic.LoadInt64(&c.totalSize)
+	if currentSize <= c.config.MaxMemoryBytes || c.evictionPaused() {
+		return 0
+	}
+
 	// Calculate how much memory we need to free
-	excessMemory := currentSize - c.config.MaxMemoryBytes
+	target := c.evictionTarget()
+	if c.samplesEviction() {
+		return c.logEvicted(c.evictSampled(target))
+	}
+	excessMemory := currentSize - target
 
 	// Be more aggressive when significantly over limit
 	multiplier := 1
@@ -260,12 +1180,13 @@ func (c *Cache) evictIfNeeded() {
 	}
 
 	// Evict from most shards when memory pressure is high
-	shardsToEvict := (c.config.ShardCount * 3) / 4 // 75% of shards
+	shards := c.allShards()
+	shardsToEvict := (len(shards) * 3) / 4 // 75% of shards
 	if shardsToEvict < 4 {
 		shardsToEvict = 4
 	}
-	if shardsToEvict > c.config.ShardCount {
-		shardsToEvict = c.config.ShardCount
+	if shardsToEvict > len(shards) {
+		shardsToEvict = len(shards)
 	}
 
 	itemsPerShard := multiplier
@@ -276,44 +1197,147 @@ func (c *Cache) evictIfNeeded() {
 
 	// Evict from different shards to distribute the load
 	evictedTotal := 0
+	start := c.evictionStart(len(shards))
 	for i := 0; i < shardsToEvict && evictedTotal < itemsPerShard*shardsToEvict; i++ {
-		shardIndex := i % c.config.ShardCount
-		shard := c.shards[shardIndex]
+		shardIndex := (start + i) % len(shards)
+		shard := shards[shardIndex]
 		evicted := c.evictFromShard(shard, itemsPerShard)
 		evictedTotal += evicted
 
 		// Check if we've freed enough memory (but continue for a bit to avoid oscillation)
-		if atomic.LoadInt64(&c.totalSize) <= c.config.MaxMemoryBytes && evictedTotal >= itemsPerShard*2 {
+		if atomic.LoadInt64(&c.totalSize) <= target && evictedTotal >= itemsPerShard*2 {
 			break
 		}
 	}
+
+	// Keep going down to the low-water mark so the next writes have headroom
+	if target < c.config.MaxMemoryBytes {
+		for atomic.LoadInt64(&c.totalSize) > target {
+			evicted := c.evictRoundRobin(target)
+			if evicted == 0 {
+				break
+			}
+			evictedTotal += evicted
+		}
+	}
+
+	return c.logEvicted(evictedTotal)
 }
 
-// evictFromShard removes the oldest entries from a shard
+// logEvicted logs an eviction pass that removed evicted entries and returns
+// evicted
+func (c *Cache) logEvicted(evicted int) int {
+	if evicted > 0 {
+		c.logs.log(logEviction, LogLevelWarn, "evicted entries over memory limit",
+			"evicted", evicted, "used", atomic.LoadInt64(&c.totalSize), "limit", c.config.MaxMemoryBytes)
+	}
+	return evicted
+}
+
+// evictionTarget returns the memory level eviction frees down to
+func (c *Cache) evictionTarget() int64 {
+	if c.config.EvictionTargetPercent <= 0 {
+		return c.config.MaxMemoryBytes
+	}
+	return c.config.MaxMemoryBytes * int64(c.config.EvictionTargetPercent) / 100
+}
+
+// evictFromShard removes entries from a shard as chosen by the eviction policy
 func (c *Cache) evictFromShard(shard *Shard, count int) int {
-	shard.mu.Lock()
-	defer shard.mu.Unlock()
+	var evicted []*Entry
 
-	evicted := 0
-	for evicted < count && shard.lruList.Len() > 0 {
-		oldest := shard.lruList.Back()
-		if oldest == nil {
+	shard.mu.Lock()
+	for len(evicted) < count {
+		entry := shard.victim()
+		if entry == nil {
 			break
 		}
 
-		entry := oldest.Value.(*Entry)
-		delete(shard.data, entry.key)
-		shard.lruList.Remove(oldest)
-		atomic.AddInt64(&c.totalSize, -entry.size)
-		atomic.AddInt64(&shard.size, -entry.size)
-		evicted++
+		c.removeLocked(shard, entry)
+		evicted = append(evicted, entry)
 	}
+	shard.mu.Unlock()
+	atomic.AddInt64(&c.evictions, int64(len(evicted)))
 
-	return evicted
+	// Invoke callbacks outside of the lock
+	c.demote(evicted)
+	c.notifyEvicted(evicted)
+	return len(evicted)
+}
+
+// evictCallbackQueueSize bounds the OnEvict calls queued by EvictCallbackAsync
+const evictCallbackQueueSize = 4096
+
+// evictedValue is an OnEvict call queued by EvictCallbackAsync
+type evictedValue struct {
+	key   string
+	value interface{}
+}
+
+// notifyEvicted invokes OnEvict for each evicted entry, or queues the calls
+// when EvictCallbackAsync is set and the cache is open
+func (c *Cache) notifyEvicted(entries []*Entry) {
+	if c.config.OnEvict == nil {
+		return
+	}
+	async := c.callbacks != nil && atomic.LoadInt32(&c.closed) == 0
+	for _, entry := range entries {
+		value, _ := c.loadValue(entry.get())
+		if !async {
+			c.config.OnEvict(entry.key, value)
+			continue
+		}
+		select {
+		case c.callbacks <- evictedValue{key: entry.key, value: value}:
+		default:
+			atomic.AddInt64(&c.dropped, 1)
+		}
+	}
 }
 
-// cleanupRoutine runs periodic cleanup of expired entries
-func (c *Cache) cleanupRoutine() {
+// evictCallbackRoutine runs queued OnEvict calls until the cache is closed,
+// then runs whatever is still queued
+func (c *Cache) evictCallbackRoutine() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.stopCh:
+			for {
+				select {
+				case ev := <-c.callbacks:
+					c.config.OnEvict(ev.key, ev.value)
+				default:
+					return
+				}
+			}
+		case ev := <-c.callbacks:
+			c.config.OnEvict(ev.key, ev.value)
+		}
+	}
+}
+
+// spawn runs fn in a new goroutine, or synchronously once
+// MaxBackgroundGoroutines spawned goroutines are already running
+func (c *Cache) spawn(fn func()) {
+	if c.spawnSlots == nil {
+		go fn()
+		return
+	}
+	select {
+	case c.spawnSlots <- struct{}{}:
+		go func() {
+			defer func() { <-c.spawnSlots }()
+			fn()
+		}()
+	default:
+		fn()
+	}
+}
+
+// cleanupRoutine runs periodic cleanup of expired entries in the shards owned
+// by worker, one of workers cleanup goroutines
+func (c *Cache) cleanupRoutine(worker, workers int) {
 	defer c.wg.Done()
 
 	ticker := time.NewTicker(c.config.CleanupInterval)
@@ -324,59 +1348,255 @@ func (c *Cache) cleanupRoutine() {
 		case <-c.stopCh:
 			return
 		case <-ticker.C:
-			c.cleanupExpired()
+			c.cleanupStripe(worker, workers, c.config.CleanupMaxDuration)
+			if worker == 0 {
+				c.pruneNegatives()
+			}
 		}
 	}
 }
 
-// cleanupExpired removes expired entries from all shards
-func (c *Cache) cleanupExpired() {
-	now := time.Now().UnixNano()
+// cleanupExpired removes expired entries from all shards and returns the number removed.
+// When budget > 0, each shard's write lock is held for at most roughly budget at a
+// time, and released between chunks of the shard's expired entries.
+func (c *Cache) cleanupExpired(budget time.Duration) int {
+	return c.cleanupStripe(0, 1, budget)
+}
 
-	for _, shard := range c.shards {
-		shard.mu.Lock()
+// cleanupStripe removes expired entries from every workers-th shard starting
+// at worker and returns the number removed
+func (c *Cache) cleanupStripe(worker, workers int, budget time.Duration) int {
+	removed := 0
+	shards := c.allShards()
+	for i := worker; i < len(shards); i += workers {
+		removed += c.cleanupShard(shards[i], budget)
+	}
+	return removed
+}
 
-		// Collect expired keys
-		var expiredKeys []string
-		for key, entry := range shard.data {
-			if entry.expiry > 0 && now > entry.expiry {
-				expiredKeys = append(expiredKeys, key)
+// cleanupCheckEvery is how many entries are scanned between cleanup budget checks
+const cleanupCheckEvery = 256
+
+// cleanupShard removes expired entries from a single shard. They are collected
+// under the read lock, then removed under the write lock in chunks: when
+// budget > 0, a chunk ends once it has held the lock for budget, and the lock
+// is released and retaken for the next one, so even a huge shard is fully
+// cleaned in one pass without blocking writers for all of it.
+func (c *Cache) cleanupShard(shard *Shard, budget time.Duration) int {
+	now := c.now()
+	reclaimable := func(entry *Entry) bool {
+		return entry.expiry > 0 && now > entry.expiry && !c.retainsStale(entry.expiry, now)
+	}
+
+	var expired []*Entry
+	shard.mu.RLock()
+	for _, entry := range shard.data {
+		if reclaimable(entry) {
+			expired = append(expired, entry)
+		}
+	}
+	shard.mu.RUnlock()
+
+	removed := 0
+	for len(expired) > 0 {
+		shard.mu.Lock()
+		start := time.Now()
+		n := 0
+		for n < len(expired) {
+			entry := expired[n]
+			n++
+			// The entry may have been replaced or refreshed since it was collected
+			if shard.data[entry.key] == entry && reclaimable(entry) {
+				c.removeLocked(shard, entry)
+				removed++
+			}
+			if budget > 0 && n%cleanupCheckEvery == 0 && time.Since(start) > budget {
+				break
 			}
 		}
+		shard.mu.Unlock()
 
-		// Remove expired entries
-		for _, key := range expiredKeys {
-			entry := shard.data[key]
-			delete(shard.data, key)
-			shard.lruList.Remove(entry.listNode)
-			atomic.AddInt64(&c.totalSize, -entry.size)
-			atomic.AddInt64(&shard.size, -entry.size)
+		expired = expired[n:]
+		if len(expired) > 0 {
+			runtime.Gosched()
 		}
+	}
 
-		shard.mu.Unlock()
+	atomic.AddInt64(&c.expirations, int64(removed))
+	return removed
+}
+
+// DeleteExpired synchronously removes all expired entries and returns the number
+// removed. It is safe to call concurrently with the background cleanup. Unlike
+// the background cleanup it ignores CleanupMaxDuration, scanning every shard fully.
+func (c *Cache) DeleteExpired() int {
+	return c.cleanupExpired(0)
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// that have not been cleaned up yet
+func (c *Cache) Len() int {
+	total := 0
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		total += len(shard.data)
+		shard.mu.RUnlock()
 	}
+	return total
 }
 
-// Clear removes all entries from the cache
+// Clear removes all entries from the cache. Shards are cleared in parallel by
+// up to GOMAXPROCS goroutines.
 func (c *Cache) Clear() {
-	for _, shard := range c.shards {
+	c.clearShards(runtime.GOMAXPROCS(0))
+}
+
+// clearShards resets every shard using up to workers goroutines, each handling
+// an interleaved subset of the shards
+func (c *Cache) clearShards(workers int) {
+	shards := c.allShards()
+	if workers > len(shards) {
+		workers = len(shards)
+	}
+
+	clearFrom := func(start, step int) {
+		for i := start; i < len(shards); i += step {
+			shard := shards[i]
+			shard.mu.Lock()
+			shard.reset()
+			// Subtract this shard's size rather than zeroing the total
+			// afterwards, which would erase writes to already cleared shards
+			size := atomic.SwapInt64(&shard.size, 0)
+			atomic.AddInt64(&c.totalSize, -size)
+			shard.mu.Unlock()
+		}
+	}
+
+	if workers <= 1 {
+		clearFrom(0, 1)
+	} else {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(start int) {
+				defer wg.Done()
+				clearFrom(start, workers)
+			}(w)
+		}
+		wg.Wait()
+	}
+
+	c.tags.reset()
+	c.indexes.reset()
+}
+
+// Reset returns the cache to its freshly created state: all entries, stats,
+// negative-cache results and access frequencies are dropped, while the
+// configuration and background goroutines are kept. It is meant for reusing a
+// cache between tests or benchmark runs and should only be called while no
+// other operations are in flight.
+func (c *Cache) Reset() {
+	c.Clear()
+
+	for _, shard := range c.allShards() {
+		if shard.sketch != nil {
+			shard.sketch.reset()
+		}
+	}
+
+	c.negatives.Range(func(key, _ interface{}) bool {
+		c.negatives.Delete(key)
+		return true
+	})
+
+	if c.filter != nil {
+		c.filter.reset()
+	}
+
+	c.breaker.reset()
+
+	c.ResetStats()
+}
+
+// FlushShard removes all entries from a single shard, leaving the others intact.
+// It is intended as an operational escape hatch for recovering one bad shard.
+func (c *Cache) FlushShard(shardID int) error {
+	shards := c.allShards()
+	if shardID < 0 || shardID >= len(shards) {
+		return ErrShardError{ShardID: shardID, Err: ErrInvalidShard}
+	}
+
+	shard := shards[shardID]
+	shard.mu.Lock()
+	for _, entry := range shard.data {
+		c.untag(entry)
+		c.unindex(entry)
+	}
+	shard.reset()
+	size := atomic.SwapInt64(&shard.size, 0)
+	atomic.AddInt64(&c.totalSize, -size)
+	shard.mu.Unlock()
+
+	return nil
+}
+
+// Compact rebuilds each shard's map at a size fitting its current entries, so
+// memory held by a map that grew before a mass deletion is released to the
+// garbage collector; Go maps never shrink on their own. Shards are rebuilt one
+// at a time, each blocking only its own operations while it is copied. A map
+// is never shrunk below the ExpectedEntries hint.
+func (c *Cache) Compact() {
+	for _, shard := range c.allShards() {
 		shard.mu.Lock()
-		shard.data = make(map[string]*Entry)
-		shard.lruList = list.New()
-		atomic.StoreInt64(&shard.size, 0)
+		capacity := len(shard.data)
+		if capacity < shard.capacity {
+			capacity = shard.capacity
+		}
+		data := make(map[string]*Entry, capacity)
+		for key, entry := range shard.data {
+			data[key] = entry
+		}
+		shard.data = data
 		shard.mu.Unlock()
 	}
-	atomic.StoreInt64(&c.totalSize, 0)
 }
 
 // Close gracefully shuts down the cache
 func (c *Cache) Close() error {
+	c.startMu.Lock()
 	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		c.startMu.Unlock()
 		return ErrCacheClosed
 	}
-
 	close(c.stopCh)
+	c.startMu.Unlock()
+
 	c.wg.Wait()
 
 	return nil
 }
+
+// CloseWithDrain shuts down the cache like Close, then removes every remaining
+// entry and invokes OnEvict for each so resources held by values can be released
+// deterministically. New writes are rejected before draining begins.
+func (c *Cache) CloseWithDrain() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	for _, shard := range c.allShards() {
+		shard.mu.Lock()
+		drained := make([]*Entry, 0, len(shard.data))
+		for _, entry := range shard.data {
+			drained = append(drained, entry)
+		}
+		shard.reset()
+		size := atomic.SwapInt64(&shard.size, 0)
+		atomic.AddInt64(&c.totalSize, -size)
+		shard.mu.Unlock()
+
+		c.notifyEvicted(drained)
+	}
+
+	return nil
+}
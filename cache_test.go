@@ -1,9 +1,14 @@
 package fastcache
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -168,6 +173,44 @@ func TestMemoryLimit(t *testing.T) {
 		stats.MemoryUsage, stats.TotalEntries, totalInserted, stats.TotalEntries < int64(totalInserted))
 }
 
+func TestEntryLargerThanCache(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  64 * 1024,
+		ShardCount:      4,
+		CleanupInterval: time.Minute,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := cache.Set(fmt.Sprintf("key_%d", i), "value"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	before := cache.GetStats()
+
+	err := cache.Set("giant", make([]byte, 2*config.MaxMemoryBytes))
+	var tooLarge ErrEntryTooLarge
+	if !errors.As(err, &tooLarge) || tooLarge.Max != config.MaxMemoryBytes {
+		t.Fatalf("Expected ErrEntryTooLarge capped at MaxMemoryBytes, got %v", err)
+	}
+
+	after := cache.GetStats()
+	if after.TotalEntries != before.TotalEntries || after.TotalSize != before.TotalSize || after.Evictions != 0 {
+		t.Errorf("Expected the cache untouched, got %d entries (%d bytes), %d evictions",
+			after.TotalEntries, after.TotalSize, after.Evictions)
+	}
+	if _, found := cache.Get("giant"); found {
+		t.Error("Expected the giant value not to be stored")
+	}
+	for i := 0; i < 10; i++ {
+		if _, found := cache.Get(fmt.Sprintf("key_%d", i)); !found {
+			t.Errorf("Expected key_%d to be preserved", i)
+		}
+	}
+}
+
 func TestSimpleEviction(t *testing.T) {
 	// Simple test to verify basic eviction works
 	config := &Config{
@@ -259,6 +302,70 @@ func TestStats(t *testing.T) {
 	}
 }
 
+func TestSetReport(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 4096
+	config.ShardCount = 1
+	cache := New(config)
+	defer cache.Close()
+
+	if evicted, err := cache.SetReport("first", "value"); err != nil || evicted != 0 {
+		t.Fatalf("Expected no eviction with room to spare, got %d, %v", evicted, err)
+	}
+
+	// Fill the cache to its limit without evicting
+	value := strings.Repeat("x", 100)
+	i := 0
+	for cache.GetStats().TotalSize+calculateSize(fmt.Sprintf("key%d", i), value) <= config.MaxMemoryBytes {
+		if evicted, _ := cache.SetReport(fmt.Sprintf("key%d", i), value); evicted != 0 {
+			t.Fatalf("Expected no eviction under the limit, got %d", evicted)
+		}
+		i++
+	}
+
+	evicted, err := cache.SetReport("overflow", value)
+	if err != nil {
+		t.Fatalf("SetReport failed: %v", err)
+	}
+	if evicted == 0 {
+		t.Error("Expected SetReport to report evictions once the cache is full")
+	}
+	if stats := cache.GetStats(); stats.Evictions != int64(evicted) {
+		t.Errorf("Expected reported evictions %d to match stats %d", evicted, stats.Evictions)
+	}
+}
+
+func TestEvictionAndExpirationCounts(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 4096
+	config.ShardCount = 1
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("short", "value", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if removed := cache.DeleteExpired(); removed != 1 {
+		t.Fatalf("Expected 1 expired entry removed, got %d", removed)
+	}
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), strings.Repeat("x", 100))
+	}
+
+	stats := cache.GetStats()
+	if stats.Expirations != 1 {
+		t.Errorf("Expected 1 expiration, got %d", stats.Expirations)
+	}
+	if stats.Evictions == 0 {
+		t.Error("Expected evictions once over the memory limit")
+	}
+
+	cache.ResetStats()
+	if stats := cache.GetStats(); stats.Evictions != 0 || stats.Expirations != 0 {
+		t.Errorf("Expected counts reset, got %d evictions and %d expirations", stats.Evictions, stats.Expirations)
+	}
+}
+
 func TestConfigValidation(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -312,6 +419,122 @@ func TestConfigValidation(t *testing.T) {
 	}
 }
 
+func TestNewSized(t *testing.T) {
+	isPowerOfTwo := func(n int) bool { return n > 0 && n&(n-1) == 0 }
+
+	previous := 0
+	for _, budget := range []int64{1 << 10, 1 << 20, 16 << 20, 256 << 20, 1 << 30, 64 << 30} {
+		shards := sizedShardCount(budget, 8)
+		if !isPowerOfTwo(shards) {
+			t.Errorf("Expected power-of-two shard count for %d bytes, got %d", budget, shards)
+		}
+		if shards < previous {
+			t.Errorf("Expected shard count to grow with budget, got %d after %d", shards, previous)
+		}
+		previous = shards
+	}
+	if small, large := sizedShardCount(1<<20, 8), sizedShardCount(1<<30, 8); small >= large {
+		t.Errorf("Expected a larger budget to get more shards, got %d and %d", small, large)
+	}
+	if few, many := sizedShardCount(1<<30, 1), sizedShardCount(1<<30, 16); few >= many {
+		t.Errorf("Expected more CPUs to get more shards, got %d and %d", few, many)
+	}
+
+	cache := NewSized(8 << 20)
+	defer cache.Close()
+
+	config := cache.config
+	if config.MaxMemoryBytes != 8<<20 || !isPowerOfTwo(len(cache.allShards())) {
+		t.Errorf("Unexpected sized config: %d bytes, %d shards", config.MaxMemoryBytes, len(cache.allShards()))
+	}
+	if config.DefaultTTL <= 0 || config.CleanupInterval <= 0 {
+		t.Errorf("Expected default TTL and cleanup, got %v and %v", config.DefaultTTL, config.CleanupInterval)
+	}
+	if err := cache.Set("key", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, found := cache.Get("key"); !found || value != "value" {
+		t.Errorf("Expected value, got %v, %v", value, found)
+	}
+}
+
+func TestPauseEviction(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  8 * 1024, // 8KB, about 38 entries of 150 bytes
+		ShardCount:      1,        // LRU order is per shard
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 30; i++ {
+		_ = cache.Set(fmt.Sprintf("old_%d", i), make([]byte, 150))
+	}
+
+	// Bulk load a new dataset larger than the limit
+	cache.PauseEviction()
+	const loaded = 60
+	for i := 0; i < loaded; i++ {
+		_ = cache.Set(fmt.Sprintf("new_%d", i), make([]byte, 150))
+	}
+	if evictions := cache.GetStats().Evictions; evictions != 0 {
+		t.Fatalf("Expected no evictions while paused, got %d", evictions)
+	}
+	if cache.GetStats().TotalSize <= config.MaxMemoryBytes {
+		t.Fatalf("Expected the load to exceed the limit while paused, got %d bytes", cache.GetStats().TotalSize)
+	}
+
+	if evicted := cache.ResumeEviction(); evicted == 0 {
+		t.Error("Expected ResumeEviction to evict")
+	}
+	if cache.GetStats().TotalSize > config.MaxMemoryBytes {
+		t.Errorf("Expected memory within %d bytes after resuming, got %d", config.MaxMemoryBytes, cache.GetStats().TotalSize)
+	}
+
+	for i := 0; i < 30; i++ {
+		if cache.Has(fmt.Sprintf("old_%d", i)) {
+			t.Errorf("Expected old_%d to be evicted before the fresh load", i)
+		}
+	}
+	for i := loaded - 10; i < loaded; i++ {
+		if !cache.Has(fmt.Sprintf("new_%d", i)) {
+			t.Errorf("Expected freshest entry new_%d to survive", i)
+		}
+	}
+}
+
+func TestPauseEvictionNested(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  4 * 1024,
+		ShardCount:      4,
+		CleanupInterval: time.Second,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	cache.PauseEviction()
+	cache.PauseEviction()
+	for i := 0; i < 50; i++ {
+		_ = cache.Set(fmt.Sprintf("key_%d", i), make([]byte, 150))
+	}
+
+	if evicted := cache.ResumeEviction(); evicted != 0 {
+		t.Errorf("Expected eviction to stay paused until the outer resume, evicted %d", evicted)
+	}
+	_ = cache.Set("one_more", make([]byte, 150))
+	if cache.GetStats().Evictions != 0 {
+		t.Error("Expected no evictions while still paused")
+	}
+
+	cache.ResumeEviction()
+	if cache.GetStats().TotalSize > config.MaxMemoryBytes {
+		t.Errorf("Expected memory within limit after the outer resume, got %d", cache.GetStats().TotalSize)
+	}
+}
+
 func TestLRUEviction(t *testing.T) {
 	config := &Config{
 		MaxMemoryBytes:  8 * 1024, // 8KB - smaller for more predictable behavior
@@ -345,8 +568,10 @@ func TestLRUEviction(t *testing.T) {
 		}
 	}
 
-	// Add more data to force eviction
-	additionalEntries := 40
+	// Add more data to force eviction. The cache holds about 36 entries, so
+	// this evicts about 14, fewer than the 25 older keys: under LRU the
+	// recently used keys must outlive those.
+	additionalEntries := 20
 	for i := initialEntries; i < initialEntries+additionalEntries; i++ {
 		key := fmt.Sprintf("lru_key_%d", i)
 		value := make([]byte, entrySize)
@@ -449,142 +674,1368 @@ func TestClear(t *testing.T) {
 	}
 }
 
-func TestDifferentValueTypes(t *testing.T) {
+func TestClearConcurrentSet(t *testing.T) {
 	cache := New(DefaultConfig())
 	defer cache.Close()
 
-	// Test different value types
-	testCases := []struct {
-		key   string
-		value interface{}
-	}{
-		{"string", "test string"},
-		{"int", 42},
-		{"float", 3.14},
-		{"bool", true},
-		{"bytes", []byte("test bytes")},
-		{"slice", []int{1, 2, 3}},
-		{"map", map[string]string{"key": "value"}},
-		{"struct", struct{ Name string }{"test"}},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.key, func(t *testing.T) {
-			err := cache.Set(tc.key, tc.value)
-			if err != nil {
-				t.Fatalf("Set failed for %s: %v", tc.key, err)
-			}
-
-			value, exists := cache.Get(tc.key)
-			if !exists {
-				t.Fatalf("Key %s not found", tc.key)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = cache.Set(fmt.Sprintf("clear_race_%d_%d", g, i%1000), "value")
 			}
+		}(g)
+	}
+	for i := 0; i < 20; i++ {
+		cache.clearShards(4)
+	}
+	close(stop)
+	wg.Wait()
 
-			// Note: Deep comparison might not work for all types
-			// In a real application, you might want to use reflection
-			// or specific comparison logic for complex types
-			if fmt.Sprintf("%v", value) != fmt.Sprintf("%v", tc.value) {
-				t.Errorf("Expected %v, got %v", tc.value, value)
-			}
-		})
+	if errs := cache.Verify(); len(errs) > 0 {
+		t.Errorf("Expected consistent sizes after concurrent Set and Clear, got %v", errs)
 	}
 }
 
-func TestClosedCache(t *testing.T) {
+func TestReset(t *testing.T) {
 	cache := New(DefaultConfig())
+	defer cache.Close()
 
-	// Close the cache
-	err := cache.Close()
-	if err != nil {
-		t.Fatalf("Close failed: %v", err)
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("reset_key_%d", i), i)
+	}
+	for i := 0; i < 50; i++ {
+		cache.Get(fmt.Sprintf("reset_key_%d", i))
 	}
+	cache.Get("missing")
 
-	// Operations on closed cache should fail gracefully
-	err = cache.Set("key", "value")
-	if err != ErrCacheClosed {
-		t.Errorf("Expected ErrCacheClosed, got %v", err)
+	cache.Reset()
+
+	if n := cache.Len(); n != 0 {
+		t.Errorf("Expected 0 entries after reset, got %d", n)
+	}
+	stats := cache.GetStats()
+	if stats.HitCount != 0 || stats.MissCount != 0 || stats.TotalSize != 0 {
+		t.Errorf("Expected zeroed stats after reset, got %+v", stats)
 	}
 
-	_, exists := cache.Get("key")
-	if exists {
-		t.Error("Get should return false for closed cache")
+	// The cache is still usable
+	if err := cache.Set("after", "value"); err != nil {
+		t.Fatalf("Set after reset failed: %v", err)
+	}
+	if value, ok := cache.Get("after"); !ok || value != "value" {
+		t.Errorf("Expected value after reset, got %v, %v", value, ok)
+	}
+	if stats := cache.GetStats(); stats.HitCount != 1 || stats.TotalEntries != 1 {
+		t.Errorf("Expected stats to count again after reset, got %+v", stats)
 	}
+}
 
-	deleted := cache.Delete("key")
-	if deleted {
-		t.Error("Delete should return false for closed cache")
+func TestRefreshOnReadThreshold(t *testing.T) {
+	config := DefaultConfig()
+	config.RefreshOnReadThreshold = 0.25
+	cache := New(config)
+	defer cache.Close()
+
+	const ttl = 400 * time.Millisecond
+	start := time.Now()
+	_ = cache.Set("hot", "value", ttl)
+	_ = cache.Set("cold", "value", ttl)
+
+	// A read early in the lifetime does not extend it
+	if _, ok := cache.Get("cold"); !ok {
+		t.Fatal("Expected cold entry to exist")
 	}
 
-	// Second close should return error
-	err = cache.Close()
-	if err != ErrCacheClosed {
-		t.Errorf("Expected ErrCacheClosed on second close, got %v", err)
+	// A read in the last quarter of the lifetime extends it by the original TTL
+	time.Sleep(350*time.Millisecond - time.Since(start))
+	if _, ok := cache.Get("hot"); !ok {
+		t.Fatal("Expected hot entry to exist before its TTL")
 	}
-}
 
-// Load test for high concurrency
-func TestHighLoad(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping load test in short mode")
+	time.Sleep(500*time.Millisecond - time.Since(start))
+	if _, ok := cache.Get("hot"); !ok {
+		t.Error("Expected hot entry to survive past its original TTL")
 	}
+	if _, ok := cache.Get("cold"); ok {
+		t.Error("Expected cold entry to expire at its original TTL")
+	}
+}
 
+func TestGetOrDefault(t *testing.T) {
 	cache := New(DefaultConfig())
 	defer cache.Close()
 
-	const duration = 2 * time.Second
-	const numWorkers = 100
+	_ = cache.Set("key", "cached")
+	_ = cache.Set("expired", "old", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
 
-	var wg sync.WaitGroup
-	start := time.Now()
-	stop := make(chan struct{})
+	if value := cache.GetOrDefault("key", "fallback"); value != "cached" {
+		t.Errorf("Expected cached value, got %v", value)
+	}
+	if value := cache.GetOrDefault("missing", "fallback"); value != "fallback" {
+		t.Errorf("Expected default for missing key, got %v", value)
+	}
+	if value := cache.GetOrDefault("expired", "fallback"); value != "fallback" {
+		t.Errorf("Expected default for expired key, got %v", value)
+	}
 
-	// Start worker goroutines
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			ops := 0
-			for {
-				select {
-				case <-stop:
-					return
-				default:
-					if rand.Float32() < 0.3 {
-						// Write operation
-						key := fmt.Sprintf("load_key_%d_%d", workerID, ops)
-						value := fmt.Sprintf("load_value_%d_%d", workerID, ops)
-						_ = cache.Set(key, value)
-					} else {
-						// Read operation
-						key := fmt.Sprintf("load_key_%d_%d", workerID, rand.Intn(ops+1))
-						cache.Get(key)
-					}
-					ops++
-				}
-			}
-		}(i)
+	if stats := cache.GetStats(); stats.HitCount != 1 || stats.MissCount != 2 {
+		t.Errorf("Expected 1 hit and 2 misses, got %d and %d", stats.HitCount, stats.MissCount)
 	}
+}
 
-	// Run for specified duration
-	time.Sleep(duration)
-	close(stop)
-	wg.Wait()
+func TestSetExpireAt(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = time.Hour
+	cache := New(config)
+	defer cache.Close()
 
-	elapsed := time.Since(start)
-	stats := cache.GetStats()
-	totalOps := stats.HitCount + stats.MissCount
+	at := time.Now().Add(200 * time.Millisecond)
+	if err := cache.SetExpireAt("aligned", "value", at); err != nil {
+		t.Fatalf("SetExpireAt failed: %v", err)
+	}
 
-	qps := float64(totalOps) / elapsed.Seconds()
+	shard := cache.rlockShard("aligned")
+	expiry := shard.data["aligned"].expiry
+	shard.mu.RUnlock()
+	if expiry != at.UnixNano() {
+		t.Errorf("Expected expiry exactly at %d, got %d", at.UnixNano(), expiry)
+	}
+	if _, found := cache.Get("aligned"); !found {
+		t.Error("Expected entry before its expiry time")
+	}
 
-	t.Logf("Load test results:")
-	t.Logf("Duration: %v", elapsed)
-	t.Logf("Total operations: %d", totalOps)
-	t.Logf("QPS: %.0f", qps)
-	t.Logf("Hit ratio: %.2f%%", stats.HitRatio*100)
-	t.Logf("Memory usage: %s", stats.MemoryUsage)
-	t.Logf("Total entries: %d", stats.TotalEntries)
+	time.Sleep(time.Until(at) + 20*time.Millisecond)
+	if _, found := cache.Get("aligned"); found {
+		t.Error("Expected entry to expire at its absolute expiry time")
+	}
 
-	if qps < 50000 { // At least 50K QPS for shorter test
-		t.Logf("Warning: QPS (%.0f) is lower than expected", qps)
+	// A time in the past expires the key immediately
+	_ = cache.Set("stale", "value")
+	if err := cache.SetExpireAt("stale", "new", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("SetExpireAt in the past failed: %v", err)
+	}
+	if _, found := cache.Get("stale"); found {
+		t.Error("Expected a past expiry to remove the key")
+	}
+}
+
+func TestNilValues(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	// By default nil is stored and only the bool tells it apart from a miss
+	if err := cache.Set("nil", nil); err != nil {
+		t.Fatalf("Set nil failed: %v", err)
+	}
+	if value, ok := cache.Get("nil"); !ok || value != nil {
+		t.Errorf("Expected (nil, true), got (%v, %v)", value, ok)
+	}
+	if value, ok := cache.Get("missing"); ok || value != nil {
+		t.Errorf("Expected (nil, false) for a miss, got (%v, %v)", value, ok)
+	}
+
+	config := DefaultConfig()
+	config.RejectNilValues = true
+	strict := New(config)
+	defer strict.Close()
+
+	var user *struct{ Name string }
+	var values map[string]int
+	for name, value := range map[string]interface{}{"nil": nil, "typed_ptr": user, "typed_map": values} {
+		if err := strict.Set(name, value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Expected ErrInvalidValue for %s, got %v", name, err)
+		}
+		if _, ok := strict.Get(name); ok {
+			t.Errorf("Expected rejected %s not to be stored", name)
+		}
+	}
+	if err := strict.SetBytes("nil_bytes", nil); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for nil bytes, got %v", err)
+	}
+
+	// Empty but non-nil values are still accepted
+	if err := strict.Set("empty", []string{}); err != nil {
+		t.Errorf("Expected empty slice to be stored, got %v", err)
+	}
+	if err := strict.Set("blank", ""); err != nil {
+		t.Errorf("Expected empty string to be stored, got %v", err)
+	}
+}
+
+func TestMaxTTL(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 24 * time.Hour
+	config.MaxTTL = time.Minute
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("huge", "value", 100*365*24*time.Hour)
+	_ = cache.Set("default", "value")
+	_ = cache.Set("short", "value", time.Second)
+
+	for _, key := range []string{"huge", "default"} {
+		info, ok := cache.EntryInfo(key)
+		if !ok || info.TTL <= 0 || info.TTL > config.MaxTTL {
+			t.Errorf("Expected %s TTL clamped to %v, got %+v", key, config.MaxTTL, info)
+		}
+	}
+	if info, _ := cache.EntryInfo("short"); info.TTL > time.Second {
+		t.Errorf("Expected TTL under MaxTTL to be kept, got %v", info.TTL)
+	}
+
+	// Entries without any TTL expire after MaxTTL too
+	config = DefaultConfig()
+	config.DefaultTTL = 0
+	config.MaxTTL = time.Minute
+	eternal := New(config)
+	defer eternal.Close()
+
+	_ = eternal.Set("key", "value")
+	if info, _ := eternal.EntryInfo("key"); info.TTL <= 0 || info.TTL > time.Minute {
+		t.Errorf("Expected entry without TTL to expire within MaxTTL, got %v", info.TTL)
+	}
+}
+
+func TestGetE(t *testing.T) {
+	cache := New(DefaultConfig())
+
+	_ = cache.Set("key", "value")
+	if value, err := cache.GetE("key"); err != nil || value != "value" {
+		t.Errorf("Expected value, got %v, %v", value, err)
+	}
+	if _, err := cache.GetE("missing"); err != ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	cache.Close()
+	if _, err := cache.GetE("key"); err != ErrCacheClosed {
+		t.Errorf("Expected ErrCacheClosed, got %v", err)
+	}
+}
+
+func TestValueType(t *testing.T) {
+	type user struct{ Name string }
+
+	config := DefaultConfig()
+	config.ValueType = reflect.TypeOf(&user{})
+	cache := New(config)
+	defer cache.Close()
+
+	if err := cache.Set("alice", &user{Name: "alice"}); err != nil {
+		t.Errorf("Expected matching type to be stored, got %v", err)
+	}
+	if value, ok := cache.Get("alice"); !ok || value.(*user).Name != "alice" {
+		t.Errorf("Expected stored user, got %v, %v", value, ok)
+	}
+
+	err := cache.Set("bob", user{Name: "bob"})
+	var typeErr ErrValueType
+	if !errors.As(err, &typeErr) || typeErr.Actual != reflect.TypeOf(user{}) {
+		t.Fatalf("Expected ErrValueType for wrong type, got %v", err)
+	}
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Error("Expected ErrValueType to wrap ErrTypeMismatch")
+	}
+	if cache.Has("bob") {
+		t.Error("Expected wrong-typed value not to be stored")
+	}
+
+	if err := cache.Set("nobody", nil); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected untyped nil to be rejected, got %v", err)
+	}
+	if err := cache.SetBytes("raw", []byte("data")); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected SetBytes to be rejected, got %v", err)
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxKeyLength = 8
+	cache := New(config)
+	defer cache.Close()
+
+	if err := cache.Set("", "value"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for empty key, got %v", err)
+	}
+
+	if err := cache.Set("12345678", "value"); err != nil {
+		t.Errorf("Expected key at the maximum length to be accepted, got %v", err)
+	}
+
+	err := cache.Set("123456789", "value")
+	var tooLong ErrKeyTooLong
+	if !errors.As(err, &tooLong) || tooLong.Length != 9 || tooLong.Max != 8 {
+		t.Fatalf("Expected ErrKeyTooLong for over-length key, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidKey) || !IsPermanentError(err) {
+		t.Error("Expected ErrKeyTooLong to be a permanent ErrInvalidKey")
+	}
+
+	if err := cache.SetBytes("123456789", []byte("value")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected SetBytes to reject over-length key, got %v", err)
+	}
+	if err := cache.SetTransaction(map[string]interface{}{"ok": 1, "": 2}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected SetTransaction to reject empty key, got %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected only the valid key to be stored, got %d entries", cache.Len())
+	}
+}
+
+func TestHas(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 1
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("first", 1)
+	_ = cache.Set("second", 2)
+	_ = cache.Set("expiring", 3, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if !cache.Has("first") {
+		t.Error("Expected Has to find first")
+	}
+	if cache.Has("missing") {
+		t.Error("Expected Has to report missing key as absent")
+	}
+	if cache.Has("expiring") {
+		t.Error("Expected Has to report expired key as absent")
+	}
+
+	stats := cache.GetStats()
+	if stats.HitCount != 0 || stats.MissCount != 0 {
+		t.Errorf("Expected Has not to affect stats, got %d hits, %d misses", stats.HitCount, stats.MissCount)
+	}
+
+	// Has does not promote, so first is still the least recently used entry
+	if victim := cache.allShards()[0].victim(); victim == nil || victim.key != "first" {
+		t.Errorf("Expected Has not to change LRU order, victim is %v", victim)
+	}
+}
+
+func TestDifferentValueTypes(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	// Test different value types
+	testCases := []struct {
+		key   string
+		value interface{}
+	}{
+		{"string", "test string"},
+		{"int", 42},
+		{"float", 3.14},
+		{"bool", true},
+		{"bytes", []byte("test bytes")},
+		{"slice", []int{1, 2, 3}},
+		{"map", map[string]string{"key": "value"}},
+		{"struct", struct{ Name string }{"test"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			err := cache.Set(tc.key, tc.value)
+			if err != nil {
+				t.Fatalf("Set failed for %s: %v", tc.key, err)
+			}
+
+			value, exists := cache.Get(tc.key)
+			if !exists {
+				t.Fatalf("Key %s not found", tc.key)
+			}
+
+			// Note: Deep comparison might not work for all types
+			// In a real application, you might want to use reflection
+			// or specific comparison logic for complex types
+			if fmt.Sprintf("%v", value) != fmt.Sprintf("%v", tc.value) {
+				t.Errorf("Expected %v, got %v", tc.value, value)
+			}
+		})
+	}
+}
+
+func TestClosedCache(t *testing.T) {
+	cache := New(DefaultConfig())
+
+	// Close the cache
+	err := cache.Close()
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Operations on closed cache should fail gracefully
+	err = cache.Set("key", "value")
+	if !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Expected ErrCacheClosed, got %v", err)
+	}
+
+	_, exists := cache.Get("key")
+	if exists {
+		t.Error("Get should return false for closed cache")
+	}
+
+	deleted := cache.Delete("key")
+	if deleted {
+		t.Error("Delete should return false for closed cache")
+	}
+
+	// Second close should return error
+	err = cache.Close()
+	if err != ErrCacheClosed {
+		t.Errorf("Expected ErrCacheClosed on second close, got %v", err)
+	}
+}
+
+// Load test for high concurrency
+func TestHighLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping load test in short mode")
+	}
+
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	const duration = 2 * time.Second
+	const numWorkers = 100
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	stop := make(chan struct{})
+
+	// Start worker goroutines
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			ops := 0
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					if rand.Float32() < 0.3 {
+						// Write operation
+						key := fmt.Sprintf("load_key_%d_%d", workerID, ops)
+						value := fmt.Sprintf("load_value_%d_%d", workerID, ops)
+						_ = cache.Set(key, value)
+					} else {
+						// Read operation
+						key := fmt.Sprintf("load_key_%d_%d", workerID, rand.Intn(ops+1))
+						cache.Get(key)
+					}
+					ops++
+				}
+			}
+		}(i)
+	}
+
+	// Run for specified duration
+	time.Sleep(duration)
+	close(stop)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	stats := cache.GetStats()
+	totalOps := stats.HitCount + stats.MissCount
+
+	qps := float64(totalOps) / elapsed.Seconds()
+
+	t.Logf("Load test results:")
+	t.Logf("Duration: %v", elapsed)
+	t.Logf("Total operations: %d", totalOps)
+	t.Logf("QPS: %.0f", qps)
+	t.Logf("Hit ratio: %.2f%%", stats.HitRatio*100)
+	t.Logf("Memory usage: %s", stats.MemoryUsage)
+	t.Logf("Total entries: %d", stats.TotalEntries)
+
+	if qps < 50000 { // At least 50K QPS for shorter test
+		t.Logf("Warning: QPS (%.0f) is lower than expected", qps)
+	}
+}
+
+func TestAsyncEviction(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:           64 * 1024, // 64KB
+		ShardCount:               16,
+		DefaultTTL:               0,
+		CleanupInterval:          time.Second,
+		AsyncEviction:            true,
+		EvictionOvershootPercent: 50,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	// Inline eviction acts as a backstop once the overshoot tolerance is
+	// exceeded, so usage never passes it by more than the entry just written
+	value := make([]byte, 256)
+	limit := config.MaxMemoryBytes * int64(100+config.EvictionOvershootPercent) / 100
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("async_key_%d", i)
+		_ = cache.Set(key, value)
+
+		if size := cache.GetStats().TotalSize; size > limit+calculateSize(key, value) {
+			t.Fatalf("Memory usage %d exceeds the %d%% overshoot bound of %d bytes", size, config.EvictionOvershootPercent, limit)
+		}
+	}
+
+	// Memory should eventually settle below the limit
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cache.GetStats().TotalSize <= config.MaxMemoryBytes {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stats := cache.GetStats()
+	if stats.TotalSize > config.MaxMemoryBytes {
+		t.Errorf("Memory usage (%d) did not settle below limit (%d)", stats.TotalSize, config.MaxMemoryBytes)
+	}
+	if stats.TotalEntries == 0 {
+		t.Error("Expected some entries to remain after eviction")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 16
+	config.DisableBackgroundCleanup = true
+	cache := New(config)
+	defer cache.Close()
+
+	const n = 200000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("compact_key_%d", i)
+		_ = cache.Set(keys[i], i)
+	}
+	for _, key := range keys[100:] {
+		cache.Delete(key)
+	}
+	keys = nil
+
+	heapInUse := func() uint64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+
+	before := heapInUse()
+	cache.Compact()
+	after := heapInUse()
+	t.Logf("heap %d -> %d bytes", before, after)
+
+	// The emptied maps held several megabytes of buckets
+	if before < after || before-after < 1<<20 {
+		t.Errorf("Expected Compact to release at least 1MB, heap went from %d to %d bytes", before, after)
+	}
+
+	if got := cache.Len(); got != 100 {
+		t.Errorf("Expected 100 entries after Compact, got %d", got)
+	}
+	for i := 0; i < 100; i++ {
+		if value, found := cache.Get(fmt.Sprintf("compact_key_%d", i)); !found || value != i {
+			t.Errorf("Expected compact_key_%d to survive Compact, got %v (found=%v)", i, value, found)
+		}
+	}
+	if errs := cache.Verify(); len(errs) > 0 {
+		t.Errorf("Expected a consistent cache, got %v", errs)
+	}
+}
+
+func TestFlushShard(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 8
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 200; i++ {
+		_ = cache.Set(fmt.Sprintf("flush_key_%d", i), fmt.Sprintf("value_%d", i))
+	}
+
+	target := 3
+	shardStats := cache.GetShardStats()
+	flushedEntries := shardStats[target].EntryCount
+	if flushedEntries == 0 {
+		t.Fatal("Expected target shard to hold entries")
+	}
+
+	if err := cache.FlushShard(target); err != nil {
+		t.Fatalf("FlushShard failed: %v", err)
+	}
+
+	for i, s := range cache.GetShardStats() {
+		if i == target && s.EntryCount != 0 {
+			t.Errorf("Flushed shard still has %d entries", s.EntryCount)
+		}
+		if i != target && s.EntryCount != shardStats[i].EntryCount {
+			t.Errorf("Shard %d changed from %d to %d entries", i, shardStats[i].EntryCount, s.EntryCount)
+		}
+	}
+
+	stats := cache.GetStats()
+	if stats.TotalEntries != int64(200-flushedEntries) {
+		t.Errorf("Expected %d entries, got %d", 200-flushedEntries, stats.TotalEntries)
+	}
+
+	var shardTotal int64
+	for _, s := range cache.GetShardStats() {
+		shardTotal += s.Size
+	}
+	if stats.TotalSize != shardTotal {
+		t.Errorf("Total size %d does not match sum of shard sizes %d", stats.TotalSize, shardTotal)
+	}
+
+	// Invalid shard IDs are rejected
+	for _, id := range []int{-1, config.ShardCount} {
+		err := cache.FlushShard(id)
+		if !errors.Is(err, ErrInvalidShard) {
+			t.Errorf("Expected ErrInvalidShard for shard %d, got %v", id, err)
+		}
+	}
+}
+
+func TestCopyValues(t *testing.T) {
+	for _, copyValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("CopyValues=%v", copyValues), func(t *testing.T) {
+			config := DefaultConfig()
+			config.CopyValues = copyValues
+			cache := New(config)
+			defer cache.Close()
+
+			original := []byte("hello")
+			_ = cache.Set("bytes", original)
+
+			// Mutate the caller's slice after Set
+			original[0] = 'j'
+
+			value, _ := cache.Get("bytes")
+			got := value.([]byte)
+
+			// Mutate the slice returned from Get
+			got[1] = 'a'
+
+			value, _ = cache.Get("bytes")
+			cached := string(value.([]byte))
+
+			if copyValues && cached != "hello" {
+				t.Errorf("Expected isolated value 'hello', got '%s'", cached)
+			}
+			if !copyValues && cached != "jallo" {
+				t.Errorf("Expected shared value 'jallo', got '%s'", cached)
+			}
+		})
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	var evicted int64
+	config := &Config{
+		MaxMemoryBytes:  4 * 1024, // 4KB
+		ShardCount:      4,
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+		OnEvict: func(key string, value interface{}) {
+			atomic.AddInt64(&evicted, 1)
+		},
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	const inserted = 100
+	for i := 0; i < inserted; i++ {
+		_ = cache.Set(fmt.Sprintf("evict_cb_key_%d", i), make([]byte, 150))
+	}
+
+	stats := cache.GetStats()
+	if got := atomic.LoadInt64(&evicted); got != inserted-stats.TotalEntries {
+		t.Errorf("Expected %d OnEvict calls, got %d", inserted-stats.TotalEntries, got)
+	}
+}
+
+func TestEvictCallbackAsync(t *testing.T) {
+	var calls int64
+	release := make(chan struct{})
+	config := &Config{
+		MaxMemoryBytes:     4 * 1024, // 4KB
+		ShardCount:         4,
+		CleanupInterval:    time.Second,
+		EvictCallbackAsync: true,
+		OnEvict: func(key string, value interface{}) {
+			<-release // a handler far slower than the cache
+			atomic.AddInt64(&calls, 1)
+		},
+	}
+
+	cache := New(config)
+
+	// Enough evictions to overflow the callback queue while the handler is
+	// stuck; with synchronous callbacks the first eviction would block
+	const inserted = 2 * evictCallbackQueueSize
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		for i := 0; i < inserted; i++ {
+			_ = cache.Set(fmt.Sprintf("async_cb_key_%d", i), make([]byte, 150))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("Set blocked on a slow OnEvict handler")
+	}
+	t.Logf("%d writes in %v with a blocked OnEvict", inserted, time.Since(start))
+
+	stats := cache.GetStats()
+	if stats.Evictions == 0 {
+		t.Fatal("Expected evictions")
+	}
+	if stats.DroppedEvictCallbacks == 0 {
+		t.Error("Expected callbacks to be dropped once the queue filled")
+	}
+
+	close(release)
+	cache.Close()
+
+	if got := atomic.LoadInt64(&calls) + stats.DroppedEvictCallbacks; got != stats.Evictions {
+		t.Errorf("Expected calls plus dropped to equal %d evictions, got %d", stats.Evictions, got)
+	}
+}
+
+func TestCloseWithDrain(t *testing.T) {
+	var mu sync.Mutex
+	drained := make(map[string]interface{})
+
+	config := DefaultConfig()
+	config.OnEvict = func(key string, value interface{}) {
+		mu.Lock()
+		drained[key] = value
+		mu.Unlock()
+	}
+
+	cache := New(config)
+
+	const n = 500
+	for i := 0; i < n; i++ {
+		_ = cache.Set(fmt.Sprintf("drain_key_%d", i), i)
+	}
+
+	if err := cache.CloseWithDrain(); err != nil {
+		t.Fatalf("CloseWithDrain failed: %v", err)
+	}
+
+	if len(drained) != n {
+		t.Errorf("Expected OnEvict to fire %d times, got %d", n, len(drained))
+	}
+	if drained["drain_key_42"] != 42 {
+		t.Errorf("Expected drained value 42, got %v", drained["drain_key_42"])
+	}
+
+	if err := cache.Set("late", "value"); !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Expected ErrCacheClosed after drain, got %v", err)
+	}
+	if err := cache.CloseWithDrain(); err != ErrCacheClosed {
+		t.Errorf("Expected ErrCacheClosed on second drain, got %v", err)
+	}
+}
+
+func TestSetOnExpiredEntryAccounting(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 4
+	config.DefaultTTL = 0
+	cache := New(config)
+	defer cache.Close()
+
+	const keys = 50
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("expiring_key_%d", i%keys)
+				_ = cache.Set(key, fmt.Sprintf("short_%d_%d", workerID, i), time.Nanosecond)
+				time.Sleep(time.Microsecond)
+				cache.Get(key) // schedules a lazy delete of the expired entry
+				_ = cache.Set(key, make([]byte, workerID*10+i))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Let any pending lazy deletes finish
+	time.Sleep(50 * time.Millisecond)
+
+	var liveSize int64
+	for _, shard := range cache.allShards() {
+		shard.mu.RLock()
+		for _, entry := range shard.data {
+			liveSize += entry.size
+		}
+		shard.mu.RUnlock()
+	}
+
+	stats := cache.GetStats()
+	if stats.TotalSize != liveSize {
+		t.Errorf("Total size %d drifted from live entry sum %d", stats.TotalSize, liveSize)
+	}
+
+	// The final non-expiring Set of every key must survive stale lazy deletes
+	if stats.TotalEntries != keys {
+		t.Errorf("Expected %d live entries, got %d", keys, stats.TotalEntries)
+	}
+}
+
+// fakeCacher is a minimal Cacher used to verify the interface can be implemented
+type fakeCacher struct {
+	data map[string]interface{}
+}
+
+func (f *fakeCacher) Get(key string) (interface{}, bool) {
+	v, ok := f.data[key]
+	return v, ok
+}
+
+func (f *fakeCacher) Set(key string, value interface{}, ttl ...time.Duration) error {
+	f.data[key] = value
+	return nil
+}
+
+func (f *fakeCacher) Delete(key string) bool {
+	_, ok := f.data[key]
+	delete(f.data, key)
+	return ok
+}
+
+func (f *fakeCacher) Clear()       { f.data = make(map[string]interface{}) }
+func (f *fakeCacher) Close() error { return nil }
+
+func TestCacherInterface(t *testing.T) {
+	var _ Cacher = (*Cache)(nil)
+	var _ Cacher = (*fakeCacher)(nil)
+
+	for name, c := range map[string]Cacher{
+		"cache": New(DefaultConfig()),
+		"fake":  &fakeCacher{data: make(map[string]interface{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer c.Close()
+
+			if err := c.Set("key", "value"); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			if v, ok := c.Get("key"); !ok || v != "value" {
+				t.Errorf("Expected 'value', got %v, %v", v, ok)
+			}
+			if !c.Delete("key") {
+				t.Error("Delete failed")
+			}
+		})
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	config.CleanupInterval = time.Hour // keep the background cleanup out of the way
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 30; i++ {
+		_ = cache.Set(fmt.Sprintf("short_key_%d", i), i, 20*time.Millisecond)
+	}
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("long_key_%d", i), i)
+	}
+
+	if n := cache.Len(); n != 50 {
+		t.Fatalf("Expected Len 50, got %d", n)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if removed := cache.DeleteExpired(); removed != 30 {
+		t.Errorf("Expected 30 expired entries removed, got %d", removed)
+	}
+	if n := cache.Len(); n != 20 {
+		t.Errorf("Expected Len 20 after DeleteExpired, got %d", n)
+	}
+	if removed := cache.DeleteExpired(); removed != 0 {
+		t.Errorf("Expected nothing left to remove, got %d", removed)
+	}
+}
+
+func TestCleanupTimeBudget(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:     1024 * 1024 * 1024,
+		ShardCount:         1, // a single huge shard
+		DefaultTTL:         0,
+		CleanupInterval:    time.Hour,
+		CleanupMaxDuration: time.Millisecond,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	const total = 200000
+	items := make([]Item, total)
+	for i := range items {
+		items[i] = Item{Key: fmt.Sprintf("budget_key_%d", i), Value: i}
+		if i%2 == 0 {
+			items[i].TTL = time.Millisecond
+		}
+	}
+	_ = cache.SetItems(items)
+	time.Sleep(5 * time.Millisecond)
+
+	// A single pass reclaims every expired entry, releasing the shard lock
+	// between chunks so other operations see the shard partway through
+	shard := cache.allShards()[0]
+	done := make(chan int)
+	go func() { done <- cache.cleanupShard(shard, config.CleanupMaxDuration) }()
+
+	var removed int
+	partial := false
+	for finished := false; !finished; {
+		select {
+		case removed = <-done:
+			finished = true
+		default:
+			if n := cache.Len(); n > total/2 && n < total {
+				partial = true
+			}
+		}
+	}
+
+	if removed != total/2 || cache.Len() != total/2 {
+		t.Errorf("Expected one pass to remove %d entries, removed %d with %d left", total/2, removed, cache.Len())
+	}
+	if !partial {
+		t.Error("Expected the shard lock to be released between cleanup chunks")
+	}
+}
+
+func TestDisableBackgroundCleanup(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	config.CleanupInterval = time.Millisecond
+	config.DisableBackgroundCleanup = true
+
+	before := runtime.NumGoroutine()
+	cache := New(config)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected no new goroutines, went from %d to %d", before, after)
+	}
+
+	_ = cache.Set("expiring", "value", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	// Nothing reclaims the entry in the background
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Expected expired entry to remain until explicit cleanup, Len=%d", n)
+	}
+	if removed := cache.DeleteExpired(); removed != 1 {
+		t.Errorf("Expected DeleteExpired to remove 1 entry, removed %d", removed)
+	}
+
+	done := make(chan error)
+	go func() { done <- cache.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked without a cleanup goroutine")
+	}
+}
+
+func TestOnMemoryPressure(t *testing.T) {
+	var calls int64
+	var mu sync.Mutex
+	var gotUsed, gotMax int64
+
+	config := &Config{
+		MaxMemoryBytes:                 16 * 1024, // 16KB
+		ShardCount:                     4,
+		DefaultTTL:                     0,
+		CleanupInterval:                time.Second,
+		MemoryPressureThresholdPercent: 75,
+		OnMemoryPressure: func(usedBytes, maxBytes int64) {
+			atomic.AddInt64(&calls, 1)
+			mu.Lock()
+			gotUsed, gotMax = usedBytes, maxBytes
+			mu.Unlock()
+		},
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	// Stay below the threshold
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("pressure_key_%d", i), make([]byte, 200))
+	}
+	if n := atomic.LoadInt64(&calls); n != 0 {
+		t.Fatalf("Expected no pressure callback below threshold, got %d", n)
+	}
+
+	// Fill well past the threshold
+	for i := 20; i < 200; i++ {
+		_ = cache.Set(fmt.Sprintf("pressure_key_%d", i), make([]byte, 200))
+	}
+
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Errorf("Expected exactly 1 throttled callback, got %d", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if gotMax != config.MaxMemoryBytes {
+		t.Errorf("Expected maxBytes %d, got %d", config.MaxMemoryBytes, gotMax)
+	}
+	if gotUsed < config.MaxMemoryBytes*75/100 {
+		t.Errorf("Expected usedBytes above threshold, got %d", gotUsed)
+	}
+}
+
+func TestEvictionTargetPercent(t *testing.T) {
+	var evictions int64
+	config := &Config{
+		MaxMemoryBytes:        64 * 1024, // 64KB
+		ShardCount:            16,
+		DefaultTTL:            0,
+		CleanupInterval:       time.Second,
+		EvictionTargetPercent: 80,
+		OnEvict: func(key string, value interface{}) {
+			atomic.AddInt64(&evictions, 1)
+		},
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	value := make([]byte, 256)
+	i := 0
+	for atomic.LoadInt64(&evictions) == 0 {
+		cache.Set(fmt.Sprintf("key_%d", i), value)
+		i++
+	}
+
+	target := config.MaxMemoryBytes * 80 / 100
+	if size := cache.GetStats().TotalSize; size > target {
+		t.Fatalf("Expected usage at or below target %d after eviction, got %d", target, size)
+	}
+
+	// The freed headroom absorbs further writes without evicting again
+	last := fmt.Sprintf("key_%d", i-1)
+	entrySize := cache.getShard(last).data[last].size
+	headroom := int((config.MaxMemoryBytes - cache.GetStats().TotalSize) / entrySize)
+	evicted := atomic.LoadInt64(&evictions)
+	for j := 0; j < headroom-1; j++ {
+		cache.Set(fmt.Sprintf("key_%d", i), value)
+		i++
+	}
+	if got := atomic.LoadInt64(&evictions); got != evicted {
+		t.Errorf("Expected no evictions while refilling headroom, got %d more", got-evicted)
+	}
+}
+
+func TestShardIndexSeeded(t *testing.T) {
+	newSeeded := func(seed uint32) *Cache {
+		config := DefaultConfig()
+		config.ShardCount = 64
+		config.HashSeed = seed
+		return New(config)
+	}
+
+	a := newSeeded(42)
+	defer a.Close()
+	b := newSeeded(42)
+	defer b.Close()
+	other := newSeeded(7)
+	defer other.Close()
+
+	moved := 0
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		idx := a.ShardIndex(key)
+		if idx < 0 || idx >= 64 {
+			t.Fatalf("ShardIndex(%q) = %d out of range", key, idx)
+		}
+		if idx != a.ShardIndex(key) || idx != b.ShardIndex(key) {
+			t.Errorf("ShardIndex(%q) is not stable for the same seed", key)
+		}
+		if idx != other.ShardIndex(key) {
+			moved++
+		}
+
+		a.Set(key, i)
+		if _, exists := a.allShards()[idx].data[key]; !exists {
+			t.Errorf("Key %q was not stored in shard %d", key, idx)
+		}
+	}
+
+	if moved == 0 {
+		t.Error("Expected a different seed to change shard placement")
+	}
+}
+
+func TestRandomHashSeed(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 64
+
+	cache := New(config)
+	defer cache.Close()
+	if cache.seed == 0 || cache.seed != processHashSeed {
+		t.Errorf("Expected an unseeded cache to use the process seed, got %d", cache.seed)
+	}
+
+	// Simulate two processes drawing different random seeds
+	seedA, seedB := newHashSeed(), newHashSeed()
+	for seedA == seedB {
+		seedB = newHashSeed()
+	}
+	config.HashSeed = seedA
+	a := New(config)
+	defer a.Close()
+	config.HashSeed = seedB
+	b := New(config)
+	defer b.Close()
+
+	moved := 0
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		if a.ShardIndex(key) != b.ShardIndex(key) {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("Expected different random seeds to place keys on different shards")
+	}
+
+	// Salting must not hurt distribution: no shard far above the mean
+	counts := make([]int, config.ShardCount)
+	const keys = 64000
+	for i := 0; i < keys; i++ {
+		counts[a.ShardIndex(fmt.Sprintf("user:%d", i))]++
+	}
+	mean := keys / config.ShardCount
+	for shard, count := range counts {
+		if count > mean*3/2 || count < mean/2 {
+			t.Errorf("Shard %d holds %d keys, expected about %d", shard, count, mean)
+		}
+	}
+}
+
+func TestNewMissingConfigFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		config *Config
+	}{
+		{
+			name:   "missing memory",
+			field:  "MaxMemoryBytes",
+			config: &Config{ShardCount: 16, CleanupInterval: time.Minute},
+		},
+		{
+			name:   "missing shard count",
+			field:  "ShardCount",
+			config: &Config{MaxMemoryBytes: 1024 * 1024, CleanupInterval: time.Minute},
+		},
+		{
+			name:   "missing cleanup interval",
+			field:  "CleanupInterval",
+			config: &Config{MaxMemoryBytes: 1024 * 1024, ShardCount: 16},
+		},
+		{
+			name:   "empty config",
+			field:  "MaxMemoryBytes",
+			config: &Config{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.config.ShardCount
+
+			cache := New(tt.config)
+			defer cache.Close()
+
+			if err := cache.Set("key", "value"); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			if value, exists := cache.Get("key"); !exists || value.(string) != "value" {
+				t.Errorf("Expected value, got %v, %v", value, exists)
+			}
+			if err := cache.config.Validate(); err != nil {
+				t.Errorf("Expected normalized config to be valid, got %v", err)
+			}
+			if tt.config.ShardCount != original {
+				t.Error("New should not modify the caller's config")
+			}
+
+			_, err := NewWithError(tt.config)
+			var configErr ErrInvalidConfig
+			if !errors.As(err, &configErr) || configErr.Field != tt.field {
+				t.Errorf("Expected ErrInvalidConfig for %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	cache, err := NewWithError(DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewWithError failed for a valid config: %v", err)
+	}
+	cache.Close()
+}
+
+func TestRejectOnFull(t *testing.T) {
+	var evictions int64
+	config := &Config{
+		MaxMemoryBytes:  16 * 1024, // 16KB
+		ShardCount:      4,
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+		RejectOnFull:    true,
+		OnEvict: func(key string, value interface{}) {
+			atomic.AddInt64(&evictions, 1)
+		},
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	value := make([]byte, 256)
+	stored := 0
+	var err error
+	for ; stored < 1000; stored++ {
+		if err = cache.Set(fmt.Sprintf("key_%d", stored), value); err != nil {
+			break
+		}
+	}
+
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("Expected ErrMemoryLimitExceeded once full, got %v", err)
+	}
+	if !IsTemporaryError(err) {
+		t.Error("Expected a rejected write to be a temporary error")
+	}
+	if err := cache.Set("another", value); !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Errorf("Expected subsequent Sets to be rejected, got %v", err)
+	}
+
+	// Existing entries are untouched
+	if n := atomic.LoadInt64(&evictions); n != 0 {
+		t.Errorf("Expected no evictions, got %d", n)
+	}
+	stats := cache.GetStats()
+	if stats.TotalEntries != int64(stored) || stats.TotalSize > config.MaxMemoryBytes {
+		t.Errorf("Expected %d entries within the limit, got %+v", stored, stats)
+	}
+	for i := 0; i < stored; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key_%d", i)); !exists {
+			t.Fatalf("key_%d should still be present", i)
+		}
+	}
+
+	// Growing an existing entry is rejected and keeps the old value
+	if err := cache.Set("key_0", make([]byte, 4096)); !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Errorf("Expected growing update to be rejected, got %v", err)
+	}
+	if got, _ := cache.Get("key_0"); len(got.([]byte)) != 256 {
+		t.Errorf("Expected key_0 to keep its value, got %d bytes", len(got.([]byte)))
+	}
+
+	// Freeing memory makes room again
+	cache.Delete("key_0")
+	if err := cache.Set("another", value); err != nil {
+		t.Errorf("Expected Set to succeed after Delete, got %v", err)
+	}
+}
+
+func TestCleanupParallelismCoversAllShards(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 64
+	config.CleanupInterval = 10 * time.Millisecond
+	config.CleanupParallelism = 4
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 5000; i++ {
+		_ = cache.Set(fmt.Sprintf("parallel_key_%d", i), i, 20*time.Millisecond)
+	}
+	_ = cache.Set("live", "value")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.Len() > 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every shard to be swept, %d entries remain", cache.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !cache.Has("live") {
+		t.Error("Expected live entry to survive cleanup")
+	}
+}
+
+func TestCleanupParallelismStripes(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 1024 * 1024 * 1024
+	config.ShardCount = 64
+	config.DisableBackgroundCleanup = true
+	cache := New(config)
+	defer cache.Close()
+
+	const total = 20000
+	items := make([]Item, total)
+	for i := range items {
+		items[i] = Item{Key: fmt.Sprintf("churn_key_%d", i), Value: i, TTL: time.Millisecond}
+	}
+	_ = cache.SetItems(items)
+	time.Sleep(5 * time.Millisecond)
+
+	// Each worker sweeps only its own stripe of shards, and together the
+	// stripes cover every shard exactly once
+	const workers = 4
+	shards := cache.allShards()
+	removed := 0
+	for w := 0; w < workers; w++ {
+		n := cache.cleanupStripe(w, workers, 0)
+		if n == 0 {
+			t.Errorf("Expected worker %d to reclaim entries from its stripe", w)
+		}
+		removed += n
+
+		for i, shard := range shards {
+			shard.mu.RLock()
+			left := len(shard.data)
+			shard.mu.RUnlock()
+			if swept := i%workers <= w; swept != (left == 0) {
+				t.Fatalf("After worker %d, shard %d has %d entries", w, i, left)
+			}
+		}
+	}
+
+	if removed != total || cache.Len() != 0 {
+		t.Errorf("Expected the stripes to reclaim all %d entries, removed %d with %d left", total, removed, cache.Len())
 	}
 }
@@ -0,0 +1,73 @@
+package fastcache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for expiry tests
+type fakeClock struct {
+	now int64
+}
+
+// Now returns the fake time in Unix nanoseconds
+func (f *fakeClock) Now() int64 {
+	return atomic.LoadInt64(&f.now)
+}
+
+// Advance moves the fake time forward by d
+func (f *fakeClock) Advance(d time.Duration) {
+	atomic.AddInt64(&f.now, int64(d))
+}
+
+// newWithFakeClock creates a cache whose expiry logic reads a fake clock
+// starting at the current wall-clock time
+func newWithFakeClock(config *Config) (*Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Now().UnixNano()}
+	return newCache(config, clock.Now), clock
+}
+
+func TestFakeClockExpiry(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	config.DisableBackgroundCleanup = true
+	cache, clock := newWithFakeClock(config)
+	defer cache.Close()
+
+	_ = cache.Set("session", "token", time.Hour)
+	_ = cache.Set("forever", "value")
+	_ = cache.Set("short", "value", time.Minute)
+
+	clock.Advance(59 * time.Minute)
+	if _, found := cache.Get("session"); !found {
+		t.Error("Expected session to be live before its TTL")
+	}
+	if _, found := cache.Get("short"); found {
+		t.Error("Expected short to expire after the clock passed its TTL")
+	}
+	if info, _ := cache.EntryInfo("session"); info == nil || info.TTL != time.Minute {
+		t.Errorf("Expected exactly a minute left, got %+v", info)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, found := cache.Get("session"); found {
+		t.Error("Expected session to expire once the clock passed its TTL")
+	}
+	cache.DeleteExpired()
+	if stats := cache.GetStats(); stats.TotalEntries != 1 {
+		t.Errorf("Expected cleanup to leave only the entry without TTL, got %d entries", stats.TotalEntries)
+	}
+	if _, found := cache.Get("forever"); !found {
+		t.Error("Expected entry without TTL to survive")
+	}
+
+	// Absolute expiries are judged by the same clock
+	if err := cache.SetExpireAt("aligned", "value", time.Unix(0, clock.Now()).Add(time.Second)); err != nil {
+		t.Fatalf("SetExpireAt failed: %v", err)
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := cache.Get("aligned"); found {
+		t.Error("Expected absolute expiry to follow the fake clock")
+	}
+}
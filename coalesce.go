@@ -0,0 +1,49 @@
+package fastcache
+
+import (
+	"reflect"
+	"time"
+)
+
+// pendingSet is an in-flight Set that identical concurrent Sets wait on
+// when CoalesceSets is enabled
+type pendingSet struct {
+	value    interface{}
+	ttl      time.Duration
+	done     chan struct{}
+	err      error
+	finished bool // the write returned rather than panicked
+}
+
+// coalesceSet implements Set with CoalesceSets. The first Set of a key
+// writes it; Sets of the same key, value and TTL that arrive before that
+// write is applied return its result without writing. The pending write is
+// withdrawn under the shard lock just before it is applied, so a Set arriving
+// later always writes and cannot be lost behind a newer value. A Set with a
+// different value or TTL writes as usual.
+func (c *Cache) coalesceSet(key string, value interface{}, ttl time.Duration) error {
+	call := &pendingSet{value: value, ttl: ttl, done: make(chan struct{})}
+	if pending, loaded := c.pendingSets.LoadOrStore(key, call); loaded {
+		leader := pending.(*pendingSet)
+		if leader.ttl == ttl && reflect.DeepEqual(leader.value, value) {
+			<-leader.done
+			if leader.finished {
+				return leader.err
+			}
+		}
+		return c.setTTL(key, value, ttl)
+	}
+
+	// Withdraw the write and release the waiters even if it fails early or
+	// panics; waiters of a panicked write fall back to writing themselves
+	defer func() {
+		c.pendingSets.CompareAndDelete(key, call)
+		close(call.done)
+	}()
+
+	_, call.err = c.setWith("set", key, value, c.expiryFor(ttl), true, func() {
+		c.pendingSets.CompareAndDelete(key, call)
+	})
+	call.finished = true
+	return call.err
+}
@@ -0,0 +1,163 @@
+package fastcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingWrites runs sets concurrent Sets of one key and value, with the
+// key's shard locked until they have all started, and returns how many
+// writes reached the cache
+func countingWrites(t *testing.T, coalesce bool, sets int) int64 {
+	config := DefaultConfig()
+	config.CoalesceSets = coalesce
+	cache := New(config)
+	defer cache.Close()
+
+	var writes int64
+	cache.AddReplica(func(ev CacheEvent) { atomic.AddInt64(&writes, 1) })
+
+	value := []byte("freshly computed")
+	shard := cache.lockShard("hot")
+	var started, done sync.WaitGroup
+	for i := 0; i < sets; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			if err := cache.Set("hot", value, time.Minute); err != nil {
+				t.Errorf("Set failed: %v", err)
+			}
+		}()
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond) // let every Set reach the shard lock
+	shard.mu.Unlock()
+	done.Wait()
+
+	if got, found := cache.Get("hot"); !found || string(got.([]byte)) != string(value) {
+		t.Errorf("Expected the value to be stored, got %v (found=%v)", got, found)
+	}
+	return atomic.LoadInt64(&writes)
+}
+
+func TestCoalesceSets(t *testing.T) {
+	const sets = 50
+	naive := countingWrites(t, false, sets)
+	coalesced := countingWrites(t, true, sets)
+	t.Logf("%d identical Sets: %d writes naive, %d coalesced", sets, naive, coalesced)
+
+	if naive != sets {
+		t.Errorf("Expected every Set to write without coalescing, got %d writes", naive)
+	}
+	if coalesced >= naive/2 {
+		t.Errorf("Expected coalescing to collapse most writes, got %d of %d", coalesced, sets)
+	}
+}
+
+func TestCoalesceSetsDifferentValues(t *testing.T) {
+	config := DefaultConfig()
+	config.CoalesceSets = true
+	cache := New(config)
+	defer cache.Close()
+
+	var writes int64
+	cache.AddReplica(func(ev CacheEvent) { atomic.AddInt64(&writes, 1) })
+
+	// A Set with a different value or TTL never joins an in-flight write
+	shard := cache.lockShard("key")
+	var wg sync.WaitGroup
+	for _, set := range []func() error{
+		func() error { return cache.Set("key", "a") },
+		func() error { return cache.Set("key", "b") },
+		func() error { return cache.Set("key", "a", time.Hour) },
+	} {
+		wg.Add(1)
+		go func(set func() error) {
+			defer wg.Done()
+			_ = set()
+		}(set)
+	}
+	time.Sleep(20 * time.Millisecond)
+	shard.mu.Unlock()
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&writes); n != 3 {
+		t.Errorf("Expected 3 writes, got %d", n)
+	}
+}
+
+func TestCoalesceSetsAfterWrite(t *testing.T) {
+	config := DefaultConfig()
+	config.CoalesceSets = true
+	cache := New(config)
+	defer cache.Close()
+
+	// The replica hook runs after the first write is applied but before its
+	// Set returns; a Set of the same value from there must write again rather
+	// than join the finished write, or it would be lost behind "v2"
+	var first int32
+	nested := make(chan error, 1)
+	cache.AddReplica(func(ev CacheEvent) {
+		if atomic.CompareAndSwapInt32(&first, 0, 1) {
+			go func() {
+				if err := cache.Set("key", "v2"); err != nil {
+					nested <- err
+					return
+				}
+				nested <- cache.Set("key", "v1")
+			}()
+			select {
+			case err := <-nested:
+				nested <- err
+			case <-time.After(time.Second):
+				nested <- errors.New("nested Set blocked on the finished write")
+			}
+		}
+	})
+
+	if err := cache.Set("key", "v1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := <-nested; err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cache.Get("key"); value != "v1" {
+		t.Errorf("Expected the last Set to win, got %v", value)
+	}
+}
+
+func TestCoalesceSetsPanic(t *testing.T) {
+	config := DefaultConfig()
+	config.CoalesceSets = true
+	cache := New(config)
+	defer cache.Close()
+
+	var panicked int32
+	cache.AddReplica(func(ev CacheEvent) {
+		if atomic.CompareAndSwapInt32(&panicked, 0, 1) {
+			panic("replica failed")
+		}
+	})
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = cache.Set("key", "value")
+	}()
+
+	// A panicked write must not leave later Sets of the key waiting on it
+	done := make(chan error, 1)
+	go func() { done <- cache.Set("key", "value") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Set failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Set blocked on a panicked write")
+	}
+}
@@ -0,0 +1,60 @@
+package fastcache
+
+import "encoding/json"
+
+// Codec encodes and decodes cached values when Config.Codec is set
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v *interface{}) error
+}
+
+// JSONCodec is a Codec using encoding/json. Decoded values take the generic
+// JSON forms: structs and maps decode as map[string]interface{}, numbers as float64.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v
+func (JSONCodec) Unmarshal(data []byte, v *interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// encodedValue wraps a value encoded by the configured Codec. data holds the
+// encoded bytes, or a *compressedValue when they were compressed.
+type encodedValue struct {
+	data interface{}
+}
+
+// size returns the stored length of the encoded value
+func (v *encodedValue) size() int64 {
+	if cv, ok := v.data.(*compressedValue); ok {
+		return int64(len(cv.data))
+	}
+	return int64(len(v.data.([]byte)))
+}
+
+// encode marshals value with the configured codec and compresses the result
+func (c *Cache) encode(value interface{}) (interface{}, error) {
+	data, err := c.config.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedValue{data: c.compress(data)}, nil
+}
+
+// decode returns a fresh copy of an encoded value
+func (c *Cache) decode(v *encodedValue) (interface{}, bool) {
+	raw, ok := c.decompress(v.data)
+	if !ok {
+		return nil, false
+	}
+
+	var value interface{}
+	if err := c.config.Codec.Unmarshal(raw.([]byte), &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
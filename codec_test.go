@@ -0,0 +1,98 @@
+package fastcache
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type codecProfile struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestJSONCodec(t *testing.T) {
+	config := DefaultConfig()
+	config.Codec = JSONCodec{}
+	cache := New(config)
+	defer cache.Close()
+
+	profile := codecProfile{Name: "alice", Tags: []string{"admin", "beta"}}
+	if err := cache.Set("profile", profile); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Mutating the caller's value does not affect the cached copy
+	profile.Tags[0] = "mutated"
+
+	value, exists := cache.Get("profile")
+	if !exists {
+		t.Fatal("Expected profile to be cached")
+	}
+	decoded := value.(map[string]interface{})
+	if decoded["name"] != "alice" {
+		t.Errorf("Expected name alice, got %v", decoded["name"])
+	}
+	tags := decoded["tags"].([]interface{})
+	if len(tags) != 2 || tags[0] != "admin" {
+		t.Errorf("Expected cached tags to be unaffected by mutation, got %v", tags)
+	}
+
+	// Mutating a returned value does not affect the cached copy either
+	tags[0] = "mutated"
+	value, _ = cache.Get("profile")
+	if got := value.(map[string]interface{})["tags"].([]interface{})[0]; got != "admin" {
+		t.Errorf("Expected cached copy to be isolated from readers, got %v", got)
+	}
+
+	// Size accounting uses the encoded length
+	encoded, _ := json.Marshal(codecProfile{Name: "alice", Tags: []string{"admin", "beta"}})
+	expected := int64(len("profile")+len(encoded)) + 64
+	if size := cache.getShard("profile").data["profile"].size; size != expected {
+		t.Errorf("Expected size %d, got %d", expected, size)
+	}
+}
+
+func TestCodecMarshalError(t *testing.T) {
+	config := DefaultConfig()
+	config.Codec = JSONCodec{}
+	cache := New(config)
+	defer cache.Close()
+
+	err := cache.Set("bad", make(chan int))
+
+	var opErr ErrOperationFailed
+	if !errors.As(err, &opErr) || opErr.Key != "bad" {
+		t.Fatalf("Expected ErrOperationFailed for key bad, got %v", err)
+	}
+	var jsonErr *json.UnsupportedTypeError
+	if !errors.As(err, &jsonErr) {
+		t.Errorf("Expected the codec error to be wrapped, got %v", err)
+	}
+	if _, exists := cache.Get("bad"); exists {
+		t.Error("A value that fails to encode should not be stored")
+	}
+}
+
+func TestCodecWithCompression(t *testing.T) {
+	config := DefaultConfig()
+	config.Codec = JSONCodec{}
+	config.CompressValuesOver = 64
+	cache := New(config)
+	defer cache.Close()
+
+	long := make([]string, 100)
+	for i := range long {
+		long[i] = "repeated"
+	}
+	cache.Set("long", long)
+
+	if _, ok := cache.getShard("long").data["long"].value.(*encodedValue).data.(*compressedValue); !ok {
+		t.Error("Expected the encoded value to be compressed")
+	}
+
+	value, exists := cache.Get("long")
+	if !exists || len(value.([]interface{})) != 100 {
+		t.Errorf("Expected 100 decoded elements, got %v, %v", value, exists)
+	}
+}
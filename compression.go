@@ -0,0 +1,111 @@
+package fastcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// Compressor compresses and decompresses cached values
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor is a Compressor using gzip at the given level.
+// A zero Level uses gzip.DefaultCompression.
+type GzipCompressor struct {
+	Level int
+}
+
+// Compress compresses data with gzip
+func (g GzipCompressor) Compress(data []byte) ([]byte, error) {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses gzip data
+func (g GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// compressedValue wraps a compressed string or []byte value
+type compressedValue struct {
+	data     []byte
+	isString bool
+}
+
+// compressor returns the configured compressor, defaulting to gzip
+func (c *Cache) compressor() Compressor {
+	if c.config.Compressor != nil {
+		return c.config.Compressor
+	}
+	return GzipCompressor{}
+}
+
+// compress compresses string and []byte values above CompressValuesOver.
+// Other values, and values that do not shrink, are returned unchanged.
+func (c *Cache) compress(value interface{}) interface{} {
+	if c.config.CompressValuesOver <= 0 {
+		return value
+	}
+
+	var raw []byte
+	isString := false
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+		isString = true
+	default:
+		return value
+	}
+
+	if len(raw) <= c.config.CompressValuesOver {
+		return value
+	}
+
+	data, err := c.compressor().Compress(raw)
+	if err != nil || len(data) >= len(raw) {
+		return value
+	}
+	return &compressedValue{data: data, isString: isString}
+}
+
+// decompress returns the original value for a compressed value
+func (c *Cache) decompress(value interface{}) (interface{}, bool) {
+	cv, ok := value.(*compressedValue)
+	if !ok {
+		return value, true
+	}
+
+	raw, err := c.compressor().Decompress(cv.data)
+	if err != nil {
+		return nil, false
+	}
+	if cv.isString {
+		return string(raw), true
+	}
+	return raw, true
+}
@@ -0,0 +1,60 @@
+package fastcache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	config := DefaultConfig()
+	config.CompressValuesOver = 256
+	cache := New(config)
+	defer cache.Close()
+
+	payload := strings.Repeat(`{"id":42,"name":"widget","tags":["a","b","c"]},`, 200)
+
+	_ = cache.Set("json_string", payload)
+	_ = cache.Set("json_bytes", []byte(payload))
+	_ = cache.Set("small", "tiny value")
+
+	uncompressedSize := calculateSize("json_string", payload)
+	if size := cache.getShard("json_string").data["json_string"].size; size >= uncompressedSize/4 {
+		t.Errorf("Expected compressed size well below %d, got %d", uncompressedSize, size)
+	}
+
+	value, exists := cache.Get("json_string")
+	if !exists || value.(string) != payload {
+		t.Error("Compressed string did not round-trip")
+	}
+
+	value, exists = cache.Get("json_bytes")
+	if !exists || !bytes.Equal(value.([]byte), []byte(payload)) {
+		t.Error("Compressed bytes did not round-trip")
+	}
+
+	value, exists = cache.Get("small")
+	if !exists || value.(string) != "tiny value" {
+		t.Error("Value below threshold did not round-trip")
+	}
+
+	if stats := cache.GetStats(); stats.TotalSize >= 2*uncompressedSize {
+		t.Errorf("Expected total size to reflect compression, got %d", stats.TotalSize)
+	}
+}
+
+func TestCompressionIncompressible(t *testing.T) {
+	config := DefaultConfig()
+	config.CompressValuesOver = 16
+	cache := New(config)
+	defer cache.Close()
+
+	// Random-looking data does not shrink and is stored as-is
+	value := []byte("q8Z!k2@Lp0#xV7&mW4$rT9^bN1*yH6(e")
+	_ = cache.Set("random", value)
+
+	got, _ := cache.Get("random")
+	if !bytes.Equal(got.([]byte), value) {
+		t.Error("Incompressible value did not round-trip")
+	}
+}
@@ -0,0 +1,146 @@
+package fastcache
+
+import (
+	"math"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// int64Type is the type of values stored by SetIfGreater
+var int64Type = reflect.TypeOf(int64(0))
+
+// SetIfGreater stores value only if it is strictly greater than the int64
+// currently stored under key, treating a missing or expired entry as negative
+// infinity. It returns whether the value was stored and the value now current.
+// If the stored value is not an int64, nothing is written and an error
+// wrapping ErrTypeMismatch is returned.
+func (c *Cache) SetIfGreater(key string, value int64, ttl ...time.Duration) (stored bool, current int64, err error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false, 0, opError("set_if_greater", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return false, 0, opError("set_if_greater", key, err)
+	}
+	if err := c.checkValueType(int64Type); err != nil {
+		return false, 0, opError("set_if_greater", key, err)
+	}
+
+	expiry := c.expiryFor(firstTTL(ttl))
+
+	shard := c.lockShard(key)
+
+	current = math.MinInt64
+	if existing, exists := shard.data[key]; exists && !existing.isExpired(c.now()) {
+		existingValue, ok := existing.value.(int64)
+		if existing.isBytes || !ok {
+			shard.mu.Unlock()
+			return false, 0, opError("set_if_greater", key, ErrTypeMismatch)
+		}
+		current = existingValue
+	}
+
+	if value <= current {
+		shard.mu.Unlock()
+		return false, current, nil
+	}
+
+	sizeDiff, err := c.setLocked(shard, key, value, expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return false, current, opError("set_if_greater", key, err)
+	}
+
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return true, value, nil
+}
+
+// CompareAndSwap replaces the value stored under key with new only if the
+// current value equals old according to reflect.DeepEqual, keeping the entry's
+// expiry. It returns whether the swap happened; a missing or expired key, or a
+// new value the cache cannot store, never swaps.
+func (c *Cache) CompareAndSwap(key string, old, new interface{}) bool {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false
+	}
+
+	prepared, err := c.prepareValue(new)
+	if err != nil {
+		return false
+	}
+
+	shard := c.lockShard(key)
+	existing, exists := shard.data[key]
+	if !exists || existing.isExpired(c.now()) {
+		shard.mu.Unlock()
+		return false
+	}
+	current, ok := c.loadValue(existing.payload.get())
+	if !ok || !reflect.DeepEqual(current, old) {
+		shard.mu.Unlock()
+		return false
+	}
+
+	sizeDiff, err := c.setLocked(shard, key, prepared, existing.expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return false
+	}
+
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return true
+}
+
+// SetIfChanged stores value unless it equals the live value under key
+// according to reflect.DeepEqual, and reports whether it was written. An
+// unchanged entry keeps its value, size and LRU position and only has its
+// expiry reset as Set would, so refresh loops re-setting identical values do
+// not churn the cache. Replicas receive the write as an EventSet, or the new
+// expiry of an unchanged entry as an EventExpire.
+func (c *Cache) SetIfChanged(key string, value interface{}, ttl ...time.Duration) (changed bool, err error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false, opError("set_if_changed", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return false, opError("set_if_changed", key, err)
+	}
+
+	expiry := c.expiryFor(firstTTL(ttl))
+	prepared, err := c.prepareValue(value)
+	if err != nil {
+		return false, opError("set_if_changed", key, err)
+	}
+
+	shard := c.lockShard(key)
+	if existing, exists := shard.data[key]; exists && !existing.isExpired(c.now()) {
+		if current, ok := c.loadValue(existing.payload.get()); ok && reflect.DeepEqual(current, value) {
+			existing.expiry = expiry
+			existing.lifetime = c.lifetimeFor(expiry)
+			queued := c.queueExpire(key, expiry)
+			shard.mu.Unlock()
+			if queued {
+				c.deliverReplication()
+			}
+			return false, nil
+		}
+	}
+
+	sizeDiff, err := c.setLocked(shard, key, prepared, expiry)
+	queued := err == nil && c.queueSet(key, value, expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return false, opError("set_if_changed", key, err)
+	}
+	if queued {
+		c.deliverReplication()
+	}
+
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return true, nil
+}
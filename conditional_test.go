@@ -0,0 +1,172 @@
+package fastcache
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetIfGreater(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	stored, current, err := cache.SetIfGreater("version", 5)
+	if err != nil || !stored || current != 5 {
+		t.Fatalf("Expected first write to be stored, got %v, %d, %v", stored, current, err)
+	}
+
+	stored, current, _ = cache.SetIfGreater("version", 3)
+	if stored || current != 5 {
+		t.Errorf("Expected smaller value to be rejected, got %v, %d", stored, current)
+	}
+
+	stored, current, _ = cache.SetIfGreater("version", 5)
+	if stored || current != 5 {
+		t.Errorf("Expected equal value to be rejected, got %v, %d", stored, current)
+	}
+
+	stored, current, _ = cache.SetIfGreater("version", 9)
+	if !stored || current != 9 {
+		t.Errorf("Expected larger value to be stored, got %v, %d", stored, current)
+	}
+
+	_ = cache.Set("name", "not a number")
+	if _, _, err := cache.SetIfGreater("name", 1); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+	if value, _ := cache.Get("name"); value != "not a number" {
+		t.Errorf("Mismatched value should be untouched, got %v", value)
+	}
+}
+
+func TestSetIfGreaterConcurrent(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	const producers = 16
+	const perProducer = 500
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for _, i := range rand.Perm(perProducer) {
+				_, _, _ = cache.SetIfGreater("counter", int64(i*producers+p))
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	value, _ := cache.Get("counter")
+	if max := int64(perProducer*producers - 1); value.(int64) != max {
+		t.Errorf("Expected final value %d, got %v", max, value)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	if cache.CompareAndSwap("missing", nil, "value") {
+		t.Error("Expected CompareAndSwap on a missing key to fail")
+	}
+
+	_ = cache.Set("key", []string{"a"})
+	if cache.CompareAndSwap("key", []string{"b"}, []string{"c"}) {
+		t.Error("Expected CompareAndSwap with a stale old value to fail")
+	}
+	if !cache.CompareAndSwap("key", []string{"a"}, []string{"a", "b"}) {
+		t.Error("Expected CompareAndSwap with the current value to succeed")
+	}
+	if value, _ := cache.Get("key"); !reflect.DeepEqual(value, []string{"a", "b"}) {
+		t.Errorf("Expected swapped value, got %v", value)
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+	_ = cache.Set("counter", 0)
+
+	const workers = 16
+	const perWorker = 200
+
+	// Each worker increments the counter with a CAS retry loop, so every
+	// successful swap must be from n to n+1 and no increment is lost
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				for {
+					current, _ := cache.Get("counter")
+					if cache.CompareAndSwap("counter", current, current.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := cache.Get("counter"); value != workers*perWorker {
+		t.Errorf("Expected counter %d, got %v", workers*perWorker, value)
+	}
+}
+
+func TestSetIfChanged(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	config.DisableBackgroundCleanup = true
+	cache, clock := newWithFakeClock(config)
+	defer cache.Close()
+
+	var writes, refreshes int
+	cache.AddReplica(func(ev CacheEvent) {
+		if ev.Type == EventExpire {
+			refreshes++
+		} else {
+			writes++
+		}
+	})
+
+	value := map[string]int{"a": 1, "b": 2}
+	for i := 0; i < 5; i++ {
+		changed, err := cache.SetIfChanged("config", map[string]int{"a": 1, "b": 2}, time.Minute)
+		if err != nil {
+			t.Fatalf("SetIfChanged failed: %v", err)
+		}
+		if changed != (i == 0) {
+			t.Errorf("Set %d: expected changed=%v, got %v", i, i == 0, changed)
+		}
+		clock.Advance(30 * time.Second)
+	}
+	if writes != 1 || refreshes != 4 {
+		t.Errorf("Expected 1 real write and 4 expiry refreshes, got %d and %d", writes, refreshes)
+	}
+
+	// Identical sets kept refreshing the TTL, so the entry outlived its first minute
+	if got, found := cache.Get("config"); !found || !reflect.DeepEqual(got, value) {
+		t.Errorf("Expected refreshed entry %v, got %v (found=%v)", value, got, found)
+	}
+
+	changed, err := cache.SetIfChanged("config", map[string]int{"a": 1, "b": 3})
+	if err != nil || !changed {
+		t.Errorf("Expected a different value to be written, got changed=%v err=%v", changed, err)
+	}
+	if writes != 2 {
+		t.Errorf("Expected 2 real writes, got %d", writes)
+	}
+
+	// An expired entry is rewritten even if the value is the same
+	_ = cache.Set("session", "token", time.Second)
+	clock.Advance(2 * time.Second)
+	if changed, _ := cache.SetIfChanged("session", "token"); !changed {
+		t.Error("Expected an expired entry to be rewritten")
+	}
+}
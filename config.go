@@ -1,6 +1,10 @@
 package fastcache
 
-import "time"
+import (
+	"reflect"
+	"runtime"
+	"time"
+)
 
 // Config holds configuration for the cache
 type Config struct {
@@ -15,8 +19,258 @@ type Config struct {
 	// Set to 0 for no expiration
 	DefaultTTL time.Duration
 
+	// MaxTTL caps the TTL of every write when > 0, including DefaultTTL, as a
+	// guardrail against entries that effectively never expire. Entries written
+	// without any TTL also expire after MaxTTL.
+	MaxTTL time.Duration
+
+	// ConsistentHashing maps keys to shards with jump consistent hashing instead
+	// of hash modulo ShardCount, so ReshardTo only moves the minimal fraction of
+	// keys. Shard selection costs O(log ShardCount) instead of O(1).
+	ConsistentHashing bool
+
+	// HashSeed is folded into the key hash so that keys crafted to collide
+	// cannot pile into one shard. Placement is reproducible for a given seed.
+	// Zero uses a random seed chosen once per process, so placement cannot be
+	// predicted from outside it.
+	HashSeed uint32
+
+	// ShardKeyFunc, when set, returns the part of a key used to choose its
+	// shard, so related keys can share one (e.g., everything up to the last
+	// ":" puts "order:42:items" and "order:42:total" together). Batch reads
+	// of colocated keys then take a single shard lock. Keys sharing a shard
+	// key always share a shard, so a hot or very common shard key skews load
+	// onto one shard. It must be deterministic and goroutine-safe.
+	ShardKeyFunc func(key string) string
+
 	// CleanupInterval determines how often expired entries are cleaned up
 	CleanupInterval time.Duration
+
+	// DisableBackgroundCleanup prevents New from starting the cleanup goroutine.
+	// Expired entries are then only reclaimed lazily on access or by DeleteExpired.
+	DisableBackgroundCleanup bool
+
+	// CleanupMaxDuration bounds how long the background cleanup holds a single
+	// shard's write lock at a time when > 0. A shard with more expired entries
+	// than can be removed in that time is cleaned in chunks, releasing the lock
+	// in between, so every expired entry is still removed in the same pass.
+	CleanupMaxDuration time.Duration
+
+	// CleanupParallelism is the number of background cleanup goroutines, each
+	// sweeping an interleaved subset of the shards. Values above 1 help very
+	// large, high-churn caches keep up on multi-core machines. Zero means one.
+	CleanupParallelism int
+
+	// CopyValues copies []byte values on Set and Get so cached bytes are isolated
+	// from caller mutation. This costs an allocation and copy per operation.
+	// Strings are immutable and never need copying.
+	CopyValues bool
+
+	// PrefixDelimiter enables per-prefix hit/miss tracking when set (e.g., ":").
+	// Keys are grouped by the substring before the first delimiter.
+	// This adds bookkeeping to every Get.
+	PrefixDelimiter string
+
+	// NegativeTTL is how long GetOrCompute remembers that a key was not found,
+	// suppressing recomputation. Zero disables negative caching.
+	NegativeTTL time.Duration
+
+	// StaleWindow keeps expired entries for this long past their expiry so
+	// GetStale can serve them while refreshing. Get and other reads still treat
+	// them as expired; they only stop being removed on read and by cleanup.
+	// Zero disables stale serving.
+	StaleWindow time.Duration
+
+	// LoaderFailureThreshold opens a circuit breaker around GetOrCompute's
+	// compute function after this many consecutive errors within
+	// LoaderFailureWindow when > 0. While open, misses fail fast with
+	// ErrLoaderUnavailable instead of calling compute.
+	LoaderFailureThreshold int
+
+	// LoaderFailureWindow bounds the run of consecutive compute errors counted
+	// toward LoaderFailureThreshold: an error more than this long after the
+	// previous one starts a new run, so occasional errors spread over time
+	// never open the circuit. Defaults to 1 minute when zero.
+	LoaderFailureWindow time.Duration
+
+	// LoaderOpenDuration is how long the loader circuit stays open before a
+	// single probe call is allowed through. Defaults to 5 seconds when zero.
+	LoaderOpenDuration time.Duration
+
+	// EnableRateStats starts a background sampler that records hit/miss counts
+	// so GetRateStats can report recent QPS
+	EnableRateStats bool
+
+	// CoalesceSets lets a Set that finds an in-flight Set of the same key,
+	// value and TTL wait for that write and share its result instead of
+	// rewriting the entry, so bursts of identical Sets from many goroutines
+	// collapse into one write. Values are compared with reflect.DeepEqual,
+	// which adds a map operation to every Set.
+	CoalesceSets bool
+
+	// ShardedStatsOnly counts hits and misses only per shard, dropping the two
+	// cache-wide atomic counters every Get would otherwise update. This removes
+	// a point of contention under very high read rates; in exchange, GetStats,
+	// GetPerformanceMetrics and GetRateStats sum the per-shard counters.
+	ShardedStatsOnly bool
+
+	// TrackLatency records Get/Set/Delete latency histograms reported by
+	// LatencyStats. This adds two clock reads to every tracked operation.
+	TrackLatency bool
+
+	// TrackEntryAge records when each entry was last written, for AgeStats.
+	// This adds a clock read to every write.
+	TrackEntryAge bool
+
+	// OnEvict is called for each entry evicted under memory pressure or drained
+	// by CloseWithDrain. It is invoked outside of shard locks.
+	OnEvict func(key string, value interface{})
+
+	// EvictCallbackAsync queues OnEvict calls for a single background goroutine
+	// so a slow handler never blocks eviction or writes. Callbacks run in
+	// eviction order, but with no ordering guarantee relative to later writes
+	// of the same key. When the queue is full the callback is dropped and
+	// counted in Stats.DroppedEvictCallbacks. Callbacks still queued at Close
+	// are run before Close returns; CloseWithDrain invokes OnEvict directly.
+	EvictCallbackAsync bool
+
+	// CompressValuesOver compresses string and []byte values larger than this
+	// many bytes when > 0. Values are transparently decompressed on Get and size
+	// accounting reflects the compressed size. Other value types are stored as-is.
+	CompressValuesOver int
+
+	// Codec encodes values on Set and decodes them on Get when set, so cached
+	// values are isolated from caller mutation and sized by their encoded
+	// length. Encoded values are compressed per CompressValuesOver. SetBytes and
+	// SetIfGreater store their values unencoded.
+	Codec Codec
+
+	// Compressor is used when CompressValuesOver is set. Defaults to gzip.
+	Compressor Compressor
+
+	// OverflowStore is an optional secondary tier that receives evicted entries
+	// and is consulted on Get misses
+	OverflowStore OverflowStore
+
+	// OnMemoryPressure is called when memory usage is at or above
+	// MemoryPressureThresholdPercent of MaxMemoryBytes, before eviction runs.
+	// Calls are throttled to at most one per second and made outside of locks.
+	OnMemoryPressure func(usedBytes, maxBytes int64)
+
+	// Logger is called on notable events: eviction under memory pressure,
+	// writes rejected by RejectOnFull, and the loader circuit breaker opening.
+	// keysAndValues holds alternating keys and values describing the event.
+	// Each kind of event is logged at most once per second, with the number of
+	// dropped events reported as "suppressed". It may be called while internal
+	// locks are held, so it must not call back into the cache.
+	Logger func(level, msg string, keysAndValues ...interface{})
+
+	// MemoryPressureThresholdPercent is the high-water mark for OnMemoryPressure.
+	// Defaults to 90 when zero.
+	MemoryPressureThresholdPercent int
+
+	// EvictionPolicy selects how entries are evicted under memory pressure
+	// Defaults to PolicyLRU
+	EvictionPolicy EvictionPolicy
+
+	// LRUPromotionInterval enables lazy LRU when > 0: a Get only moves an entry to
+	// the front of the LRU list if it has not been promoted within this interval.
+	// This skips most write locks on read-heavy workloads at the cost of
+	// approximate recency.
+	LRUPromotionInterval time.Duration
+
+	// EvictionSamples approximates global LRU with PolicyLRU when > 0: each
+	// eviction compares the least recently used entries of this many randomly
+	// chosen shards and evicts the oldest of them, rather than evicting from
+	// each visited shard regardless of age (e.g., 5). Larger samples are
+	// closer to exact global recency at the cost of more locking. Ignored by
+	// other policies.
+	EvictionSamples int
+
+	// MaxBackgroundGoroutines caps the short-lived goroutines the cache starts
+	// on behalf of operations, such as deleting an expired entry found by Get
+	// or refreshing a GetStale entry, when > 0. Once the cap is reached that
+	// work runs synchronously in the calling operation instead. The fixed
+	// workers enabled by other options (cleanup, async eviction, eviction
+	// callbacks, rate sampling) are not counted.
+	MaxBackgroundGoroutines int
+
+	// AsyncEviction offloads eviction from Set to a dedicated background goroutine
+	// so writers are not penalized when the cache is full
+	AsyncEviction bool
+
+	// EvictionOvershootPercent is how far (as a percentage of MaxMemoryBytes) memory
+	// may exceed the limit while waiting for async eviction before Set falls back to
+	// evicting inline. Only used when AsyncEviction is enabled.
+	EvictionOvershootPercent int
+
+	// RejectOnFull makes writes that would exceed MaxMemoryBytes fail with
+	// ErrMemoryLimitExceeded instead of evicting existing entries
+	RejectOnFull bool
+
+	// EnableMissFilter keeps a bloom filter of every key ever written so Get
+	// can answer lookups of never-written keys without a shard lookup. False
+	// positives fall through to the normal lookup. The filter is never
+	// cleared except by Reset, so its false positive rate grows as distinct
+	// keys accumulate; it is sized from ExpectedEntries when set.
+	EnableMissFilter bool
+
+	// ExpectedEntries pre-sizes each shard's map for this many entries across
+	// the cache, avoiding repeated map growth during bulk loads. Zero uses
+	// Go's default map sizing.
+	ExpectedEntries int64
+
+	// MaxKeyLength rejects writes of keys longer than this many bytes with
+	// ErrKeyTooLong. Zero allows keys of any length. Empty keys are always
+	// rejected with ErrInvalidKey.
+	MaxKeyLength int
+
+	// MaxEntrySize rejects writes of entries whose estimated size, including
+	// key and overhead, exceeds this many bytes with ErrEntryTooLarge, so one
+	// huge value cannot evict much of the cache. Zero allows any size up to
+	// MaxMemoryBytes; entries larger than the whole cache are always rejected.
+	MaxEntrySize int64
+
+	// SizeSamplingRate, when > 0, sizes values other than strings, byte
+	// slices, numbers and lists by walking everything they reference, which
+	// is far more accurate than the default fixed estimate for structs, maps
+	// and pointers but too slow to do on every write. Only this fraction of
+	// the writes of each value type (at least the first) is measured; the
+	// others are charged the average measured size of their type. Memory
+	// accounting is then right in aggregate for types whose values are of
+	// similar size, while individual entries of a type with widely varying
+	// sizes may be over- or under-charged. Must be between 0 and 1; 1
+	// measures every write.
+	SizeSamplingRate float64
+
+	// ValueType restricts the cache to values assignable to this type (e.g.,
+	// reflect.TypeOf(&User{})). Writes of any other type fail with ErrValueType.
+	// Nil accepts any value.
+	ValueType reflect.Type
+
+	// RejectNilValues makes writes of nil, including typed nil pointers, maps,
+	// slices and interfaces, fail with ErrInvalidValue. By default nil is
+	// stored like any other value and Get returns (nil, true), which only the
+	// bool distinguishes from a miss. Empty but non-nil values are always
+	// accepted.
+	RejectNilValues bool
+
+	// RefreshOnReadThreshold extends an entry's expiry by its original TTL when
+	// a Get finds less than this fraction of the TTL remaining (e.g., 0.1).
+	// Actively read keys stay alive without a write on every read. Zero
+	// disables refreshing.
+	RefreshOnReadThreshold float64
+
+	// EvictionTargetPercent is the low-water mark eviction frees memory down to,
+	// as a percentage of MaxMemoryBytes, once the limit is exceeded (e.g., 90).
+	// Freeing headroom in one pass avoids evicting on every Set when usage sits
+	// at the limit. Zero evicts only down to MaxMemoryBytes.
+	EvictionTargetPercent int
+
+	// MaxListLength bounds lists built by Append. Once a list would exceed it,
+	// the oldest items are dropped from the front, so the list behaves as a
+	// ring buffer of the most recent items. Zero keeps every item.
+	MaxListLength int
 }
 
 // DefaultConfig returns a default configuration optimized for 1M QPS
@@ -49,6 +303,43 @@ func LowMemoryConfig() *Config {
 	}
 }
 
+// SizedConfig returns a default configuration for a maxBytes memory budget,
+// with a power-of-two ShardCount derived from the budget and the CPU count
+func SizedConfig(maxBytes int64) *Config {
+	config := DefaultConfig()
+	if maxBytes > 0 {
+		config.MaxMemoryBytes = maxBytes
+	}
+	config.ShardCount = sizedShardCount(config.MaxMemoryBytes, runtime.NumCPU())
+	return config
+}
+
+const (
+	// shardsPerCPU is how many shards SizedConfig allots per CPU to keep lock
+	// contention low
+	shardsPerCPU = 64
+
+	// minShardBytes is the smallest per-shard budget SizedConfig allows, so
+	// small caches are not split into shards too small to evict sensibly
+	minShardBytes = 256 * 1024
+
+	// maxSizedShards caps the shard count SizedConfig derives
+	maxSizedShards = 4096
+)
+
+// sizedShardCount returns a power-of-two shard count that grows with the CPU
+// count, limited so each shard gets at least minShardBytes of maxBytes
+func sizedShardCount(maxBytes int64, cpus int) int {
+	shards := 1
+	for shards < cpus*shardsPerCPU && shards < maxSizedShards {
+		shards *= 2
+	}
+	for shards > 1 && maxBytes/int64(shards) < minShardBytes {
+		shards /= 2
+	}
+	return shards
+}
+
 // CustomConfig creates a configuration with custom parameters
 func CustomConfig(maxMemoryMB int, shardCount int, defaultTTL time.Duration) *Config {
 	return &Config{
@@ -59,6 +350,25 @@ func CustomConfig(maxMemoryMB int, shardCount int, defaultTTL time.Duration) *Co
 	}
 }
 
+// withDefaults returns a copy of the configuration with missing required
+// fields filled in from DefaultConfig
+func (c *Config) withDefaults() *Config {
+	normalized := *c
+	defaults := DefaultConfig()
+
+	if normalized.MaxMemoryBytes <= 0 {
+		normalized.MaxMemoryBytes = defaults.MaxMemoryBytes
+	}
+	if normalized.ShardCount <= 0 {
+		normalized.ShardCount = defaults.ShardCount
+	}
+	if normalized.CleanupInterval <= 0 {
+		normalized.CleanupInterval = defaults.CleanupInterval
+	}
+
+	return &normalized
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.MaxMemoryBytes <= 0 {
@@ -77,5 +387,93 @@ func (c *Config) Validate() error {
 		return ErrInvalidConfig{Field: "CleanupInterval", Message: "must be greater than 0"}
 	}
 
+	if !c.EvictionPolicy.isValid() {
+		return ErrInvalidConfig{Field: "EvictionPolicy", Message: "unknown eviction policy"}
+	}
+
+	if c.MemoryPressureThresholdPercent < 0 || c.MemoryPressureThresholdPercent > 100 {
+		return ErrInvalidConfig{Field: "MemoryPressureThresholdPercent", Message: "must be between 0 and 100"}
+	}
+
+	if c.CompressValuesOver < 0 {
+		return ErrInvalidConfig{Field: "CompressValuesOver", Message: "must not be negative"}
+	}
+
+	if c.LRUPromotionInterval < 0 {
+		return ErrInvalidConfig{Field: "LRUPromotionInterval", Message: "must not be negative"}
+	}
+
+	if c.EvictionSamples < 0 {
+		return ErrInvalidConfig{Field: "EvictionSamples", Message: "must not be negative"}
+	}
+
+	if c.MaxTTL < 0 {
+		return ErrInvalidConfig{Field: "MaxTTL", Message: "must not be negative"}
+	}
+
+	if c.StaleWindow < 0 {
+		return ErrInvalidConfig{Field: "StaleWindow", Message: "must not be negative"}
+	}
+
+	if c.MaxBackgroundGoroutines < 0 {
+		return ErrInvalidConfig{Field: "MaxBackgroundGoroutines", Message: "must not be negative"}
+	}
+
+	if c.LoaderFailureThreshold < 0 {
+		return ErrInvalidConfig{Field: "LoaderFailureThreshold", Message: "must not be negative"}
+	}
+
+	if c.LoaderFailureWindow < 0 {
+		return ErrInvalidConfig{Field: "LoaderFailureWindow", Message: "must not be negative"}
+	}
+
+	if c.LoaderOpenDuration < 0 {
+		return ErrInvalidConfig{Field: "LoaderOpenDuration", Message: "must not be negative"}
+	}
+
+	if c.NegativeTTL < 0 {
+		return ErrInvalidConfig{Field: "NegativeTTL", Message: "must not be negative"}
+	}
+
+	if c.CleanupMaxDuration < 0 {
+		return ErrInvalidConfig{Field: "CleanupMaxDuration", Message: "must not be negative"}
+	}
+
+	if c.CleanupParallelism < 0 {
+		return ErrInvalidConfig{Field: "CleanupParallelism", Message: "must not be negative"}
+	}
+
+	if c.EvictionOvershootPercent < 0 {
+		return ErrInvalidConfig{Field: "EvictionOvershootPercent", Message: "must not be negative"}
+	}
+
+	if c.EvictionTargetPercent < 0 || c.EvictionTargetPercent > 100 {
+		return ErrInvalidConfig{Field: "EvictionTargetPercent", Message: "must be between 0 and 100"}
+	}
+
+	if c.MaxListLength < 0 {
+		return ErrInvalidConfig{Field: "MaxListLength", Message: "must not be negative"}
+	}
+
+	if c.MaxEntrySize < 0 {
+		return ErrInvalidConfig{Field: "MaxEntrySize", Message: "must not be negative"}
+	}
+
+	if c.SizeSamplingRate < 0 || c.SizeSamplingRate > 1 {
+		return ErrInvalidConfig{Field: "SizeSamplingRate", Message: "must be between 0 and 1"}
+	}
+
+	if c.MaxKeyLength < 0 {
+		return ErrInvalidConfig{Field: "MaxKeyLength", Message: "must not be negative"}
+	}
+
+	if c.ExpectedEntries < 0 {
+		return ErrInvalidConfig{Field: "ExpectedEntries", Message: "must not be negative"}
+	}
+
+	if c.RefreshOnReadThreshold < 0 || c.RefreshOnReadThreshold > 1 {
+		return ErrInvalidConfig{Field: "RefreshOnReadThreshold", Message: "must be between 0 and 1"}
+	}
+
 	return nil
 }
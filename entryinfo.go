@@ -0,0 +1,59 @@
+package fastcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EntryInfo describes a single cache entry for analytics
+type EntryInfo struct {
+	Key         string        `json:"key"`
+	Size        int64         `json:"size"`
+	AccessCount int64         `json:"access_count"`
+	LastAccess  time.Time     `json:"last_access"` // zero if never read
+	TTL         time.Duration `json:"ttl"`         // remaining time to live, 0 if the entry never expires
+
+	// Priority is the entry's GDSF priority under PolicyGDSF: the shard's
+	// inflation at its last access plus its frequency per byte. The lowest
+	// priority in a shard is evicted first. It is 0 under other policies and
+	// for pinned entries.
+	Priority float64 `json:"priority,omitempty"`
+}
+
+// recordAccess counts a Get hit on the entry
+func (e *Entry) recordAccess() {
+	atomic.AddInt64(&e.accessCount, 1)
+	atomic.StoreInt64(&e.lastAccess, time.Now().UnixNano())
+}
+
+// EntryInfo returns access statistics for key without counting as an access
+// or affecting hit/miss stats. It returns false if the key is missing or expired.
+func (c *Cache) EntryInfo(key string) (*EntryInfo, bool) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, false
+	}
+
+	shard := c.rlockShard(key)
+	defer shard.mu.RUnlock()
+
+	entry, exists := shard.data[key]
+	if !exists || entry.isExpired(c.now()) {
+		return nil, false
+	}
+
+	info := &EntryInfo{
+		Key:         key,
+		Size:        entry.size,
+		AccessCount: atomic.LoadInt64(&entry.accessCount),
+	}
+	if last := atomic.LoadInt64(&entry.lastAccess); last > 0 {
+		info.LastAccess = time.Unix(0, last)
+	}
+	if entry.expiry > 0 {
+		info.TTL = time.Duration(entry.expiry - c.now())
+	}
+	if shard.gdsf != nil && !entry.pinned {
+		info.Priority = entry.priority
+	}
+	return info, true
+}
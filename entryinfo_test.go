@@ -0,0 +1,50 @@
+package fastcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntryInfo(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	cache.Set("hot", "value", time.Minute)
+
+	info, exists := cache.EntryInfo("hot")
+	if !exists {
+		t.Fatal("Expected entry info for hot")
+	}
+	if info.AccessCount != 0 || !info.LastAccess.IsZero() {
+		t.Errorf("Expected no accesses before any Get, got %+v", info)
+	}
+
+	const reads = 25
+	before := time.Now()
+	for i := 0; i < reads; i++ {
+		cache.Get("hot")
+	}
+
+	info, _ = cache.EntryInfo("hot")
+	if info.AccessCount != reads {
+		t.Errorf("Expected access count %d, got %d", reads, info.AccessCount)
+	}
+	if info.LastAccess.Before(before) {
+		t.Errorf("Expected last access after %v, got %v", before, info.LastAccess)
+	}
+	if info.Size != cache.getShard("hot").data["hot"].size {
+		t.Errorf("Unexpected size %d", info.Size)
+	}
+	if info.TTL <= 0 || info.TTL > time.Minute {
+		t.Errorf("Expected remaining TTL within a minute, got %v", info.TTL)
+	}
+
+	// Introspection is not an access
+	if stats := cache.GetStats(); stats.HitCount != reads {
+		t.Errorf("Expected %d hits, got %d", reads, stats.HitCount)
+	}
+
+	if _, exists := cache.EntryInfo("missing"); exists {
+		t.Error("Expected no entry info for a missing key")
+	}
+}
@@ -0,0 +1,78 @@
+package fastcache
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvMaxMemoryMB     = "FASTCACHE_MAX_MEMORY_MB"
+	EnvShardCount      = "FASTCACHE_SHARD_COUNT"
+	EnvDefaultTTL      = "FASTCACHE_DEFAULT_TTL"
+	EnvCleanupInterval = "FASTCACHE_CLEANUP_INTERVAL"
+)
+
+// ConfigFromEnv returns DefaultConfig with the fields set by environment
+// variables overridden: FASTCACHE_MAX_MEMORY_MB and FASTCACHE_SHARD_COUNT are
+// integers, FASTCACHE_DEFAULT_TTL and FASTCACHE_CLEANUP_INTERVAL are durations
+// such as "30m". Unset or empty variables keep the default. A malformed value
+// returns ErrInvalidConfig naming the field; the result is not validated.
+func ConfigFromEnv() (*Config, error) {
+	config := DefaultConfig()
+
+	if value, ok := lookupEnv(EnvMaxMemoryMB); ok {
+		mb, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || mb > math.MaxInt64/(1024*1024) {
+			return nil, envError("MaxMemoryBytes", EnvMaxMemoryMB, value)
+		}
+		config.MaxMemoryBytes = mb * 1024 * 1024
+	}
+	if value, ok := lookupEnv(EnvShardCount); ok {
+		shards, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, envError("ShardCount", EnvShardCount, value)
+		}
+		config.ShardCount = shards
+	}
+	if value, ok := lookupEnv(EnvDefaultTTL); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, envError("DefaultTTL", EnvDefaultTTL, value)
+		}
+		config.DefaultTTL = ttl
+	}
+	if value, ok := lookupEnv(EnvCleanupInterval); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, envError("CleanupInterval", EnvCleanupInterval, value)
+		}
+		config.CleanupInterval = interval
+	}
+
+	return config, nil
+}
+
+// NewFromEnv creates a cache from ConfigFromEnv, returning an error if a
+// variable is malformed or the resulting config fails Validate
+func NewFromEnv() (*Cache, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithError(config)
+}
+
+// lookupEnv returns the value of a set, non-empty environment variable
+func lookupEnv(name string) (string, bool) {
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
+// envError reports a malformed environment variable for a config field
+func envError(field, name, value string) error {
+	return ErrInvalidConfig{Field: field, Message: fmt.Sprintf("cannot parse %s=%q", name, value)}
+}
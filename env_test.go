@@ -0,0 +1,64 @@
+package fastcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv(EnvMaxMemoryMB, "64")
+	t.Setenv(EnvShardCount, "32")
+	t.Setenv(EnvDefaultTTL, "90s")
+	t.Setenv(EnvCleanupInterval, "")
+
+	cache, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("NewFromEnv failed: %v", err)
+	}
+	defer cache.Close()
+
+	config := cache.config
+	if config.MaxMemoryBytes != 64*1024*1024 || config.ShardCount != 32 || config.DefaultTTL != 90*time.Second {
+		t.Errorf("Unexpected config from env: %d bytes, %d shards, TTL %v", config.MaxMemoryBytes, config.ShardCount, config.DefaultTTL)
+	}
+	if config.CleanupInterval != DefaultConfig().CleanupInterval {
+		t.Errorf("Expected default cleanup interval for an empty variable, got %v", config.CleanupInterval)
+	}
+	if err := cache.Set("key", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+}
+
+func TestNewFromEnvErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		field string
+	}{
+		{EnvMaxMemoryMB, "lots", "MaxMemoryBytes"},
+		{EnvMaxMemoryMB, "0", "MaxMemoryBytes"},
+		{EnvMaxMemoryMB, "17592186044417", "MaxMemoryBytes"}, // wraps to 1MB in bytes
+		{EnvShardCount, "1.5", "ShardCount"},
+		{EnvShardCount, "100000", "ShardCount"},
+		{EnvDefaultTTL, "10", "DefaultTTL"},
+		{EnvCleanupInterval, "soon", "CleanupInterval"},
+		{EnvCleanupInterval, "-1s", "CleanupInterval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			cache, err := NewFromEnv()
+			var configErr ErrInvalidConfig
+			if !errors.As(err, &configErr) || configErr.Field != tt.field {
+				t.Errorf("Expected ErrInvalidConfig for %s, got %v", tt.field, err)
+			}
+			if cache != nil {
+				cache.Close()
+				t.Error("Expected no cache on error")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package fastcache
 import (
 	"errors"
 	"fmt"
+	"reflect"
 )
 
 // Common errors
@@ -18,6 +19,28 @@ var (
 
 	// ErrMemoryLimitExceeded is returned when memory limit would be exceeded
 	ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+	// ErrInvalidShard is returned when a shard ID is out of range
+	ErrInvalidShard = errors.New("invalid shard id")
+
+	// ErrTypeMismatch is returned when a stored value has a different type than an operation requires
+	ErrTypeMismatch = errors.New("value type mismatch")
+
+	// ErrLoaderUnavailable is returned by GetOrCompute while the loader circuit breaker is open
+	ErrLoaderUnavailable = errors.New("loader unavailable: circuit open")
+
+	// ErrInconsistent is wrapped by every discrepancy reported by Verify
+	ErrInconsistent = errors.New("inconsistent cache state")
+
+	// ErrInvalidValue is returned when a nil value is written with RejectNilValues set
+	ErrInvalidValue = errors.New("invalid value")
+
+	// ErrInvalidEvent is returned by ApplyRemote for an unknown CacheEventType
+	ErrInvalidEvent = errors.New("invalid cache event")
+
+	// ErrLoaderPanicked is returned to callers waiting on a GetOrCompute or
+	// GetOrSetBytes load whose loader panicked; the loading caller re-panics
+	ErrLoaderPanicked = errors.New("loader panicked")
 )
 
 // ErrInvalidConfig represents a configuration validation error
@@ -35,12 +58,73 @@ type ErrOperationFailed struct {
 	Operation string
 	Key       string
 	Reason    string
+	Err       error
 }
 
 func (e ErrOperationFailed) Error() string {
 	return fmt.Sprintf("operation '%s' failed for key '%s': %s", e.Operation, e.Key, e.Reason)
 }
 
+func (e ErrOperationFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrKeyTooLong is returned when a key is longer than Config.MaxKeyLength.
+// It unwraps to ErrInvalidKey.
+type ErrKeyTooLong struct {
+	Length int
+	Max    int
+}
+
+func (e ErrKeyTooLong) Error() string {
+	return fmt.Sprintf("key length %d exceeds maximum of %d", e.Length, e.Max)
+}
+
+func (e ErrKeyTooLong) Unwrap() error {
+	return ErrInvalidKey
+}
+
+// ErrEntryTooLarge is returned when an entry's estimated size exceeds
+// Config.MaxEntrySize or Config.MaxMemoryBytes
+type ErrEntryTooLarge struct {
+	Size int64
+	Max  int64
+}
+
+func (e ErrEntryTooLarge) Error() string {
+	return fmt.Sprintf("entry size %d exceeds maximum of %d", e.Size, e.Max)
+}
+
+// ErrValueType is returned when a value written to the cache is not
+// assignable to Config.ValueType. It unwraps to ErrTypeMismatch.
+type ErrValueType struct {
+	Expected reflect.Type
+	Actual   reflect.Type // nil for an untyped nil value
+}
+
+func (e ErrValueType) Error() string {
+	return fmt.Sprintf("value of type %v is not assignable to %v", e.Actual, e.Expected)
+}
+
+func (e ErrValueType) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// opError wraps err with the operation and key that caused it
+func opError(operation, key string, err error) error {
+	return ErrOperationFailed{Operation: operation, Key: key, Reason: err.Error(), Err: err}
+}
+
+// ErrParse represents a malformed line in a text load
+type ErrParse struct {
+	Line    int
+	Message string
+}
+
+func (e ErrParse) Error() string {
+	return fmt.Sprintf("parse error on line %d: %s", e.Line, e.Message)
+}
+
 // ErrShardError represents a shard-specific error
 type ErrShardError struct {
 	ShardID int
@@ -57,21 +141,19 @@ func (e ErrShardError) Unwrap() error {
 
 // IsTemporaryError checks if an error is temporary and the operation can be retried
 func IsTemporaryError(err error) bool {
-	switch err {
-	case ErrMemoryLimitExceeded:
-		return true
-	default:
-		return false
-	}
+	return errors.Is(err, ErrMemoryLimitExceeded) || errors.Is(err, ErrLoaderUnavailable)
 }
 
 // IsPermanentError checks if an error is permanent and the operation should not be retried
 func IsPermanentError(err error) bool {
-	switch err {
-	case ErrCacheClosed, ErrInvalidKey:
+	if errors.Is(err, ErrCacheClosed) || errors.Is(err, ErrInvalidKey) {
+		return true
+	}
+	var configErr ErrInvalidConfig
+	if errors.As(err, &configErr) {
 		return true
-	default:
-		var configErr ErrInvalidConfig
-		return errors.As(err, &configErr)
 	}
+	// The same entry can never fit, however much is evicted
+	var tooLarge ErrEntryTooLarge
+	return errors.As(err, &tooLarge)
 }
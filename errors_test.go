@@ -0,0 +1,60 @@
+package fastcache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetErrorWrapping(t *testing.T) {
+	cache := New(DefaultConfig())
+	cache.Close()
+
+	err := cache.Set("user:42", "value")
+	if !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Expected errors.Is(err, ErrCacheClosed), got %v", err)
+	}
+
+	var opErr ErrOperationFailed
+	if !errors.As(err, &opErr) {
+		t.Fatalf("Expected ErrOperationFailed, got %T", err)
+	}
+	if opErr.Operation != "set" || opErr.Key != "user:42" {
+		t.Errorf("Unexpected operation context: %+v", opErr)
+	}
+	if !strings.Contains(err.Error(), "user:42") {
+		t.Errorf("Expected error message to contain the key, got %q", err.Error())
+	}
+
+	if !IsPermanentError(err) {
+		t.Error("Wrapped ErrCacheClosed should still be a permanent error")
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		temporary bool
+		permanent bool
+	}{
+		{"memory limit", ErrMemoryLimitExceeded, true, false},
+		{"wrapped memory limit", opError("set", "k", ErrMemoryLimitExceeded), true, false},
+		{"closed", ErrCacheClosed, false, true},
+		{"invalid key", opError("set", "", ErrInvalidKey), false, true},
+		{"invalid config", ErrInvalidConfig{Field: "ShardCount"}, false, true},
+		{"entry too large", opError("set", "k", ErrEntryTooLarge{Size: 2048, Max: 1024}), false, true},
+		{"not found", ErrKeyNotFound, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporaryError(tt.err); got != tt.temporary {
+				t.Errorf("IsTemporaryError = %v, expected %v", got, tt.temporary)
+			}
+			if got := IsPermanentError(tt.err); got != tt.permanent {
+				t.Errorf("IsPermanentError = %v, expected %v", got, tt.permanent)
+			}
+		})
+	}
+}
@@ -401,25 +401,8 @@ func (m *MonitoringServer) metricsJSONHandler(w http.ResponseWriter, r *http.Req
 }
 
 func (m *MonitoringServer) prometheusHandler(w http.ResponseWriter, r *http.Request) {
-	snapshot := m.captureMetricsSnapshot()
-
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprintf(w, "# HELP fastcache_entries_total Total number of cache entries\n")
-	fmt.Fprintf(w, "# TYPE fastcache_entries_total gauge\n")
-	fmt.Fprintf(w, "fastcache_entries_total %d\n", snapshot.Stats.TotalEntries)
-
-	fmt.Fprintf(w, "# HELP fastcache_memory_bytes Memory usage in bytes\n")
-	fmt.Fprintf(w, "# TYPE fastcache_memory_bytes gauge\n")
-	fmt.Fprintf(w, "fastcache_memory_bytes %d\n", snapshot.Stats.TotalSize)
-
-	fmt.Fprintf(w, "# HELP fastcache_hit_ratio Cache hit ratio\n")
-	fmt.Fprintf(w, "# TYPE fastcache_hit_ratio gauge\n")
-	fmt.Fprintf(w, "fastcache_hit_ratio %.4f\n", snapshot.Stats.HitRatio)
-
-	fmt.Fprintf(w, "# HELP fastcache_operations_total Total cache operations\n")
-	fmt.Fprintf(w, "# TYPE fastcache_operations_total counter\n")
-	fmt.Fprintf(w, "fastcache_operations_total{type=\"hit\"} %d\n", snapshot.Stats.HitCount)
-	fmt.Fprintf(w, "fastcache_operations_total{type=\"miss\"} %d\n", snapshot.Stats.MissCount)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, m.cache.ExportPrometheus())
 }
 
 func (m *MonitoringServer) healthHandler(w http.ResponseWriter, r *http.Request) {
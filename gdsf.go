@@ -0,0 +1,103 @@
+package fastcache
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// gdsfQueue orders a shard's entries by Greedy-Dual-Size-Frequency priority
+// for PolicyGDSF. An entry's priority is the queue's inflation value at its
+// last access plus its frequency (hits since insertion, plus one) divided by
+// its size, so small, frequently read entries outrank large or rarely read
+// ones. The inflation value rises to each victim's priority, which ages out
+// entries that have not been accessed since it was lower.
+type gdsfQueue struct {
+	entries   []*Entry
+	inflation float64
+	total     float64 // sum of the queued entries' priorities
+}
+
+// Len implements heap.Interface
+func (q *gdsfQueue) Len() int { return len(q.entries) }
+
+// Less implements heap.Interface
+func (q *gdsfQueue) Less(i, j int) bool { return q.entries[i].priority < q.entries[j].priority }
+
+// Swap implements heap.Interface
+func (q *gdsfQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	q.entries[i].heapIndex = i
+	q.entries[j].heapIndex = j
+}
+
+// Push implements heap.Interface
+func (q *gdsfQueue) Push(x interface{}) {
+	e := x.(*Entry)
+	e.heapIndex = len(q.entries)
+	q.entries = append(q.entries, e)
+}
+
+// Pop implements heap.Interface
+func (q *gdsfQueue) Pop() interface{} {
+	n := len(q.entries) - 1
+	e := q.entries[n]
+	q.entries[n] = nil
+	q.entries = q.entries[:n]
+	e.heapIndex = -1
+	return e
+}
+
+// priorityOf returns the GDSF priority of an entry accessed now
+func (q *gdsfQueue) priorityOf(e *Entry) float64 {
+	size := e.size
+	if size < 1 {
+		size = 1
+	}
+	frequency := atomic.LoadInt64(&e.accessCount) + 1
+	return q.inflation + float64(frequency)/float64(size)
+}
+
+// add inserts an entry
+func (q *gdsfQueue) add(e *Entry) {
+	e.priority = q.priorityOf(e)
+	q.total += e.priority
+	heap.Push(q, e)
+}
+
+// touch recomputes the priority of an entry after an access or update
+func (q *gdsfQueue) touch(e *Entry) {
+	old := e.priority
+	e.priority = q.priorityOf(e)
+	q.total += e.priority - old
+	heap.Fix(q, e.heapIndex)
+}
+
+// remove drops an entry
+func (q *gdsfQueue) remove(e *Entry) {
+	heap.Remove(q, e.heapIndex)
+	q.total -= e.priority
+	if len(q.entries) == 0 {
+		q.total = 0 // drop accumulated rounding error
+	}
+}
+
+// meanPriority returns the average priority of the queued entries, or 0 if
+// the queue is empty
+func (q *gdsfQueue) meanPriority() float64 {
+	if len(q.entries) == 0 {
+		return 0
+	}
+	return q.total / float64(len(q.entries))
+}
+
+// victim returns the lowest priority entry, or nil if the queue is empty, and
+// raises the inflation value to its priority on the assumption that it is
+// about to be evicted
+func (q *gdsfQueue) victim() *Entry {
+	if len(q.entries) == 0 {
+		return nil
+	}
+	e := q.entries[0]
+	q.inflation = e.priority
+	return e
+}
@@ -0,0 +1,139 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// valueIndex maps index values to the primary key carrying them, per index
+// name. Its lock may be taken while a shard lock is held, but a shard lock is
+// never taken while holding it.
+type valueIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]string // index name -> index value -> key
+}
+
+// add records that key carries each index value in indexes, replacing any
+// other key that carried the same value
+func (x *valueIndex) add(key string, indexes map[string]string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.keys == nil {
+		x.keys = make(map[string]map[string]string)
+	}
+	for name, value := range indexes {
+		values, ok := x.keys[name]
+		if !ok {
+			values = make(map[string]string)
+			x.keys[name] = values
+		}
+		values[value] = key
+	}
+}
+
+// remove drops the index values in indexes that still point at key
+func (x *valueIndex) remove(key string, indexes map[string]string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for name, value := range indexes {
+		values := x.keys[name]
+		if values[value] != key {
+			continue
+		}
+		delete(values, value)
+		if len(values) == 0 {
+			delete(x.keys, name)
+		}
+	}
+}
+
+// lookup returns the key carrying value in the named index
+func (x *valueIndex) lookup(name, value string) (string, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	key, ok := x.keys[name][value]
+	return key, ok
+}
+
+// reset drops all indexes
+func (x *valueIndex) reset() {
+	x.mu.Lock()
+	x.keys = nil
+	x.mu.Unlock()
+}
+
+// swap exchanges the contents of two indexes
+func (x *valueIndex) swap(other *valueIndex) {
+	x.mu.Lock()
+	other.mu.Lock()
+	x.keys, other.keys = other.keys, x.keys
+	other.mu.Unlock()
+	x.mu.Unlock()
+}
+
+// unindex removes an entry's index values. The caller must hold the entry's
+// shard write lock.
+func (c *Cache) unindex(entry *Entry) {
+	if len(entry.indexes) == 0 {
+		return
+	}
+	c.indexes.remove(entry.key, entry.indexes)
+	entry.indexes = nil
+}
+
+// SetWithIndex stores a key-value pair that can also be found with GetByIndex
+// under each index name and value in indexKeys, such as {"email": "a@b.c"}.
+// An index value identifies one key: writing it for another key moves it
+// there. The index values are removed when the entry is deleted, evicted or
+// expires, and setting the key again without them removes them.
+func (c *Cache) SetWithIndex(key string, value interface{}, indexKeys map[string]string, ttl ...time.Duration) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("set_with_index", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return opError("set_with_index", key, err)
+	}
+
+	expiry := c.expiryFor(firstTTL(ttl))
+	value, err := c.prepareValue(value)
+	if err != nil {
+		return opError("set_with_index", key, err)
+	}
+	indexes := make(map[string]string, len(indexKeys))
+	for name, indexValue := range indexKeys {
+		indexes[name] = indexValue
+	}
+
+	shard := c.lockShard(key)
+	sizeDiff, err := c.setLocked(shard, key, value, expiry)
+	if err == nil && len(indexes) > 0 {
+		shard.data[key].indexes = indexes
+		c.indexes.add(key, indexes)
+	}
+	shard.mu.Unlock()
+	if err != nil {
+		return opError("set_with_index", key, err)
+	}
+
+	// Trigger eviction if needed (outside of lock to avoid deadlock)
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
+
+// GetByIndex retrieves the value of the key carrying indexValue in the named
+// index, as written by SetWithIndex. A found key is read with Get and counts
+// toward hit/miss statistics like one; an index value no key carries does not.
+// A write racing with the lookup may replace the value before it is read.
+func (c *Cache) GetByIndex(indexName, indexValue string) (interface{}, bool) {
+	key, ok := c.indexes.lookup(indexName, indexValue)
+	if !ok {
+		return nil, false
+	}
+	return c.Get(key)
+}
@@ -0,0 +1,82 @@
+package fastcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetByIndex(t *testing.T) {
+	type user struct {
+		ID    int
+		Email string
+	}
+
+	config := DefaultConfig()
+	config.DisableBackgroundCleanup = true
+	cache, clock := newWithFakeClock(config)
+	defer cache.Close()
+
+	alice := user{ID: 1, Email: "alice@example.com"}
+	if err := cache.SetWithIndex("user:1", alice, map[string]string{"email": alice.Email}); err != nil {
+		t.Fatalf("SetWithIndex failed: %v", err)
+	}
+
+	value, found := cache.GetByIndex("email", "alice@example.com")
+	if !found || value != alice {
+		t.Fatalf("Expected %v by email, got %v (found=%v)", alice, value, found)
+	}
+	if _, found := cache.GetByIndex("email", "bob@example.com"); found {
+		t.Error("Expected unknown email to miss")
+	}
+	if _, found := cache.GetByIndex("phone", "alice@example.com"); found {
+		t.Error("Expected unknown index to miss")
+	}
+
+	// Changing the email drops the old index value
+	alice.Email = "alice@example.org"
+	_ = cache.SetWithIndex("user:1", alice, map[string]string{"email": alice.Email})
+	if _, found := cache.GetByIndex("email", "alice@example.com"); found {
+		t.Error("Expected old email to be unindexed after overwrite")
+	}
+	if value, found := cache.GetByIndex("email", "alice@example.org"); !found || value != alice {
+		t.Errorf("Expected %v by new email, got %v (found=%v)", alice, value, found)
+	}
+
+	// Deleting the primary entry removes its index values
+	cache.Delete("user:1")
+	if _, found := cache.GetByIndex("email", "alice@example.org"); found {
+		t.Error("Expected email to miss after delete")
+	}
+	if n := len(cache.indexes.keys); n != 0 {
+		t.Errorf("Expected empty index after delete, got %d index names", n)
+	}
+
+	// Expired entries are unindexed when removed
+	_ = cache.SetWithIndex("user:2", user{ID: 2}, map[string]string{"email": "carol@example.com"}, time.Minute)
+	clock.Advance(2 * time.Minute)
+	if _, found := cache.GetByIndex("email", "carol@example.com"); found {
+		t.Error("Expected expired entry to miss by index")
+	}
+	cache.DeleteExpired()
+	if n := len(cache.indexes.keys); n != 0 {
+		t.Errorf("Expected empty index after expiry, got %d index names", n)
+	}
+}
+
+func TestIndexValueMovesBetweenKeys(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.SetWithIndex("user:1", "first", map[string]string{"email": "shared@example.com"})
+	_ = cache.SetWithIndex("user:2", "second", map[string]string{"email": "shared@example.com"})
+
+	if value, _ := cache.GetByIndex("email", "shared@example.com"); value != "second" {
+		t.Errorf("Expected latest writer to own the index value, got %v", value)
+	}
+
+	// Removing the previous owner must not drop the current one
+	cache.Delete("user:1")
+	if value, _ := cache.GetByIndex("email", "shared@example.com"); value != "second" {
+		t.Errorf("Expected index value to survive deleting the old owner, got %v", value)
+	}
+}
@@ -0,0 +1,224 @@
+package fastcache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// KeyedCache is a cache keyed by any comparable type, such as a small struct,
+// so composite keys can be used without formatting them into strings. Keys
+// are spread over shards by a caller-supplied hash and compared with ==.
+//
+// It supports a subset of Cache's features: TTLs, LRU eviction against
+// MaxMemoryBytes across all shards, and hit/miss counts. Expired entries are removed when read
+// or evicted; there is no background cleanup, so no Close is needed.
+type KeyedCache[K comparable, V any] struct {
+	config    *Config
+	hash      func(K) uint64
+	shards    []*keyedShard[K, V]
+	totalSize int64
+	totalHits int64
+	totalMiss int64
+	evictFrom uint32 // rotates the first shard of eviction passes
+}
+
+// keyedShard is a single shard of a KeyedCache
+type keyedShard[K comparable, V any] struct {
+	mu      sync.Mutex
+	data    map[K]*keyedEntry[K, V]
+	lruList *list.List
+}
+
+// keyedEntry is a single KeyedCache entry
+type keyedEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	size     int64
+	expiry   int64 // Unix timestamp in nanoseconds
+	listNode *list.Element
+}
+
+// isExpired checks if the entry has expired
+func (e *keyedEntry[K, V]) isExpired(now int64) bool {
+	return e.expiry > 0 && now > e.expiry
+}
+
+// NewKeyed creates a cache keyed by K. hash must return equal values for
+// equal keys; its quality determines how evenly keys spread over shards.
+// MaxMemoryBytes, ShardCount, DefaultTTL and ExpectedEntries are taken from
+// config, with the same defaults as New.
+func NewKeyed[K comparable, V any](config *Config, hash func(K) uint64) *KeyedCache[K, V] {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	config = config.withDefaults()
+
+	capacity := 0
+	if config.ExpectedEntries > 0 {
+		capacity = int(config.ExpectedEntries / int64(config.ShardCount))
+	}
+
+	c := &KeyedCache[K, V]{
+		config: config,
+		hash:   hash,
+		shards: make([]*keyedShard[K, V], config.ShardCount),
+	}
+	for i := range c.shards {
+		c.shards[i] = &keyedShard[K, V]{
+			data:    make(map[K]*keyedEntry[K, V], capacity),
+			lruList: list.New(),
+		}
+	}
+	return c
+}
+
+// getShard returns the shard for key
+func (c *KeyedCache[K, V]) getShard(key K) *keyedShard[K, V] {
+	return c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+// keyedEntrySize estimates the memory used by an entry
+func keyedEntrySize[K comparable, V any](key K, value V) int64 {
+	return int64(unsafe.Sizeof(key)) + calculateSize("", value)
+}
+
+// Set stores a key-value pair with optional TTL, evicting least recently used
+// entries across shards while the cache is over MaxMemoryBytes
+func (c *KeyedCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
+	var expiry int64
+	if t := firstTTL(ttl); t > 0 {
+		expiry = time.Now().Add(t).UnixNano()
+	} else if c.config.DefaultTTL > 0 {
+		expiry = time.Now().Add(c.config.DefaultTTL).UnixNano()
+	}
+	size := keyedEntrySize(key, value)
+
+	shard := c.getShard(key)
+	shard.mu.Lock()
+	entry, exists := shard.data[key]
+	if exists {
+		atomic.AddInt64(&c.totalSize, size-entry.size)
+		entry.value = value
+		entry.size = size
+		entry.expiry = expiry
+		shard.lruList.MoveToFront(entry.listNode)
+	} else {
+		entry = &keyedEntry[K, V]{key: key, value: value, size: size, expiry: expiry}
+		entry.listNode = shard.lruList.PushFront(entry)
+		shard.data[key] = entry
+		atomic.AddInt64(&c.totalSize, size)
+	}
+	shard.mu.Unlock()
+
+	c.evictIfNeeded(entry)
+}
+
+// evictIfNeeded removes least recently used entries while the cache is over
+// MaxMemoryBytes, one per shard in turn starting from a rotating shard, so
+// memory is reclaimed from whichever shards hold it. written, the entry just
+// stored, is never evicted.
+func (c *KeyedCache[K, V]) evictIfNeeded(written *keyedEntry[K, V]) {
+	for atomic.LoadInt64(&c.totalSize) > c.config.MaxMemoryBytes {
+		start := int(atomic.AddUint32(&c.evictFrom, 1) % uint32(len(c.shards)))
+		evicted := false
+		for i := 0; i < len(c.shards) && atomic.LoadInt64(&c.totalSize) > c.config.MaxMemoryBytes; i++ {
+			if c.evictFromShard(c.shards[(start+i)%len(c.shards)], written) {
+				evicted = true
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+// evictFromShard removes the shard's least recently used entry other than
+// written and reports whether one was removed
+func (c *KeyedCache[K, V]) evictFromShard(shard *keyedShard[K, V], written *keyedEntry[K, V]) bool {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	elem := shard.lruList.Back()
+	if elem != nil && elem.Value.(*keyedEntry[K, V]) == written {
+		elem = elem.Prev()
+	}
+	if elem == nil {
+		return false
+	}
+	c.removeLocked(shard, elem.Value.(*keyedEntry[K, V]))
+	return true
+}
+
+// Get retrieves a value by key
+func (c *KeyedCache[K, V]) Get(key K) (V, bool) {
+	shard := c.getShard(key)
+	shard.mu.Lock()
+	entry, exists := shard.data[key]
+	if exists && entry.isExpired(time.Now().UnixNano()) {
+		c.removeLocked(shard, entry)
+		exists = false
+	}
+	if !exists {
+		shard.mu.Unlock()
+		atomic.AddInt64(&c.totalMiss, 1)
+		var zero V
+		return zero, false
+	}
+	shard.lruList.MoveToFront(entry.listNode)
+	value := entry.value
+	shard.mu.Unlock()
+
+	atomic.AddInt64(&c.totalHits, 1)
+	return value, true
+}
+
+// Delete removes a key from the cache
+func (c *KeyedCache[K, V]) Delete(key K) bool {
+	shard := c.getShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, exists := shard.data[key]
+	if !exists {
+		return false
+	}
+	c.removeLocked(shard, entry)
+	return true
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// not yet removed
+func (c *KeyedCache[K, V]) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		shard.mu.Lock()
+		n += len(shard.data)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// Size returns the estimated memory used by all entries, in bytes
+func (c *KeyedCache[K, V]) Size() int64 {
+	return atomic.LoadInt64(&c.totalSize)
+}
+
+// HitCount returns the number of Get hits
+func (c *KeyedCache[K, V]) HitCount() int64 {
+	return atomic.LoadInt64(&c.totalHits)
+}
+
+// MissCount returns the number of Get misses
+func (c *KeyedCache[K, V]) MissCount() int64 {
+	return atomic.LoadInt64(&c.totalMiss)
+}
+
+// removeLocked deletes an entry. The caller must hold the shard's lock.
+func (c *KeyedCache[K, V]) removeLocked(shard *keyedShard[K, V], entry *keyedEntry[K, V]) {
+	delete(shard.data, entry.key)
+	shard.lruList.Remove(entry.listNode)
+	atomic.AddInt64(&c.totalSize, -entry.size)
+}
@@ -0,0 +1,115 @@
+package fastcache
+
+import (
+	"testing"
+	"time"
+)
+
+type userKey struct {
+	tenant uint32
+	id     uint64
+}
+
+func hashUserKey(k userKey) uint64 {
+	h := uint64(k.tenant)*0x9E3779B97F4A7C15 ^ k.id
+	h ^= h >> 33
+	h *= 0xFF51AFD7ED558CCD
+	return h ^ h>>33
+}
+
+func TestKeyedCache(t *testing.T) {
+	cache := NewKeyed[userKey, string](DefaultConfig(), hashUserKey)
+
+	cache.Set(userKey{1, 42}, "alice")
+	cache.Set(userKey{2, 42}, "bob")
+
+	if value, ok := cache.Get(userKey{1, 42}); !ok || value != "alice" {
+		t.Errorf("Expected alice, got %q, %v", value, ok)
+	}
+	if value, ok := cache.Get(userKey{2, 42}); !ok || value != "bob" {
+		t.Errorf("Expected bob, got %q, %v", value, ok)
+	}
+	if _, ok := cache.Get(userKey{3, 42}); ok {
+		t.Error("Expected miss for unknown key")
+	}
+	if cache.HitCount() != 2 || cache.MissCount() != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", cache.HitCount(), cache.MissCount())
+	}
+
+	cache.Set(userKey{1, 42}, "alice2")
+	if value, _ := cache.Get(userKey{1, 42}); value != "alice2" {
+		t.Errorf("Expected updated value, got %q", value)
+	}
+
+	if !cache.Delete(userKey{1, 42}) || cache.Delete(userKey{1, 42}) {
+		t.Error("Expected Delete to succeed once")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", cache.Len())
+	}
+
+	cache.Delete(userKey{2, 42})
+	if size := cache.Size(); size != 0 {
+		t.Errorf("Expected size 0 after deleting all entries, got %d", size)
+	}
+}
+
+func TestKeyedCacheTTL(t *testing.T) {
+	cache := NewKeyed[userKey, int](DefaultConfig(), hashUserKey)
+
+	cache.Set(userKey{1, 1}, 1, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(userKey{1, 1}); ok {
+		t.Error("Expected entry to expire")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed on read, got %d entries", cache.Len())
+	}
+}
+
+func TestKeyedCacheEviction(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 4 * 1024
+	config.ShardCount = 1
+	cache := NewKeyed[userKey, []byte](config, hashUserKey)
+
+	value := make([]byte, 100)
+	for i := uint64(0); i < 200; i++ {
+		cache.Set(userKey{1, i}, value)
+	}
+
+	if size := cache.Size(); size > config.MaxMemoryBytes {
+		t.Errorf("Expected size within %d bytes, got %d", config.MaxMemoryBytes, size)
+	}
+	if _, ok := cache.Get(userKey{1, 0}); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := cache.Get(userKey{1, 199}); !ok {
+		t.Error("Expected most recent entry to survive")
+	}
+}
+
+func TestKeyedCacheEvictionAcrossShards(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 4 * 1024
+	config.ShardCount = 2
+	// Tenant 0 keys go to shard 0, tenant 1 keys to shard 1
+	cache := NewKeyed[userKey, []byte](config, func(k userKey) uint64 { return uint64(k.tenant) })
+
+	value := make([]byte, 100)
+	for i := uint64(0); cache.Size()+keyedEntrySize(userKey{}, value) <= config.MaxMemoryBytes; i++ {
+		cache.Set(userKey{0, i}, value)
+	}
+
+	// A write to the other shard reclaims memory from the full one
+	cache.Set(userKey{1, 0}, value)
+	if size := cache.Size(); size > config.MaxMemoryBytes {
+		t.Errorf("Expected size within %d bytes, got %d", config.MaxMemoryBytes, size)
+	}
+	if _, ok := cache.Get(userKey{1, 0}); !ok {
+		t.Error("Expected the written entry to survive")
+	}
+	if _, ok := cache.Get(userKey{0, 0}); ok {
+		t.Error("Expected the least recently used entry of the full shard to be evicted")
+	}
+}
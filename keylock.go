@@ -0,0 +1,19 @@
+package fastcache
+
+// keyLockStripes is the number of mutexes KeyLock spreads keys over
+const keyLockStripes = 1024
+
+// KeyLock locks key for a compound operation spanning calls the cache cannot
+// make atomic itself, such as fetching a value remotely and then storing it,
+// and returns the function that unlocks it. Operations holding the same key's
+// lock run one at a time; other keys proceed in parallel.
+//
+// The lock is advisory and independent of the shard locks: the cache's own
+// methods never take it, so they are safe to call while holding it. Keys are
+// striped over a fixed pool of mutexes, so two different keys may share one.
+// To avoid deadlock, never acquire a second KeyLock while holding one.
+func (c *Cache) KeyLock(key string) (unlock func()) {
+	mu := &c.keyLocks[c.hash(key)%keyLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
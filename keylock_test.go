@@ -0,0 +1,85 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyLockMutualExclusion(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	const workers = 16
+	const rounds = 100
+
+	// Unsynchronized read-modify-write, made safe only by KeyLock
+	var inside int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				unlock := cache.KeyLock("counter")
+				if atomic.AddInt32(&inside, 1) != 1 {
+					t.Error("Expected one holder of the key lock at a time")
+				}
+				value, _ := cache.Get("counter")
+				count, _ := value.(int)
+				_ = cache.Set("counter", count+1)
+				atomic.AddInt32(&inside, -1)
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := cache.Get("counter"); value != workers*rounds {
+		t.Errorf("Expected %d, got %v", workers*rounds, value)
+	}
+}
+
+func TestKeyLockParallelAcrossKeys(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	// Find a key on a different stripe from "a"
+	other := "b"
+	for i := 0; cache.hash(other)%keyLockStripes == cache.hash("a")%keyLockStripes; i++ {
+		other = string(rune('b' + i))
+	}
+
+	unlock := cache.KeyLock("a")
+
+	acquired := make(chan struct{})
+	go func() {
+		release := cache.KeyLock(other)
+		release()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		unlock()
+		t.Fatal("Expected a different key's lock to be available while one is held")
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		release := cache.KeyLock("a")
+		release()
+		close(blocked)
+	}()
+
+	select {
+	case <-blocked:
+		unlock()
+		t.Fatal("Expected the same key's lock to block while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-blocked
+}
@@ -0,0 +1,200 @@
+package fastcache
+
+import (
+	"container/list"
+	"path"
+	"strings"
+)
+
+// KeysMatching returns the live keys matching a glob pattern using path.Match
+// syntax ("*" matches any run of characters other than "/", "?" matches one
+// character, and "[...]" matches a character class). A malformed pattern
+// matches nothing.
+func (c *Cache) KeysMatching(pattern string) []string {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil
+	}
+
+	now := c.now()
+	var keys []string
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		for key, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+			if matched, _ := path.Match(pattern, key); matched {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return keys
+}
+
+// OrderedKeys returns the live keys of a shard from most to least recently
+// used, as tracked by its recency list: with PolicyFIFO this is newest to
+// oldest insertion, and with PolicyTinyLFU keys still on probation follow the
+// others, since they are the first eviction candidates. PolicyRandom and
+// PolicyGDSF keep no recency order, so their keys are returned in arbitrary
+// order. Pinned keys are never evicted and are omitted. It returns nil for an
+// invalid shard ID.
+func (c *Cache) OrderedKeys(shardID int) []string {
+	shards := c.allShards()
+	if shardID < 0 || shardID >= len(shards) {
+		return nil
+	}
+
+	shard := shards[shardID]
+	now := c.now()
+	live := func(entry *Entry) bool {
+		return entry.expiry == 0 || now <= entry.expiry
+	}
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	keys := make([]string, 0, len(shard.data))
+	if shard.lruList == nil {
+		for key, entry := range shard.data {
+			if !entry.pinned && live(entry) {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+
+	for _, l := range []*list.List{shard.lruList, shard.probation} {
+		if l == nil {
+			continue
+		}
+		for elem := l.Front(); elem != nil; elem = elem.Next() {
+			if entry := elem.Value.(*Entry); live(entry) {
+				keys = append(keys, entry.key)
+			}
+		}
+	}
+	return keys
+}
+
+// CountByPrefix returns the number of live keys starting with prefix without
+// collecting them. An empty prefix counts every live key.
+func (c *Cache) CountByPrefix(prefix string) int {
+	now := c.now()
+	count := 0
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		for key, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+			if strings.HasPrefix(key, prefix) {
+				count++
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return count
+}
+
+// GetByPrefix returns every live entry whose key starts with prefix, e.g. all
+// values under a "widget:dashboard7:" namespace. Keys sharing a prefix span
+// shards, so every shard is scanned under its read lock, but only matching
+// entries are copied; values are decoded after the lock is released. Each
+// returned key counts as a hit, as if read by Get, but keeps its recency.
+func (c *Cache) GetByPrefix(prefix string) map[string]interface{} {
+	results := make(map[string]interface{})
+	for _, shard := range c.allShards() {
+		now := c.now()
+		var entries []*Entry
+		var payloads []payload
+
+		shard.mu.RLock()
+		for key, entry := range shard.data {
+			if !strings.HasPrefix(key, prefix) || entry.isExpired(now) {
+				continue
+			}
+			entries = append(entries, entry)
+			payloads = append(payloads, entry.payload)
+		}
+		shard.mu.RUnlock()
+
+		for i, entry := range entries {
+			value, ok := c.loadValue(payloads[i].get())
+			if !ok {
+				continue
+			}
+			results[entry.key] = value
+			entry.recordAccess()
+			c.recordHit(shard, entry.key)
+		}
+	}
+	return results
+}
+
+// KeyValue is a key and its value, as returned by Snapshot
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// Snapshot returns every live entry. Each shard is copied under its lock and
+// released before the next is visited, so the result is a point-in-time view
+// per shard: no key appears twice and no shard's writes are seen half-applied,
+// but writes to different shards during the call may or may not be included.
+// Values are decoded after the shard lock is released.
+func (c *Cache) Snapshot() []KeyValue {
+	var result []KeyValue
+	for _, shard := range c.allShards() {
+		now := c.now()
+		start := len(result)
+		var payloads []payload
+
+		shard.mu.RLock()
+		for key, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+			result = append(result, KeyValue{Key: key})
+			payloads = append(payloads, entry.payload)
+		}
+		shard.mu.RUnlock()
+
+		kept := start
+		for i, p := range payloads {
+			value, ok := c.loadValue(p.get())
+			if !ok {
+				continue
+			}
+			result[kept] = KeyValue{Key: result[start+i].Key, Value: value}
+			kept++
+		}
+		result = result[:kept]
+	}
+	return result
+}
+
+// DeleteMatching removes all live keys matching a glob pattern (see KeysMatching)
+// and returns the number removed. A malformed pattern removes nothing.
+func (c *Cache) DeleteMatching(pattern string) int {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0
+	}
+
+	now := c.now()
+	removed := 0
+	for _, shard := range c.allShards() {
+		shard.mu.Lock()
+		for key, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+			if matched, _ := path.Match(pattern, key); matched {
+				c.removeLocked(shard, entry)
+				removed++
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
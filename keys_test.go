@@ -0,0 +1,252 @@
+package fastcache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeysMatching(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for _, key := range []string{
+		"user:1:profile", "user:2:profile", "user:10:profile",
+		"user:1:settings", "order:1", "order:2",
+	} {
+		_ = cache.Set(key, key)
+	}
+	_ = cache.Set("user:3:profile", "expired", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"user:*:profile", []string{"user:10:profile", "user:1:profile", "user:2:profile"}},
+		{"user:?:profile", []string{"user:1:profile", "user:2:profile"}},
+		{"order:*", []string{"order:1", "order:2"}},
+		{"user:1:*", []string{"user:1:profile", "user:1:settings"}},
+		{"missing:*", nil},
+		{"[", nil}, // malformed
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			keys := cache.KeysMatching(tt.pattern)
+			sort.Strings(keys)
+			if len(keys) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, keys)
+			}
+			for i := range keys {
+				if keys[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, keys)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCountByPrefix(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for i := 0; i < 30; i++ {
+		_ = cache.Set(fmt.Sprintf("user:%d", i), i)
+	}
+	for i := 0; i < 12; i++ {
+		_ = cache.Set(fmt.Sprintf("order:%d", i), i)
+	}
+	_ = cache.Set("user:expired", "expired", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		prefix   string
+		expected int
+	}{
+		{"user:", 30},
+		{"order:", 12},
+		{"user:1", 11}, // user:1 and user:10 through user:19
+		{"missing:", 0},
+		{"", 42},
+	}
+
+	for _, tt := range tests {
+		if count := cache.CountByPrefix(tt.prefix); count != tt.expected {
+			t.Errorf("CountByPrefix(%q) = %d, expected %d", tt.prefix, count, tt.expected)
+		}
+	}
+}
+
+func TestOrderedKeys(t *testing.T) {
+	newCache := func(policy EvictionPolicy) *Cache {
+		config := DefaultConfig()
+		config.ShardCount = 1
+		config.EvictionPolicy = policy
+		return New(config)
+	}
+
+	cache := newCache(PolicyLRU)
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("key_%d", i), i)
+	}
+	cache.Get("key_3")
+	cache.Get("key_7")
+
+	keys := cache.OrderedKeys(0)
+	if len(keys) != 20 {
+		t.Fatalf("Expected 20 keys, got %d", len(keys))
+	}
+	if keys[0] != "key_7" || keys[1] != "key_3" {
+		t.Errorf("Expected recently read keys at the front, got %v", keys[:3])
+	}
+	if keys[len(keys)-1] != "key_0" {
+		t.Errorf("Expected least recently used key last, got %s", keys[len(keys)-1])
+	}
+
+	if cache.OrderedKeys(-1) != nil || cache.OrderedKeys(1) != nil {
+		t.Error("Expected nil for an invalid shard ID")
+	}
+
+	// FIFO ignores reads: newest insertion first
+	fifo := newCache(PolicyFIFO)
+	defer fifo.Close()
+	for i := 0; i < 5; i++ {
+		_ = fifo.Set(fmt.Sprintf("key_%d", i), i)
+	}
+	fifo.Get("key_0")
+	if keys := fifo.OrderedKeys(0); keys[0] != "key_4" || keys[4] != "key_0" {
+		t.Errorf("Expected insertion order for FIFO, got %v", keys)
+	}
+
+	// Random has no order, but still returns every key
+	random := newCache(PolicyRandom)
+	defer random.Close()
+	for i := 0; i < 5; i++ {
+		_ = random.Set(fmt.Sprintf("key_%d", i), i)
+	}
+	if keys := random.OrderedKeys(0); len(keys) != 5 {
+		t.Errorf("Expected 5 keys for Random, got %v", keys)
+	}
+}
+
+func TestGetByPrefix(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(fmt.Sprintf("widget:dashboard7:%d", i), i)
+		_ = cache.Set(fmt.Sprintf("widget:dashboard8:%d", i), i)
+		_ = cache.Set(fmt.Sprintf("user:%d", i), i)
+	}
+	_ = cache.Set("widget:dashboard7:expired", "old", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	results := cache.GetByPrefix("widget:dashboard7:")
+	if len(results) != 10 {
+		t.Fatalf("Expected 10 entries under the prefix, got %d", len(results))
+	}
+	for i := 0; i < 10; i++ {
+		if value := results[fmt.Sprintf("widget:dashboard7:%d", i)]; value != i {
+			t.Errorf("Expected widget:dashboard7:%d = %d, got %v", i, i, value)
+		}
+	}
+
+	if hits := cache.GetStats().HitCount; hits != 10 {
+		t.Errorf("Expected each returned key to count as a hit, got %d hits", hits)
+	}
+	if results := cache.GetByPrefix("missing:"); len(results) != 0 {
+		t.Errorf("Expected no entries for an unused prefix, got %v", results)
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	for _, key := range []string{
+		"user:1:profile", "user:2:profile", "user:22:profile", "user:1:settings", "order:1",
+	} {
+		_ = cache.Set(key, key)
+	}
+
+	if removed := cache.DeleteMatching("user:?:profile"); removed != 2 {
+		t.Errorf("Expected 2 keys removed, got %d", removed)
+	}
+	if _, exists := cache.Get("user:22:profile"); !exists {
+		t.Error("user:22:profile should not match user:?:profile")
+	}
+
+	if removed := cache.DeleteMatching("user:*"); removed != 2 {
+		t.Errorf("Expected 2 keys removed, got %d", removed)
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Expected 1 key left, got %d", n)
+	}
+	if removed := cache.DeleteMatching("["); removed != 0 {
+		t.Errorf("Malformed pattern should remove nothing, removed %d", removed)
+	}
+}
+
+func TestSnapshotConcurrentWrites(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 16
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 1000; i++ {
+		_ = cache.Set(fmt.Sprintf("snap_%d", i), i)
+	}
+
+	// Writers keep updating existing keys with their own index and adding and
+	// removing other keys while snapshots are taken
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = cache.Set(fmt.Sprintf("snap_%d", i%1000), i%1000)
+				key := fmt.Sprintf("churn_%d_%d", w, i%100)
+				_ = cache.Set(key, -1)
+				cache.Delete(key)
+			}
+		}(w)
+	}
+
+	for round := 0; round < 20; round++ {
+		seen := make(map[string]bool)
+		stable := 0
+		for _, kv := range cache.Snapshot() {
+			if seen[kv.Key] {
+				t.Fatalf("Key %s appeared twice in a snapshot", kv.Key)
+			}
+			seen[kv.Key] = true
+
+			var i int
+			if _, err := fmt.Sscanf(kv.Key, "snap_%d", &i); err == nil {
+				stable++
+				if kv.Value != i {
+					t.Fatalf("Expected %s to hold %d, got %v", kv.Key, i, kv.Value)
+				}
+			}
+		}
+		if stable != 1000 {
+			t.Fatalf("Expected all 1000 stable keys in the snapshot, got %d", stable)
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,128 @@
+package fastcache
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Latency histograms split each power of two into latencySubBuckets linear
+// buckets, giving roughly 12% relative precision
+const (
+	latencySubBucketBits = 3
+	latencySubBuckets    = 1 << latencySubBucketBits
+	latencyBuckets       = 64 * latencySubBuckets
+)
+
+// LatencyPercentiles summarizes the latency distribution of one operation
+type LatencyPercentiles struct {
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+}
+
+// latencyHistogram is a log-linear histogram of durations in nanoseconds,
+// updated with atomic operations only
+type latencyHistogram struct {
+	counts [latencyBuckets]int64
+	total  int64
+}
+
+// latencyBucket returns the bucket index for a duration in nanoseconds
+func latencyBucket(ns uint64) int {
+	if ns < latencySubBuckets {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 1
+	sub := (ns >> uint(exp-latencySubBucketBits)) & (latencySubBuckets - 1)
+	return (exp-latencySubBucketBits+1)*latencySubBuckets + int(sub)
+}
+
+// latencyBucketUpper returns the largest duration in nanoseconds that falls in bucket i
+func latencyBucketUpper(i int) uint64 {
+	if i < latencySubBuckets {
+		return uint64(i)
+	}
+	exp := i/latencySubBuckets + latencySubBucketBits - 1
+	sub := uint64(i % latencySubBuckets)
+	return ((latencySubBuckets+sub+1)<<uint(exp-latencySubBucketBits) - 1)
+}
+
+// record adds a single observation
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.AddInt64(&h.counts[latencyBucket(uint64(d))], 1)
+	atomic.AddInt64(&h.total, 1)
+}
+
+// observe records the time elapsed since start in h. It is meant to be deferred.
+func (h *latencyHistogram) observe(start time.Time) {
+	h.record(time.Since(start))
+}
+
+// reset discards all observations
+func (h *latencyHistogram) reset() {
+	for i := range h.counts {
+		atomic.StoreInt64(&h.counts[i], 0)
+	}
+	atomic.StoreInt64(&h.total, 0)
+}
+
+// percentiles computes P50/P95/P99 from a snapshot of the bucket counts.
+// Each percentile is reported as the upper bound of the bucket it falls in.
+func (h *latencyHistogram) percentiles() LatencyPercentiles {
+	var counts [latencyBuckets]int64
+	var total int64
+	for i := range counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+		total += counts[i]
+	}
+
+	result := LatencyPercentiles{Count: total}
+	if total == 0 {
+		return result
+	}
+
+	targets := []struct {
+		rank int64
+		dst  *time.Duration
+	}{
+		{(total*50 + 99) / 100, &result.P50},
+		{(total*95 + 99) / 100, &result.P95},
+		{(total*99 + 99) / 100, &result.P99},
+	}
+
+	var seen int64
+	t := 0
+	for i := 0; i < latencyBuckets && t < len(targets); i++ {
+		seen += counts[i]
+		for t < len(targets) && seen >= targets[t].rank {
+			*targets[t].dst = time.Duration(latencyBucketUpper(i))
+			t++
+		}
+	}
+	return result
+}
+
+// latencyTracker holds one histogram per tracked operation
+type latencyTracker struct {
+	get    latencyHistogram
+	set    latencyHistogram
+	delete latencyHistogram
+}
+
+// LatencyStats returns latency percentiles keyed by operation ("get", "set",
+// "delete"). It returns nil unless TrackLatency is enabled.
+func (c *Cache) LatencyStats() map[string]LatencyPercentiles {
+	if c.latency == nil {
+		return nil
+	}
+	return map[string]LatencyPercentiles{
+		"get":    c.latency.get.percentiles(),
+		"set":    c.latency.set.percentiles(),
+		"delete": c.latency.delete.percentiles(),
+	}
+}
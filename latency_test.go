@@ -0,0 +1,72 @@
+package fastcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	for _, ns := range []uint64{0, 1, 7, 8, 15, 16, 17, 100, 1000, 123456, 1 << 40} {
+		i := latencyBucket(ns)
+		if upper := latencyBucketUpper(i); ns > upper {
+			t.Errorf("Value %d exceeds upper bound %d of its bucket %d", ns, upper, i)
+		}
+		if i > 0 && ns <= latencyBucketUpper(i-1) {
+			t.Errorf("Value %d should fall in an earlier bucket than %d", ns, i)
+		}
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	config := DefaultConfig()
+	config.TrackLatency = true
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		cache.Set(key, i)
+		cache.Get(key)
+		cache.Get("missing")
+		if i%2 == 0 {
+			cache.Delete(key)
+		}
+	}
+
+	stats := cache.LatencyStats()
+	expected := map[string]int64{"get": 2000, "set": 1000, "delete": 500}
+	for op, count := range expected {
+		p, ok := stats[op]
+		if !ok {
+			t.Fatalf("Missing latency stats for %s", op)
+		}
+		if p.Count != count {
+			t.Errorf("Expected %d %s observations, got %d", count, op, p.Count)
+		}
+		if p.P50 <= 0 {
+			t.Errorf("Expected a populated P50 for %s, got %v", op, p.P50)
+		}
+		if p.P50 > p.P95 || p.P95 > p.P99 {
+			t.Errorf("Percentiles for %s are not monotonic: %+v", op, p)
+		}
+		if p.P99 > time.Second {
+			t.Errorf("Implausible P99 for %s: %v", op, p.P99)
+		}
+	}
+
+	cache.ResetStats()
+	if p := cache.LatencyStats()["get"]; p.Count != 0 {
+		t.Errorf("Expected ResetStats to clear latency, got %+v", p)
+	}
+}
+
+func TestLatencyStatsDisabled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	cache.Set("key", "value")
+	if stats := cache.LatencyStats(); stats != nil {
+		t.Errorf("Expected nil latency stats when disabled, got %v", stats)
+	}
+}
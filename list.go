@@ -0,0 +1,75 @@
+package fastcache
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// listType is the type of values built by Append
+var listType = reflect.TypeOf([]interface{}(nil))
+
+// Append atomically appends items to the []interface{} stored under key,
+// creating the list if the key is missing or expired, and returns the list's
+// new length. An existing entry keeps its expiry; a new one gets DefaultTTL.
+// When MaxListLength is set, the oldest items are dropped from the front to
+// keep the list within it. If the stored value is not a []interface{},
+// nothing is written and an error wrapping ErrTypeMismatch is returned.
+//
+// The list is copied on every append, so slices previously returned by Get
+// are never modified.
+func (c *Cache) Append(key string, items ...interface{}) (int, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return 0, opError("append", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return 0, opError("append", key, err)
+	}
+	if err := c.checkValueType(listType); err != nil {
+		return 0, opError("append", key, err)
+	}
+
+	expiry := c.expiryFor(0)
+
+	shard := c.lockShard(key)
+
+	var current []interface{}
+	if existing, exists := shard.data[key]; exists && !existing.isExpired(c.now()) {
+		value, ok := c.loadValue(existing.payload.get())
+		list, isList := value.([]interface{})
+		if existing.isBytes || !ok || !isList {
+			shard.mu.Unlock()
+			return 0, opError("append", key, ErrTypeMismatch)
+		}
+		current = list
+		expiry = existing.expiry
+	}
+
+	// Skip the items a full list would drop rather than copying them
+	drop := 0
+	if limit := c.config.MaxListLength; limit > 0 && len(current)+len(items) > limit {
+		drop = len(current) + len(items) - limit
+	}
+	list := make([]interface{}, 0, len(current)+len(items)-drop)
+	if drop < len(current) {
+		list = append(list, current[drop:]...)
+		list = append(list, items...)
+	} else {
+		list = append(list, items[drop-len(current):]...)
+	}
+
+	prepared, err := c.prepareValue(list)
+	if err != nil {
+		shard.mu.Unlock()
+		return 0, opError("append", key, err)
+	}
+	sizeDiff, err := c.setLocked(shard, key, prepared, expiry)
+	shard.mu.Unlock()
+	if err != nil {
+		return 0, opError("append", key, err)
+	}
+
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return len(list), nil
+}
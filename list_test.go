@@ -0,0 +1,114 @@
+package fastcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	if n, err := cache.Append("events", "a", "b"); err != nil || n != 2 {
+		t.Fatalf("Expected new list of 2, got %d, %v", n, err)
+	}
+	if n, err := cache.Append("events", "c"); err != nil || n != 3 {
+		t.Fatalf("Expected list of 3, got %d, %v", n, err)
+	}
+
+	value, _ := cache.Get("events")
+	list := value.([]interface{})
+	if len(list) != 3 || list[0] != "a" || list[2] != "c" {
+		t.Errorf("Expected [a b c], got %v", list)
+	}
+
+	// A list returned by Get is not modified by later appends
+	_, _ = cache.Append("events", "d")
+	if len(list) != 3 || list[2] != "c" {
+		t.Errorf("Expected returned list to be unchanged, got %v", list)
+	}
+
+	_ = cache.Set("scalar", 42)
+	if _, err := cache.Append("scalar", 1); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch appending to an int, got %v", err)
+	}
+}
+
+func TestAppendMaxListLength(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxListLength = 3
+	cache := New(config)
+	defer cache.Close()
+
+	_, _ = cache.Append("ring", 1, 2)
+	_, _ = cache.Append("ring", 3, 4)
+	if n, _ := cache.Append("ring", 5, 6, 7, 8); n != 3 {
+		t.Errorf("Expected length bounded to 3, got %d", n)
+	}
+
+	value, _ := cache.Get("ring")
+	list := value.([]interface{})
+	if len(list) != 3 || list[0] != 6 || list[2] != 8 {
+		t.Errorf("Expected most recent items [6 7 8], got %v", list)
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	const appenders = 16
+	const perAppender = 200
+
+	var wg sync.WaitGroup
+	for i := 0; i < appenders; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < perAppender; j++ {
+				if _, err := cache.Append("list", id*perAppender+j); err != nil {
+					t.Errorf("Append failed: %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	value, _ := cache.Get("list")
+	list := value.([]interface{})
+	if len(list) != appenders*perAppender {
+		t.Fatalf("Expected %d items, got %d", appenders*perAppender, len(list))
+	}
+	seen := make(map[int]bool, len(list))
+	for _, item := range list {
+		seen[item.(int)] = true
+	}
+	if len(seen) != appenders*perAppender {
+		t.Errorf("Expected every appended item once, got %d distinct", len(seen))
+	}
+
+	config := DefaultConfig()
+	config.MaxListLength = 50
+	bounded := New(config)
+	defer bounded.Close()
+
+	for i := 0; i < appenders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perAppender; j++ {
+				if n, _ := bounded.Append("list", j); n > config.MaxListLength {
+					t.Errorf("Expected length at most %d, got %d", config.MaxListLength, n)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := bounded.Get("list"); len(value.([]interface{})) != config.MaxListLength {
+		t.Errorf("Expected full list of %d, got %d", config.MaxListLength, len(value.([]interface{})))
+	}
+}
@@ -0,0 +1,63 @@
+package fastcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Log levels passed to Config.Logger
+const (
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// logInterval is the minimum time between two log calls for the same event
+const logInterval = time.Second
+
+// logEvent identifies a kind of notable event for rate limiting
+type logEvent int
+
+const (
+	logEviction logEvent = iota
+	logRejectedWrite
+	logLoaderCircuitOpen
+	numLogEvents
+)
+
+// eventLogger rate limits calls to Config.Logger per kind of event. Events
+// dropped by the limit are counted and reported as "suppressed" on the next
+// call for the same event. A nil eventLogger logs nothing.
+type eventLogger struct {
+	logger     func(level, msg string, keysAndValues ...interface{})
+	interval   int64 // nanoseconds
+	last       [numLogEvents]int64
+	suppressed [numLogEvents]int64
+}
+
+// newEventLogger returns a rate-limited wrapper around logger, or nil if
+// logger is nil
+func newEventLogger(logger func(level, msg string, keysAndValues ...interface{})) *eventLogger {
+	if logger == nil {
+		return nil
+	}
+	return &eventLogger{logger: logger, interval: int64(logInterval)}
+}
+
+// log calls the logger unless the event was logged within the interval
+func (l *eventLogger) log(event logEvent, level, msg string, keysAndValues ...interface{}) {
+	if l == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&l.last[event])
+	if (last != 0 && now-last < l.interval) || !atomic.CompareAndSwapInt64(&l.last[event], last, now) {
+		atomic.AddInt64(&l.suppressed[event], 1)
+		return
+	}
+
+	if n := atomic.SwapInt64(&l.suppressed[event], 0); n > 0 {
+		keysAndValues = append(keysAndValues, "suppressed", n)
+	}
+	l.logger(level, msg, keysAndValues...)
+}
@@ -0,0 +1,107 @@
+package fastcache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger collects log calls
+type recordingLogger struct {
+	mu    sync.Mutex
+	calls []string
+	kvs   [][]interface{}
+}
+
+func (l *recordingLogger) log(level, msg string, keysAndValues ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, level+": "+msg)
+	l.kvs = append(l.kvs, keysAndValues)
+}
+
+func (l *recordingLogger) snapshot() ([]string, [][]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...), append([][]interface{}(nil), l.kvs...)
+}
+
+func TestLoggerEvictionRateLimited(t *testing.T) {
+	logger := &recordingLogger{}
+	config := &Config{
+		MaxMemoryBytes:  4 * 1024,
+		ShardCount:      4,
+		CleanupInterval: time.Second,
+		Logger:          logger.log,
+	}
+	cache := New(config)
+	defer cache.Close()
+
+	value := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		_ = cache.Set(fmt.Sprintf("flood_%d", i), value)
+	}
+
+	calls, _ := logger.snapshot()
+	if len(calls) != 1 {
+		t.Fatalf("Expected a single rate-limited eviction log, got %v", calls)
+	}
+	if calls[0] != "warn: evicted entries over memory limit" {
+		t.Errorf("Unexpected log call %q", calls[0])
+	}
+
+	// Once the interval has passed, the next event is logged with the number
+	// of suppressed events
+	cache.logs.interval = int64(10 * time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("flood_again_%d", i), value)
+	}
+
+	calls, kvs := logger.snapshot()
+	if len(calls) < 2 {
+		t.Fatalf("Expected a second eviction log, got %v", calls)
+	}
+	last := kvs[1]
+	if len(last) < 2 || last[len(last)-2] != "suppressed" || last[len(last)-1].(int64) == 0 {
+		t.Errorf("Expected suppressed count in %v", last)
+	}
+}
+
+func TestLoggerRejectedWritesAndCircuit(t *testing.T) {
+	logger := &recordingLogger{}
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 1024
+	config.RejectOnFull = true
+	config.LoaderFailureThreshold = 1
+	config.Logger = logger.log
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("half", make([]byte, 512))
+	_ = cache.Set("other_half", make([]byte, 512))
+	_, _, _ = cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+		return nil, 0, false, errors.New("backend down")
+	})
+
+	calls, _ := logger.snapshot()
+	want := []string{"warn: write rejected: memory limit exceeded", "error: loader circuit opened"}
+	if len(calls) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], calls[i])
+		}
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var logs *eventLogger
+	logs.log(logEviction, LogLevelWarn, "ignored")
+	if newEventLogger(nil) != nil {
+		t.Error("Expected no event logger without a Logger")
+	}
+}
@@ -0,0 +1,71 @@
+package fastcache
+
+import "sync/atomic"
+
+const (
+	// missFilterMinBits is the smallest miss filter, used when ExpectedEntries is unset
+	missFilterMinBits = 1 << 23
+
+	// missFilterBitsPerEntry sizes the miss filter from ExpectedEntries for
+	// roughly a 2% false positive rate with missFilterHashes
+	missFilterBitsPerEntry = 8
+
+	// missFilterHashes is the number of bits set per key
+	missFilterHashes = 4
+)
+
+// missFilter is a bloom filter of every key ever written to the cache, used
+// by EnableMissFilter to answer lookups of keys that were never written
+// without touching a shard. Bits are only ever set, so a "maybe present"
+// answer can be wrong but "absent" never is. All updates are atomic.
+type missFilter struct {
+	bits []uint64
+	mask uint64
+}
+
+// newMissFilter creates a filter sized for expected keys
+func newMissFilter(expected int64) *missFilter {
+	n := uint64(missFilterMinBits)
+	for int64(n) < expected*missFilterBitsPerEntry {
+		n <<= 1
+	}
+	return &missFilter{bits: make([]uint64, n/64), mask: n - 1}
+}
+
+// add records that key was written
+func (f *missFilter) add(key string) {
+	h := sketchHash(key)
+	h1, h2 := h, h>>32|1
+	for i := uint64(0); i < missFilterHashes; i++ {
+		bit := (h1 + i*h2) & f.mask
+		word := &f.bits[bit/64]
+		mask := uint64(1) << (bit % 64)
+		for {
+			old := atomic.LoadUint64(word)
+			if old&mask != 0 || atomic.CompareAndSwapUint64(word, old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+// mayContain reports whether key may have been written. False means it
+// definitely was not.
+func (f *missFilter) mayContain(key string) bool {
+	h := sketchHash(key)
+	h1, h2 := h, h>>32|1
+	for i := uint64(0); i < missFilterHashes; i++ {
+		bit := (h1 + i*h2) & f.mask
+		if atomic.LoadUint64(&f.bits[bit/64])&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reset clears every bit
+func (f *missFilter) reset() {
+	for i := range f.bits {
+		atomic.StoreUint64(&f.bits[i], 0)
+	}
+}
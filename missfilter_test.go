@@ -0,0 +1,87 @@
+package fastcache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMissFilter(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableMissFilter = true
+	cache := New(config)
+	defer cache.Close()
+
+	const inserted = 10000
+	for i := 0; i < inserted; i++ {
+		_ = cache.Set(fmt.Sprintf("present_%d", i), i)
+	}
+
+	// Inserted keys always pass through the filter and are found
+	for i := 0; i < inserted; i++ {
+		key := fmt.Sprintf("present_%d", i)
+		if !cache.filter.mayContain(key) {
+			t.Fatalf("Expected filter to pass inserted key %s", key)
+		}
+		if value, ok := cache.Get(key); !ok || value != i {
+			t.Fatalf("Expected %s to be found, got %v, %v", key, value, ok)
+		}
+	}
+
+	// Never-inserted keys are filtered, apart from rare false positives
+	passed := 0
+	for i := 0; i < inserted; i++ {
+		key := fmt.Sprintf("absent_%d", i)
+		if cache.filter.mayContain(key) {
+			passed++
+		}
+		if _, ok := cache.Get(key); ok {
+			t.Fatalf("Expected %s to miss", key)
+		}
+	}
+	if passed > inserted/100 {
+		t.Errorf("Expected under 1%% false positives, got %d of %d", passed, inserted)
+	}
+
+	stats := cache.GetStats()
+	if stats.HitCount != inserted || stats.MissCount != inserted {
+		t.Errorf("Expected filtered lookups to count as misses, got %d hits, %d misses", stats.HitCount, stats.MissCount)
+	}
+}
+
+func TestMissFilterAfterDelete(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableMissFilter = true
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("key", "value")
+	cache.Delete("key")
+	_ = cache.SetBytes("key", []byte("again"))
+
+	if value, ok := cache.GetBytes("key"); !ok || string(value) != "again" {
+		t.Errorf("Expected rewritten key to be found, got %q, %v", value, ok)
+	}
+
+	cache.Reset()
+	if cache.filter.mayContain("key") {
+		t.Error("Expected Reset to clear the filter")
+	}
+}
+
+func TestMissFilterSwapContents(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableMissFilter = true
+	live := New(config)
+	defer live.Close()
+	next := New(config)
+	defer next.Close()
+
+	_ = next.Set("new_only", "new")
+	if err := live.SwapContents(next); err != nil {
+		t.Fatalf("SwapContents failed: %v", err)
+	}
+
+	if value, ok := live.Get("new_only"); !ok || value != "new" {
+		t.Errorf("Expected swapped-in key to pass the filter, got %v, %v", value, ok)
+	}
+}
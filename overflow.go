@@ -0,0 +1,56 @@
+package fastcache
+
+import "time"
+
+// OverflowStore is a secondary cache tier. Entries evicted under memory pressure
+// are handed to Store, and a Get miss consults Load and promotes any value found
+// back into the cache. Implementations must be safe for concurrent use; they are
+// always called outside of shard locks.
+type OverflowStore interface {
+	// Store saves an evicted entry with its remaining TTL (0 means no expiry)
+	Store(key string, value interface{}, ttl time.Duration)
+
+	// Load returns a previously stored entry and its remaining TTL. Since the
+	// value is promoted back into the cache, implementations may remove it.
+	Load(key string) (value interface{}, ttl time.Duration, ok bool)
+}
+
+// demote hands evicted entries to the overflow store
+func (c *Cache) demote(entries []*Entry) {
+	store := c.config.OverflowStore
+	if store == nil {
+		return
+	}
+
+	now := c.now()
+	for _, entry := range entries {
+		var ttl time.Duration
+		if entry.expiry > 0 {
+			if now >= entry.expiry {
+				continue
+			}
+			ttl = time.Duration(entry.expiry - now)
+		}
+
+		if value, ok := c.loadValue(entry.get()); ok {
+			store.Store(entry.key, value, ttl)
+		}
+	}
+}
+
+// promoteFromOverflow loads a missing key from the overflow store and stores it back in
+// the cache, reporting whether it was found
+func (c *Cache) promoteFromOverflow(key string) (interface{}, bool) {
+	store := c.config.OverflowStore
+	if store == nil {
+		return nil, false
+	}
+
+	value, ttl, ok := store.Load(key)
+	if !ok {
+		return nil, false
+	}
+
+	_ = c.Set(key, value, ttl)
+	return value, true
+}
@@ -0,0 +1,93 @@
+package fastcache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryOverflow is an in-memory OverflowStore for tests
+type memoryOverflow struct {
+	mu     sync.Mutex
+	data   map[string]interface{}
+	stores int
+	loads  int
+}
+
+func (m *memoryOverflow) Store(key string, value interface{}, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	m.stores++
+}
+
+func (m *memoryOverflow) Load(key string) (interface{}, time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	value, ok := m.data[key]
+	if ok {
+		delete(m.data, key)
+	}
+	return value, 0, ok
+}
+
+func TestOverflowStore(t *testing.T) {
+	overflow := &memoryOverflow{data: make(map[string]interface{})}
+	config := &Config{
+		MaxMemoryBytes:  4 * 1024, // 4KB
+		ShardCount:      4,
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+		OverflowStore:   overflow,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	const inserted = 100
+	for i := 0; i < inserted; i++ {
+		_ = cache.Set(fmt.Sprintf("tier_key_%d", i), fmt.Sprintf("%0150d", i))
+	}
+
+	if overflow.stores == 0 {
+		t.Fatal("Expected evicted entries to be demoted to the overflow store")
+	}
+
+	// Find a key that was evicted from the hot tier
+	var evictedKey string
+	for key := range overflow.data {
+		evictedKey = key
+		break
+	}
+
+	var i int
+	fmt.Sscanf(evictedKey, "tier_key_%d", &i)
+
+	// Make room so promoting the key does not immediately evict it again
+	cache.Clear()
+
+	value, exists := cache.Get(evictedKey)
+	if !exists {
+		t.Fatalf("Evicted key %s should be retrievable via the overflow store", evictedKey)
+	}
+	if value.(string) != fmt.Sprintf("%0150d", i) {
+		t.Errorf("Unexpected value for %s: %v", evictedKey, value)
+	}
+
+	// The value was promoted back into the hot tier
+	loads := overflow.loads
+	if _, exists := cache.getShard(evictedKey).data[evictedKey]; !exists {
+		t.Errorf("Expected %s to be promoted back into the cache", evictedKey)
+	}
+	cache.Get(evictedKey)
+	if overflow.loads != loads {
+		t.Error("Promoted key should be served from the hot tier")
+	}
+
+	// Keys never stored anywhere are still misses
+	if _, exists := cache.Get("never_stored"); exists {
+		t.Error("Expected miss for a key in neither tier")
+	}
+}
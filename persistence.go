@@ -0,0 +1,231 @@
+package fastcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxTextLineSize is the longest line LoadText accepts
+const maxTextLineSize = 1024 * 1024
+
+// loadCheckEvery is how many records LoadTextContext and RestoreFromContext
+// load between context checks, starting before the first record
+const loadCheckEvery = 64
+
+// LoadText populates the cache from r, which holds one key=value pair per line.
+// Each line is split on the first "=" and stored as a string value with the
+// given TTL. Blank lines and lines starting with "#" are skipped. It returns
+// the number of entries loaded; a malformed line stops the load with ErrParse.
+func (c *Cache) LoadText(r io.Reader, ttl time.Duration) (int, error) {
+	return c.LoadTextContext(context.Background(), r, ttl)
+}
+
+// LoadTextContext is like LoadText but stops early with ctx.Err() once ctx is
+// canceled, e.g. to abort a long load on shutdown. Entries already loaded are
+// kept. Cancellation is checked between lines, so a Read blocked on r is not
+// interrupted.
+func (c *Cache) LoadTextContext(ctx context.Context, r io.Reader, ttl time.Duration) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTextLineSize)
+
+	loaded := 0
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if lineNum%loadCheckEvery == 1 {
+			if err := ctx.Err(); err != nil {
+				return loaded, err
+			}
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return loaded, ErrParse{Line: lineNum, Message: "missing '='"}
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" {
+			return loaded, ErrParse{Line: lineNum, Message: "empty key"}
+		}
+
+		if err := c.Set(key, line[i+1:], ttl); err != nil {
+			return loaded, err
+		}
+		loaded++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return loaded, err
+	}
+	return loaded, nil
+}
+
+// maxDumpRecordSize is the largest record RestoreFrom accepts
+const maxDumpRecordSize = 1 << 30
+
+// dumpRecord is a single entry as written by DumpTo
+type dumpRecord struct {
+	Key     string
+	Value   interface{}
+	Bytes   []byte
+	IsBytes bool
+	Expiry  int64 // Unix nanoseconds, 0 for no expiry
+}
+
+// DumpTo streams every live entry to w, one shard at a time, as a sequence of
+// records: a uvarint length followed by a gob-encoded entry. Only one shard's
+// entries are held in memory at once, and no shard lock is held while
+// writing. Values are written decoded and decompressed; custom value types
+// stored behind interface{} must be registered with gob.Register. Entries
+// written during the dump may or may not be included.
+func (c *Cache) DumpTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf bytes.Buffer
+	var lenBuf [binary.MaxVarintLen64]byte
+
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		entries := make([]*Entry, 0, len(shard.data))
+		payloads := make([]payload, 0, len(shard.data))
+		expiries := make([]int64, 0, len(shard.data))
+		for _, entry := range shard.data {
+			entries = append(entries, entry)
+			payloads = append(payloads, entry.payload)
+			expiries = append(expiries, entry.expiry)
+		}
+		shard.mu.RUnlock()
+
+		now := c.now()
+		for i, entry := range entries {
+			if expiries[i] > 0 && now > expiries[i] {
+				continue
+			}
+
+			record := dumpRecord{Key: entry.key, Expiry: expiries[i]}
+			if payloads[i].isBytes {
+				record.Bytes, record.IsBytes = payloads[i].bytes, true
+			} else {
+				value, ok := c.loadValue(payloads[i].value)
+				if !ok {
+					continue
+				}
+				record.Value = value
+			}
+
+			buf.Reset()
+			if err := gob.NewEncoder(&buf).Encode(&record); err != nil {
+				return fmt.Errorf("dump key %q: %w", entry.key, err)
+			}
+			n := binary.PutUvarint(lenBuf[:], uint64(buf.Len()))
+			if _, err := bw.Write(lenBuf[:n]); err != nil {
+				return err
+			}
+			if _, err := bw.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// RestoreFrom reads records written by DumpTo from r and stores them with
+// their original expiry, one record at a time. Entries that have expired since
+// the dump are skipped. Existing entries with the same keys are overwritten.
+func (c *Cache) RestoreFrom(r io.Reader) error {
+	return c.RestoreFromContext(context.Background(), r)
+}
+
+// RestoreFromContext is like RestoreFrom but stops early with ctx.Err() once
+// ctx is canceled, keeping the entries already restored. Cancellation is
+// checked between records, so a Read blocked on r is not interrupted.
+func (c *Cache) RestoreFromContext(ctx context.Context, r io.Reader) error {
+	br := bufio.NewReader(r)
+	var buf []byte
+
+	for records := 1; ; records++ {
+		if records%loadCheckEvery == 1 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		if size > maxDumpRecordSize {
+			return fmt.Errorf("restore: record of %d bytes exceeds limit", size)
+		}
+
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+
+		var record dumpRecord
+		if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&record); err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		if record.Expiry > 0 && c.now() > record.Expiry {
+			continue
+		}
+		if err := c.restoreRecord(&record); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreRecord stores a dumped entry with its absolute expiry
+func (c *Cache) restoreRecord(record *dumpRecord) error {
+	if c.isClosed() {
+		return opError("restore", record.Key, ErrCacheClosed)
+	}
+	if err := c.validateKey(record.Key); err != nil {
+		return opError("restore", record.Key, err)
+	}
+
+	var p payload
+	var size int64
+	if record.IsBytes {
+		if err := c.checkValueType(bytesType); err != nil {
+			return opError("restore", record.Key, err)
+		}
+		p = payload{bytes: record.Bytes, isBytes: true}
+		size = int64(len(record.Key)+len(record.Bytes)) + 64
+	} else {
+		value, err := c.prepareValue(record.Value)
+		if err != nil {
+			return opError("restore", record.Key, err)
+		}
+		p = payload{value: value}
+		size = c.entrySize(record.Key, value)
+	}
+
+	shard := c.lockShard(record.Key)
+	sizeDiff, err := c.putLocked(shard, record.Key, p, size, c.clampExpiry(record.Expiry))
+	shard.mu.Unlock()
+	if err != nil {
+		return opError("restore", record.Key, err)
+	}
+
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
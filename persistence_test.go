@@ -0,0 +1,244 @@
+package fastcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadText(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	input := `# seed data
+user:1=alice
+
+user:2 = bob
+query=a=b&c=d
+  # indented comment
+empty=
+`
+
+	loaded, err := cache.LoadText(strings.NewReader(input), time.Minute)
+	if err != nil {
+		t.Fatalf("LoadText failed: %v", err)
+	}
+	if loaded != 4 {
+		t.Errorf("Expected 4 entries loaded, got %d", loaded)
+	}
+
+	expected := map[string]string{
+		"user:1": "alice",
+		"user:2": " bob",
+		"query":  "a=b&c=d",
+		"empty":  "",
+	}
+	for key, want := range expected {
+		value, exists := cache.Get(key)
+		if !exists || value.(string) != want {
+			t.Errorf("Expected %s=%q, got %v, %v", key, want, value, exists)
+		}
+	}
+}
+
+func TestLoadTextParseError(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	input := "a=1\nb=2\n# comment\nnot a pair\nc=3\n"
+
+	loaded, err := cache.LoadText(strings.NewReader(input), 0)
+
+	var parseErr ErrParse
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected ErrParse, got %v", err)
+	}
+	if parseErr.Line != 4 {
+		t.Errorf("Expected parse error on line 4, got %d", parseErr.Line)
+	}
+	if loaded != 2 {
+		t.Errorf("Expected 2 entries loaded before the error, got %d", loaded)
+	}
+	if _, exists := cache.Get("c"); exists {
+		t.Error("Lines after the parse error should not be loaded")
+	}
+}
+
+func TestDumpRestoreStreaming(t *testing.T) {
+	src := New(DefaultConfig())
+	defer src.Close()
+
+	for i := 0; i < 500; i++ {
+		_ = src.Set(fmt.Sprintf("str_%d", i), fmt.Sprintf("value_%d", i))
+	}
+	_ = src.Set("int", 42)
+	_ = src.SetBytes("bytes", []byte{1, 2, 3})
+	_ = src.Set("expiring", "soon", time.Hour)
+	_ = src.Set("expired", "gone", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	dst := New(DefaultConfig())
+	defer dst.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(src.DumpTo(pw))
+	}()
+	if err := dst.RestoreFrom(pr); err != nil {
+		t.Fatalf("RestoreFrom failed: %v", err)
+	}
+
+	if n := dst.Len(); n != 503 {
+		t.Errorf("Expected 503 restored entries, got %d", n)
+	}
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("str_%d", i)
+		if value, ok := dst.Get(key); !ok || value != fmt.Sprintf("value_%d", i) {
+			t.Fatalf("Expected %s to survive, got %v, %v", key, value, ok)
+		}
+	}
+	if value, ok := dst.Get("int"); !ok || value != 42 {
+		t.Errorf("Expected int value, got %v, %v", value, ok)
+	}
+	if value, ok := dst.GetBytes("bytes"); !ok || !bytes.Equal(value, []byte{1, 2, 3}) {
+		t.Errorf("Expected bytes value, got %v, %v", value, ok)
+	}
+	if _, ok := dst.Get("expired"); ok {
+		t.Error("Expected expired entry to be skipped")
+	}
+	if info, ok := dst.EntryInfo("expiring"); !ok || info.TTL <= 59*time.Minute {
+		t.Errorf("Expected expiry to be preserved, got %+v", info)
+	}
+}
+
+func TestRestoreFromTruncated(t *testing.T) {
+	src := New(DefaultConfig())
+	defer src.Close()
+	_ = src.Set("key", "value")
+
+	var buf bytes.Buffer
+	if err := src.DumpTo(&buf); err != nil {
+		t.Fatalf("DumpTo failed: %v", err)
+	}
+
+	dst := New(DefaultConfig())
+	defer dst.Close()
+	if err := dst.RestoreFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Error("Expected an error for a truncated dump")
+	}
+}
+
+// cancelingReader yields an endless stream of key=value lines and cancels
+// its context once after lines have been read
+type cancelingReader struct {
+	buf    bytes.Buffer
+	lines  int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) {
+		fmt.Fprintf(&r.buf, "key_%d=value\n", r.lines)
+		r.lines++
+		if r.lines == r.after {
+			r.cancel()
+		}
+	}
+	return r.buf.Read(p)
+}
+
+func TestLoadTextContextCanceled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelingReader{after: 10000, cancel: cancel}
+
+	start := time.Now()
+	loaded, err := cache.LoadTextContext(ctx, r, time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the load to stop promptly, took %v", elapsed)
+	}
+	if loaded < r.after/2 || loaded > r.lines {
+		t.Errorf("Expected a partial load near %d entries, got %d", r.after, loaded)
+	}
+
+	// Entries loaded before the cancellation stay in place
+	if stats := cache.GetStats(); stats.TotalEntries != int64(loaded) {
+		t.Errorf("Expected %d entries kept, got %d", loaded, stats.TotalEntries)
+	}
+	if _, found := cache.Get("key_0"); !found {
+		t.Error("Expected key_0 to be kept")
+	}
+
+	// An already canceled context loads nothing
+	loaded, err = cache.LoadTextContext(ctx, strings.NewReader("late=value\n"), time.Minute)
+	if !errors.Is(err, context.Canceled) || loaded != 0 {
+		t.Errorf("Expected nothing loaded with a canceled context, got %d, %v", loaded, err)
+	}
+	if _, found := cache.Get("late"); found {
+		t.Error("Expected late not to be loaded")
+	}
+}
+
+func TestRestoreFromContextCanceled(t *testing.T) {
+	source := New(DefaultConfig())
+	defer source.Close()
+	for i := 0; i < 5000; i++ {
+		_ = source.Set(fmt.Sprintf("key_%d", i), i)
+	}
+	var dump bytes.Buffer
+	if err := source.DumpTo(&dump); err != nil {
+		t.Fatalf("DumpTo failed: %v", err)
+	}
+
+	// Canceled partway through the dump, the entries read so far are kept
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelAfterReader{r: bytes.NewReader(dump.Bytes()), remaining: dump.Len() / 2, cancel: cancel}
+
+	target := New(DefaultConfig())
+	defer target.Close()
+	if err := target.RestoreFromContext(ctx, r); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if stats := target.GetStats(); stats.TotalEntries == 0 || stats.TotalEntries >= 5000 {
+		t.Errorf("Expected a partial restore, got %d entries", stats.TotalEntries)
+	}
+
+	// An already canceled context restores nothing
+	empty := New(DefaultConfig())
+	defer empty.Close()
+	if err := empty.RestoreFromContext(ctx, bytes.NewReader(dump.Bytes())); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if stats := empty.GetStats(); stats.TotalEntries != 0 {
+		t.Errorf("Expected nothing restored with a canceled context, got %d entries", stats.TotalEntries)
+	}
+}
+
+// cancelAfterReader reads from r and cancels its context once remaining
+// bytes have been read
+type cancelAfterReader struct {
+	r         io.Reader
+	remaining int
+	cancel    context.CancelFunc
+}
+
+func (r *cancelAfterReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if r.remaining -= n; r.remaining <= 0 {
+		r.cancel()
+	}
+	return n, err
+}
@@ -0,0 +1,39 @@
+package fastcache
+
+import "sync/atomic"
+
+// Pin marks key as non-evictable so it survives memory pressure. Pinned
+// entries still count toward memory, still expire, and can still be removed
+// with Delete; the pin lasts until Unpin or until the entry is removed, and is
+// kept when the key is overwritten. It returns false if the key is not present.
+func (c *Cache) Pin(key string) bool {
+	return c.setPinned(key, true)
+}
+
+// Unpin makes a pinned key evictable again. It returns false if the key is not present.
+func (c *Cache) Unpin(key string) bool {
+	return c.setPinned(key, false)
+}
+
+// setPinned pins or unpins the live entry for key
+func (c *Cache) setPinned(key string, pinned bool) bool {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false
+	}
+
+	shard := c.lockShard(key)
+	defer shard.mu.Unlock()
+
+	entry, exists := shard.data[key]
+	if !exists || entry.isExpired(c.now()) {
+		return false
+	}
+
+	if pinned {
+		shard.pin(entry)
+	} else {
+		shard.unpin(entry)
+		c.markPromoted(entry)
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package fastcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyRandom, PolicyTinyLFU, PolicyGDSF} {
+		t.Run(policy.String(), func(t *testing.T) {
+			config := &Config{
+				MaxMemoryBytes:  8 * 1024, // 8KB
+				ShardCount:      4,
+				DefaultTTL:      0,
+				CleanupInterval: time.Second,
+				EvictionPolicy:  policy,
+			}
+
+			cache := New(config)
+			defer cache.Close()
+
+			cache.Set("feature_flags", "on")
+			if !cache.Pin("feature_flags") {
+				t.Fatal("Pin failed for an existing key")
+			}
+			if cache.Pin("missing") {
+				t.Error("Pin should fail for a missing key")
+			}
+
+			value := make([]byte, 150)
+			const inserted = 500
+			for i := 0; i < inserted; i++ {
+				cache.Set(fmt.Sprintf("flood_%d", i), value)
+			}
+
+			if value, exists := cache.Get("feature_flags"); !exists || value.(string) != "on" {
+				t.Fatalf("Pinned key should survive eviction, got %v, %v", value, exists)
+			}
+			if stats := cache.GetStats(); stats.TotalEntries >= inserted {
+				t.Errorf("Expected other entries to be evicted, got %d entries", stats.TotalEntries)
+			}
+
+			// Once unpinned, the key becomes evictable again. The new keys
+			// are read too, so frequency-based policies rank them above it.
+			if !cache.Unpin("feature_flags") {
+				t.Fatal("Unpin failed for an existing key")
+			}
+			for i := 0; i < inserted; i++ {
+				key := fmt.Sprintf("flood_again_%d", i)
+				cache.Set(key, value)
+				cache.Get(key)
+				cache.Get(key)
+			}
+			if _, exists := cache.Get("feature_flags"); exists {
+				t.Error("Unpinned key should have been evicted")
+			}
+		})
+	}
+}
+
+func TestPinAllEntriesInShard(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  2 * 1024, // 2KB
+		ShardCount:      1,
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	value := make([]byte, 100)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("pinned_%d", i)
+		cache.Set(key, value)
+		cache.Pin(key)
+	}
+
+	// Eviction has no victims and must give up rather than loop
+	done := make(chan struct{})
+	go func() {
+		cache.Set("overflow", make([]byte, 1024))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Set blocked with every entry pinned")
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("pinned_%d", i)); !exists {
+			t.Errorf("pinned_%d should not have been evicted", i)
+		}
+	}
+
+	// Pinned entries can still be deleted explicitly
+	if !cache.Delete("pinned_0") {
+		t.Error("Delete should remove a pinned entry")
+	}
+}
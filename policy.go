@@ -0,0 +1,305 @@
+package fastcache
+
+import (
+	"container/list"
+	"math/rand"
+	"sync/atomic"
+)
+
+// EvictionPolicy selects how victims are chosen when the cache is over its memory limit
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used entry in a shard (default)
+	PolicyLRU EvictionPolicy = iota
+
+	// PolicyRandom evicts a pseudo-random entry from a shard. No recency list is
+	// maintained, so Get only takes a read lock.
+	PolicyRandom
+
+	// PolicyTinyLFU gates admission on access frequency. New entries start on
+	// probation and graduate to the LRU list when read; the eviction victim is
+	// whichever of the oldest probationary and oldest LRU entry has been
+	// accessed less often. Frequencies decay over time, so entries that were
+	// once hot but are now cold become evictable. This resists scans and
+	// one-hit wonders at the cost of a counter update on every access.
+	PolicyTinyLFU
+
+	// PolicySizeAware keeps an LRU list but, among the few least recently used
+	// entries, evicts the largest first. One large cold entry is reclaimed
+	// before several small ones that are only slightly colder, freeing more
+	// memory per eviction.
+	PolicySizeAware
+
+	// PolicyFIFO evicts the oldest inserted entry in a shard. Reads and updates
+	// do not reorder entries, so Get only takes a read lock.
+	PolicyFIFO
+
+	// PolicyGDSF (Greedy-Dual-Size-Frequency) evicts the entry with the lowest
+	// access frequency per byte, aged so that entries not read for a while
+	// lose their advantage. When value sizes vary widely this keeps many
+	// small, frequently read entries instead of a few large ones, maximizing
+	// hits per byte. Entries are kept in a per-shard priority queue, so every
+	// read and write costs O(log n) under the shard's write lock.
+	PolicyGDSF
+)
+
+// sizeAwareWindow is the number of least recently used entries PolicySizeAware
+// compares by size
+const sizeAwareWindow = 8
+
+// String returns the name of the policy
+func (p EvictionPolicy) String() string {
+	switch p {
+	case PolicyLRU:
+		return "lru"
+	case PolicyRandom:
+		return "random"
+	case PolicyTinyLFU:
+		return "tinylfu"
+	case PolicySizeAware:
+		return "size_aware"
+	case PolicyFIFO:
+		return "fifo"
+	case PolicyGDSF:
+		return "gdsf"
+	default:
+		return "unknown"
+	}
+}
+
+// isValid reports whether p is a known policy
+func (p EvictionPolicy) isValid() bool {
+	switch p {
+	case PolicyLRU, PolicyRandom, PolicyTinyLFU, PolicySizeAware, PolicyFIFO, PolicyGDSF:
+		return true
+	default:
+		return false
+	}
+}
+
+// usesList reports whether the policy maintains a per-shard recency list
+func (p EvictionPolicy) usesList() bool {
+	return p != PolicyRandom && p != PolicyGDSF
+}
+
+// promotesOnAccess reports whether reads and updates move an entry to the
+// front of the recency list, or reprioritize it for PolicyGDSF. Policies that
+// ignore access skip the write lock this would take on every read.
+func (p EvictionPolicy) promotesOnAccess() bool {
+	return (p.usesList() && p != PolicyFIFO) || p == PolicyGDSF
+}
+
+// link registers a newly inserted entry with the shard's eviction policy
+func (s *Shard) link(e *Entry) {
+	if e.pinned {
+		return
+	}
+	if s.gdsf != nil {
+		s.gdsf.add(e)
+		return
+	}
+	if s.probation != nil {
+		e.listNode = s.probation.PushFront(e)
+		e.probation = true
+		return
+	}
+	if s.lruList != nil {
+		e.listNode = s.lruList.PushFront(e)
+	}
+}
+
+// promote records an access to an entry
+func (s *Shard) promote(e *Entry) {
+	if e.pinned {
+		return
+	}
+	if s.gdsf != nil {
+		s.gdsf.touch(e)
+		return
+	}
+	if e.probation {
+		// A second access graduates the entry from probation
+		s.probation.Remove(e.listNode)
+		e.listNode = s.lruList.PushFront(e)
+		e.probation = false
+		return
+	}
+	if s.lruList != nil {
+		s.lruList.MoveToFront(e.listNode)
+	}
+}
+
+// unlink removes an entry from the shard's eviction bookkeeping
+func (s *Shard) unlink(e *Entry) {
+	if e.pinned {
+		return
+	}
+	if s.gdsf != nil {
+		s.gdsf.remove(e)
+		return
+	}
+	if e.probation {
+		s.probation.Remove(e.listNode)
+		return
+	}
+	if s.lruList != nil {
+		s.lruList.Remove(e.listNode)
+	}
+}
+
+// victim returns the next entry to evict, or nil if the shard is empty
+func (s *Shard) victim() *Entry {
+	if s.gdsf != nil {
+		return s.gdsf.victim()
+	}
+	if s.lruList != nil {
+		if s.probation != nil {
+			return s.admit()
+		}
+		if s.sizeWindow > 0 {
+			return s.largestCold()
+		}
+		if oldest := s.lruList.Back(); oldest != nil {
+			return oldest.Value.(*Entry)
+		}
+		return nil
+	}
+
+	// Map iteration order is randomized, so the first entry is a pseudo-random pick
+	for _, entry := range s.data {
+		if !entry.pinned {
+			return entry
+		}
+	}
+	return nil
+}
+
+// largestCold returns the largest of the sizeWindow least recently used
+// entries, preferring the older entry on ties
+func (s *Shard) largestCold() *Entry {
+	var largest *Entry
+	elem := s.lruList.Back()
+	for i := 0; i < s.sizeWindow && elem != nil; i++ {
+		entry := elem.Value.(*Entry)
+		if largest == nil || entry.size > largest.size {
+			largest = entry
+		}
+		elem = elem.Prev()
+	}
+	return largest
+}
+
+// pin excludes an entry from eviction. Pinned entries are removed from the
+// recency lists, so victim never has to skip over them.
+func (s *Shard) pin(e *Entry) {
+	if e.pinned {
+		return
+	}
+	s.unlink(e)
+	e.pinned = true
+}
+
+// unpin makes a pinned entry evictable again as the most recently used entry
+func (s *Shard) unpin(e *Entry) {
+	if !e.pinned {
+		return
+	}
+	e.pinned = false
+	e.probation = false
+	if s.gdsf != nil {
+		s.gdsf.add(e)
+		return
+	}
+	if s.lruList != nil {
+		e.listNode = s.lruList.PushFront(e)
+	}
+}
+
+// reset drops all entries from the shard
+func (s *Shard) reset() {
+	s.data = make(map[string]*Entry, s.capacity)
+	if s.lruList != nil {
+		s.lruList = list.New()
+	}
+	if s.probation != nil {
+		s.probation = list.New()
+	}
+	if s.gdsf != nil {
+		s.gdsf = &gdsfQueue{}
+	}
+}
+
+// samplesEviction reports whether eviction approximates global LRU by
+// sampling shards
+func (c *Cache) samplesEviction() bool {
+	return c.config.EvictionSamples > 0 && c.config.EvictionPolicy == PolicyLRU
+}
+
+// evictSampled evicts entries until memory is at or below target, each time
+// choosing the least recently used of the LRU tails of EvictionSamples random
+// shards, and returns the number evicted. Recency is compared by the time an
+// entry was last written or promoted.
+func (c *Cache) evictSampled(target int64) int {
+	evicted := 0
+	for atomic.LoadInt64(&c.totalSize) > target {
+		shard, entry := c.sampleVictim()
+		if entry != nil {
+			// A victim promoted or removed since it was sampled is simply
+			// resampled
+			if c.evictEntry(shard, entry) {
+				evicted++
+			}
+			continue
+		}
+
+		// Every sampled shard was empty; fall back to a full pass so
+		// eviction always makes progress
+		n := c.evictRoundRobin(target)
+		if n == 0 {
+			break
+		}
+		evicted += n
+	}
+	return evicted
+}
+
+// sampleVictim returns the least recently used of the LRU tails of
+// EvictionSamples randomly chosen shards, or nil if they are all empty
+func (c *Cache) sampleVictim() (*Shard, *Entry) {
+	shards := c.allShards()
+	var victimShard *Shard
+	var victim *Entry
+	var oldest int64
+	for i := 0; i < c.config.EvictionSamples; i++ {
+		shard := shards[rand.Intn(len(shards))]
+		shard.mu.RLock()
+		if tail := shard.lruList.Back(); tail != nil {
+			entry := tail.Value.(*Entry)
+			if used := atomic.LoadInt64(&entry.lastPromoted); victim == nil || used < oldest {
+				victimShard, victim, oldest = shard, entry, used
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return victimShard, victim
+}
+
+// evictEntry evicts entry if it is still stored and the least recently used
+// entry of its shard, and reports whether it did
+func (c *Cache) evictEntry(shard *Shard, entry *Entry) bool {
+	shard.mu.Lock()
+	tail := shard.lruList.Back()
+	if shard.data[entry.key] != entry || tail == nil || tail.Value.(*Entry) != entry {
+		shard.mu.Unlock()
+		return false
+	}
+	c.removeLocked(shard, entry)
+	shard.mu.Unlock()
+	atomic.AddInt64(&c.evictions, 1)
+
+	evicted := []*Entry{entry}
+	c.demote(evicted)
+	c.notifyEvicted(evicted)
+	return true
+}
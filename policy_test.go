@@ -0,0 +1,399 @@
+package fastcache
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRandomEviction(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  8 * 1024, // 8KB
+		ShardCount:      4,
+		DefaultTTL:      0,
+		CleanupInterval: time.Second,
+		EvictionPolicy:  PolicyRandom,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	for _, shard := range cache.allShards() {
+		if shard.lruList != nil {
+			t.Fatal("Random policy should not allocate an LRU list")
+		}
+	}
+
+	value := make([]byte, 150)
+	const inserted = 200
+	for i := 0; i < inserted; i++ {
+		_ = cache.Set(fmt.Sprintf("random_key_%d", i), value)
+	}
+
+	stats := cache.GetStats()
+	if stats.TotalEntries >= inserted {
+		t.Errorf("Expected eviction with random policy, got %d entries", stats.TotalEntries)
+	}
+	if stats.TotalSize > config.MaxMemoryBytes*3 {
+		t.Errorf("Memory usage too high: %d bytes (limit: %d)", stats.TotalSize, config.MaxMemoryBytes)
+	}
+
+	// Surviving entries are still readable and deletable
+	found := 0
+	for i := 0; i < inserted; i++ {
+		key := fmt.Sprintf("random_key_%d", i)
+		if _, exists := cache.Get(key); exists {
+			found++
+			if !cache.Delete(key) {
+				t.Errorf("Delete failed for %s", key)
+			}
+		}
+	}
+	if int64(found) != stats.TotalEntries {
+		t.Errorf("Expected %d readable entries, got %d", stats.TotalEntries, found)
+	}
+	if size := cache.GetStats().TotalSize; size != 0 {
+		t.Errorf("Expected 0 size after deleting all entries, got %d", size)
+	}
+}
+
+func TestInvalidEvictionPolicy(t *testing.T) {
+	config := DefaultConfig()
+	config.EvictionPolicy = EvictionPolicy(99)
+	if err := config.Validate(); err == nil {
+		t.Error("Expected unknown eviction policy to be rejected")
+	}
+}
+
+func TestLazyLRUEviction(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:       8 * 1024, // 8KB
+		ShardCount:           4,
+		DefaultTTL:           0,
+		CleanupInterval:      time.Second,
+		LRUPromotionInterval: 10 * time.Millisecond,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 30; i++ {
+		_ = cache.Set(fmt.Sprintf("lazy_key_%d", i), make([]byte, 150))
+	}
+
+	// Wait out the promotion window so reads promote again
+	time.Sleep(2 * config.LRUPromotionInterval)
+
+	recentKeys := make([]string, 5)
+	for i := range recentKeys {
+		recentKeys[i] = fmt.Sprintf("lazy_key_%d", i)
+		for j := 0; j < 3; j++ {
+			cache.Get(recentKeys[i])
+		}
+	}
+
+	// Evict fewer entries than there are keys older than the recent ones
+	for i := 30; i < 50; i++ {
+		_ = cache.Set(fmt.Sprintf("lazy_key_%d", i), make([]byte, 150))
+	}
+
+	stillExists := 0
+	for _, key := range recentKeys {
+		if _, exists := cache.Get(key); exists {
+			stillExists++
+		}
+	}
+
+	// Same tolerance as strict LRU since eviction is distributed across shards
+	if stillExists < 2 {
+		t.Errorf("Expected at least 2 recently used keys to survive, got %d", stillExists)
+	}
+}
+
+func TestLazyLRUSkipsPromotion(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 1
+	config.LRUPromotionInterval = time.Hour
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("first", 1)
+	_ = cache.Set("second", 2)
+
+	// Within the promotion window, reading "first" does not move it to the front
+	cache.Get("first")
+
+	front := cache.allShards()[0].lruList.Front().Value.(*Entry)
+	if front.key != "second" {
+		t.Errorf("Expected 'second' at front of LRU list, got '%s'", front.key)
+	}
+}
+
+func TestSizeAwareEviction(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  16 * 1024, // 16KB
+		ShardCount:      1,
+		CleanupInterval: time.Second,
+		EvictionPolicy:  PolicySizeAware,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	// Interleave small entries that are read often with large cold entries,
+	// so by recency alone the two kinds are evenly mixed and LRU would evict
+	// the oldest small entry first
+	large := make([]byte, 2048)
+	small := make([]byte, 64)
+	const pairs = 5
+	for i := 0; i < pairs; i++ {
+		key := fmt.Sprintf("small_%d", i)
+		_ = cache.Set(key, small)
+		for j := 0; j < 3; j++ {
+			cache.Get(key)
+		}
+		_ = cache.Set(fmt.Sprintf("large_%d", i), large)
+	}
+
+	// Put the cache under pressure with entries that are younger than both
+	for i := 0; i < 60; i++ {
+		_ = cache.Set(fmt.Sprintf("filler_%d", i), small)
+	}
+	cache.evictIfNeeded()
+
+	for i := 0; i < pairs; i++ {
+		if _, ok := cache.Get(fmt.Sprintf("small_%d", i)); !ok {
+			t.Errorf("Expected small hot entry small_%d to survive", i)
+		}
+	}
+	evicted := 0
+	for i := 0; i < pairs; i++ {
+		if _, ok := cache.Get(fmt.Sprintf("large_%d", i)); !ok {
+			evicted++
+		}
+	}
+	if evicted == 0 {
+		t.Error("Expected large cold entries to be evicted first")
+	}
+}
+
+func TestFIFOIgnoresReads(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 1
+	config.EvictionPolicy = PolicyFIFO
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 5; i++ {
+		_ = cache.Set(fmt.Sprintf("fifo_%d", i), i)
+	}
+
+	// Reads and updates of the oldest entries do not protect them
+	for i := 0; i < 3; i++ {
+		cache.Get("fifo_0")
+		cache.Get("fifo_1")
+	}
+	_ = cache.Set("fifo_0", 100)
+
+	shard := cache.allShards()[0]
+	for i := 0; i < 5; i++ {
+		if evicted := cache.evictFromShard(shard, 1); evicted != 1 {
+			t.Fatalf("Expected one eviction, got %d", evicted)
+		}
+		if cache.Has(fmt.Sprintf("fifo_%d", i)) {
+			t.Fatalf("Expected fifo_%d to be evicted in insertion order", i)
+		}
+	}
+}
+
+// skewedHitRatio replays a Zipf-distributed read-through workload, writing
+// each missed key, and returns the hit ratio
+func skewedHitRatio(t *testing.T, samples int) float64 {
+	config := &Config{
+		MaxMemoryBytes:  32 * 1024, // 32KB, about 150 entries
+		ShardCount:      16,
+		DefaultTTL:      0,
+		CleanupInterval: time.Minute,
+		HashSeed:        1,
+		EvictionSamples: samples,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 2000)
+	value := make([]byte, 150)
+	for i := 0; i < 50000; i++ {
+		key := fmt.Sprintf("skewed_%d", zipf.Uint64())
+		if _, found := cache.Get(key); !found {
+			_ = cache.Set(key, value)
+		}
+	}
+
+	stats := cache.GetStats()
+	t.Logf("EvictionSamples=%d: hit ratio %.3f, %d evictions", samples, stats.HitRatio, stats.Evictions)
+	return stats.HitRatio
+}
+
+func TestSampledEvictionHitRatio(t *testing.T) {
+	perShard := skewedHitRatio(t, 0)
+	sampled := skewedHitRatio(t, 5)
+	if sampled <= perShard {
+		t.Errorf("Expected sampled global eviction to beat per-shard eviction, got %.3f vs %.3f", sampled, perShard)
+	}
+}
+
+func TestGDSFHitRatio(t *testing.T) {
+	gdsf := mixedSizeHitRatio(t, PolicyGDSF)
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyTinyLFU} {
+		if other := mixedSizeHitRatio(t, policy); gdsf <= other {
+			t.Errorf("Expected GDSF to beat %s on mixed sizes, got %.3f vs %.3f", policy, gdsf, other)
+		}
+	}
+	if lfu := mixedSizeLFUHitRatio(t); gdsf <= lfu {
+		t.Errorf("Expected GDSF to beat plain LFU on mixed sizes, got %.3f vs %.3f", gdsf, lfu)
+	}
+}
+
+func TestGDSFPriorityStats(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 1
+	config.EvictionPolicy = PolicyGDSF
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("small", "x")
+	_ = cache.Set("large", string(make([]byte, 4096)))
+	_ = cache.Set("pinned", "x")
+	cache.Pin("pinned")
+
+	small, _ := cache.EntryInfo("small")
+	large, _ := cache.EntryInfo("large")
+	if small.Priority <= large.Priority || large.Priority <= 0 {
+		t.Errorf("Expected a small entry to outrank a large one, got %v vs %v", small.Priority, large.Priority)
+	}
+	if pinned, _ := cache.EntryInfo("pinned"); pinned.Priority != 0 {
+		t.Errorf("Expected no priority for a pinned entry, got %v", pinned.Priority)
+	}
+
+	cache.Get("large")
+	if read, _ := cache.EntryInfo("large"); read.Priority <= large.Priority {
+		t.Errorf("Expected a read to raise priority, got %v then %v", large.Priority, read.Priority)
+	}
+
+	stats := cache.GetShardStats()[0]
+	small, _ = cache.EntryInfo("small")
+	large, _ = cache.EntryInfo("large")
+	if mean := (small.Priority + large.Priority) / 2; math.Abs(stats.MeanPriority-mean) > 1e-12 {
+		t.Errorf("Expected mean priority %v, got %v", mean, stats.MeanPriority)
+	}
+
+	// Other policies report no priorities
+	lru := New(DefaultConfig())
+	defer lru.Close()
+	_ = lru.Set("key", "value")
+	if info, _ := lru.EntryInfo("key"); info.Priority != 0 || lru.GetShardStats()[0].MeanPriority != 0 {
+		t.Errorf("Expected no priorities under LRU, got %v", info.Priority)
+	}
+}
+
+const (
+	mixedSizeKeys   = 2000
+	mixedSizeReads  = 50000
+	mixedSizeMemory = 1024 * 1024 // 1MB
+)
+
+// mixedSizeWorkload returns a Zipf-distributed sequence of key ids and a
+// value for each id, with sizes from 64 bytes to 16KB independent of
+// popularity
+func mixedSizeWorkload() ([]uint64, [][]byte) {
+	sizes := rand.New(rand.NewSource(2))
+	values := make([][]byte, mixedSizeKeys)
+	for i := range values {
+		values[i] = make([]byte, 64<<sizes.Intn(9))
+	}
+
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, mixedSizeKeys-1)
+	ids := make([]uint64, mixedSizeReads)
+	for i := range ids {
+		ids[i] = zipf.Uint64()
+	}
+	return ids, values
+}
+
+// mixedSizeHitRatio replays mixedSizeWorkload as a read-through workload,
+// writing each missed key, and returns the hit ratio
+func mixedSizeHitRatio(t *testing.T, policy EvictionPolicy) float64 {
+	config := &Config{
+		MaxMemoryBytes:  mixedSizeMemory,
+		ShardCount:      4,
+		DefaultTTL:      0,
+		CleanupInterval: time.Minute,
+		HashSeed:        1,
+		EvictionPolicy:  policy,
+	}
+
+	cache := New(config)
+	defer cache.Close()
+
+	ids, values := mixedSizeWorkload()
+	for _, id := range ids {
+		key := fmt.Sprintf("mixed_%d", id)
+		if _, found := cache.Get(key); !found {
+			_ = cache.Set(key, values[id])
+		}
+	}
+
+	if errs := cache.Verify(); len(errs) > 0 {
+		t.Fatalf("%s: inconsistent cache: %v", policy, errs)
+	}
+	stats := cache.GetStats()
+	t.Logf("%s: hit ratio %.3f, %d evictions", policy, stats.HitRatio, stats.Evictions)
+	return stats.HitRatio
+}
+
+// mixedSizeLFUHitRatio replays mixedSizeWorkload against a simulated plain
+// LFU cache with the same memory limit and entry sizes, which evicts the
+// entry read least often since insertion, least recently read first on ties
+func mixedSizeLFUHitRatio(t *testing.T) float64 {
+	type lfuEntry struct {
+		size      int64
+		frequency int
+		lastRead  int
+	}
+	resident := make(map[uint64]*lfuEntry)
+	var used int64
+	hits := 0
+
+	ids, values := mixedSizeWorkload()
+	for i, id := range ids {
+		if entry, ok := resident[id]; ok {
+			entry.frequency++
+			entry.lastRead = i
+			hits++
+			continue
+		}
+
+		size := calculateSize(fmt.Sprintf("mixed_%d", id), values[id])
+		for used+size > mixedSizeMemory && len(resident) > 0 {
+			var victim uint64
+			var coldest *lfuEntry
+			for rid, entry := range resident {
+				if coldest == nil || entry.frequency < coldest.frequency ||
+					(entry.frequency == coldest.frequency && entry.lastRead < coldest.lastRead) {
+					victim, coldest = rid, entry
+				}
+			}
+			delete(resident, victim)
+			used -= coldest.size
+		}
+		resident[id] = &lfuEntry{size: size, lastRead: i}
+		used += size
+	}
+
+	hitRatio := float64(hits) / float64(len(ids))
+	t.Logf("lfu: hit ratio %.3f", hitRatio)
+	return hitRatio
+}
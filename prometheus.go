@@ -0,0 +1,57 @@
+package fastcache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExportPrometheus returns the cache's current statistics in the Prometheus
+// text exposition format, ready to serve from a /metrics endpoint. Per-shard
+// entry counts and sizes are exported as series labeled by shard.
+func (c *Cache) ExportPrometheus() string {
+	stats := c.GetStats()
+
+	var b strings.Builder
+	writeMetricHeader(&b, "fastcache_entries", "gauge", "Number of cache entries, including expired entries not yet removed")
+	fmt.Fprintf(&b, "fastcache_entries %d\n", stats.TotalEntries)
+
+	writeMetricHeader(&b, "fastcache_memory_bytes", "gauge", "Estimated memory used by entries in bytes")
+	fmt.Fprintf(&b, "fastcache_memory_bytes %d\n", stats.TotalSize)
+
+	writeMetricHeader(&b, "fastcache_max_memory_bytes", "gauge", "Configured memory limit in bytes")
+	fmt.Fprintf(&b, "fastcache_max_memory_bytes %d\n", stats.MaxMemory)
+
+	writeMetricHeader(&b, "fastcache_hit_ratio", "gauge", "Fraction of lookups that were hits")
+	fmt.Fprintf(&b, "fastcache_hit_ratio %g\n", stats.HitRatio)
+
+	writeMetricHeader(&b, "fastcache_hits_total", "counter", "Total cache hits")
+	fmt.Fprintf(&b, "fastcache_hits_total %d\n", stats.HitCount)
+
+	writeMetricHeader(&b, "fastcache_misses_total", "counter", "Total cache misses")
+	fmt.Fprintf(&b, "fastcache_misses_total %d\n", stats.MissCount)
+
+	writeMetricHeader(&b, "fastcache_evictions_total", "counter", "Total entries evicted under memory pressure")
+	fmt.Fprintf(&b, "fastcache_evictions_total %d\n", stats.Evictions)
+
+	writeMetricHeader(&b, "fastcache_expirations_total", "counter", "Total expired entries removed")
+	fmt.Fprintf(&b, "fastcache_expirations_total %d\n", stats.Expirations)
+
+	shards := c.GetShardStats()
+	writeMetricHeader(&b, "fastcache_shard_entries", "gauge", "Number of entries per shard")
+	for _, shard := range shards {
+		fmt.Fprintf(&b, "fastcache_shard_entries{shard=\"%d\"} %d\n", shard.ShardID, shard.EntryCount)
+	}
+
+	writeMetricHeader(&b, "fastcache_shard_memory_bytes", "gauge", "Estimated memory used per shard in bytes")
+	for _, shard := range shards {
+		fmt.Fprintf(&b, "fastcache_shard_memory_bytes{shard=\"%d\"} %d\n", shard.ShardID, shard.Size)
+	}
+
+	return b.String()
+}
+
+// writeMetricHeader writes the HELP and TYPE comments for a metric
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
@@ -0,0 +1,87 @@
+package fastcache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportPrometheus(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 4
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("a", "1")
+	_ = cache.Set("b", "2")
+	cache.Get("a")
+	cache.Get("missing")
+
+	output := cache.ExportPrometheus()
+	if !strings.HasSuffix(output, "\n") {
+		t.Error("Expected output to end with a newline")
+	}
+
+	types := make(map[string]string)
+	helps := make(map[string]bool)
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			if len(fields) < 4 {
+				t.Errorf("Malformed HELP line: %q", line)
+				continue
+			}
+			helps[fields[2]] = true
+		case strings.HasPrefix(line, "# TYPE "):
+			if len(fields) != 4 {
+				t.Errorf("Malformed TYPE line: %q", line)
+				continue
+			}
+			types[fields[2]] = fields[3]
+		default:
+			if len(fields) != 2 {
+				t.Errorf("Malformed sample line: %q", line)
+				continue
+			}
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				t.Errorf("Unparseable value in %q: %v", line, err)
+			}
+			name := fields[0]
+			if i := strings.IndexByte(name, '{'); i >= 0 {
+				name = name[:i]
+			}
+			if !helps[name] || types[name] == "" {
+				t.Errorf("Sample %q precedes its HELP and TYPE comments", line)
+			}
+			samples[fields[0]] = value
+		}
+	}
+
+	expected := map[string]float64{
+		"fastcache_entries":      2,
+		"fastcache_hits_total":   1,
+		"fastcache_misses_total": 1,
+		"fastcache_hit_ratio":    0.5,
+	}
+	for name, value := range expected {
+		if got, ok := samples[name]; !ok || got != value {
+			t.Errorf("Expected %s %v, got %v (present %v)", name, value, got, ok)
+		}
+	}
+	for _, name := range []string{"fastcache_memory_bytes", "fastcache_evictions_total", "fastcache_expirations_total"} {
+		if _, ok := samples[name]; !ok {
+			t.Errorf("Expected %s in output", name)
+		}
+	}
+	if types["fastcache_hits_total"] != "counter" || types["fastcache_entries"] != "gauge" {
+		t.Errorf("Unexpected metric types: %v", types)
+	}
+	for i := 0; i < config.ShardCount; i++ {
+		if _, ok := samples["fastcache_shard_entries{shard=\""+strconv.Itoa(i)+"\"}"]; !ok {
+			t.Errorf("Expected shard_entries series for shard %d", i)
+		}
+	}
+}
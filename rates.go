@@ -0,0 +1,226 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// rateSampleInterval is how often hit/miss counters are sampled for RateStats
+	rateSampleInterval = 250 * time.Millisecond
+
+	// rateWindow is the longest window reported by RateStats
+	rateWindow = time.Minute
+)
+
+// RateStats represents operation rates over recent time windows
+type RateStats struct {
+	QPSLastSecond     float64 `json:"qps_last_second"`
+	QPS5s             float64 `json:"qps_5s"`
+	QPS1m             float64 `json:"qps_1m"`
+	HitRateLastMinute float64 `json:"hit_rate_last_minute"`
+}
+
+// rateSample is a snapshot of the cumulative hit/miss and eviction counters
+type rateSample struct {
+	at        int64 // Unix timestamp in nanoseconds
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// rateRing is a fixed-size ring buffer of counter samples
+type rateRing struct {
+	mu      sync.Mutex
+	samples []rateSample
+	next    int
+	count   int
+}
+
+// newRateRing creates a ring large enough to cover rateWindow
+func newRateRing() *rateRing {
+	return &rateRing{
+		samples: make([]rateSample, int(rateWindow/rateSampleInterval)+1),
+	}
+}
+
+// add records a sample, overwriting the oldest when full
+func (r *rateRing) add(s rateSample) {
+	r.mu.Lock()
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.count < len(r.samples) {
+		r.count++
+	}
+	r.mu.Unlock()
+}
+
+// reset discards all samples
+func (r *rateRing) reset() {
+	r.mu.Lock()
+	r.next = 0
+	r.count = 0
+	r.mu.Unlock()
+}
+
+// baseline returns the newest sample taken at least window before now, or the
+// oldest sample available if none is that old
+func (r *rateRing) baseline(now int64, window time.Duration) (rateSample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count == 0 {
+		return rateSample{}, false
+	}
+
+	cutoff := now - int64(window)
+	for i := 1; i <= r.count; i++ {
+		s := r.samples[(r.next-i+len(r.samples))%len(r.samples)]
+		if s.at <= cutoff {
+			return s, true
+		}
+	}
+	return r.samples[(r.next-r.count+len(r.samples))%len(r.samples)], true
+}
+
+// rateSampleRoutine periodically samples hit/miss counters
+func (c *Cache) rateSampleRoutine() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(rateSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.sampleRates()
+		}
+	}
+}
+
+// sampleRates records the current counters in the rate ring
+func (c *Cache) sampleRates() {
+	sample := rateSample{at: time.Now().UnixNano(), evictions: atomic.LoadInt64(&c.evictions)}
+	sample.hits, sample.misses = c.hitsAndMisses()
+	c.rates.add(sample)
+}
+
+// GetRateStats returns operation rates over recent windows. Rates are zero
+// unless Config.EnableRateStats is set.
+func (c *Cache) GetRateStats() RateStats {
+	if c.rates == nil {
+		return RateStats{}
+	}
+
+	current := rateSample{at: time.Now().UnixNano()}
+	current.hits, current.misses = c.hitsAndMisses()
+
+	qps := func(window time.Duration) (float64, int64, int64) {
+		base, ok := c.rates.baseline(current.at, window)
+		if !ok || current.at <= base.at {
+			return 0, 0, 0
+		}
+		hits := current.hits - base.hits
+		misses := current.misses - base.misses
+		if hits < 0 || misses < 0 {
+			// Counters were reset since the baseline sample
+			return 0, 0, 0
+		}
+		elapsed := time.Duration(current.at - base.at).Seconds()
+		return float64(hits+misses) / elapsed, hits, misses
+	}
+
+	var stats RateStats
+	stats.QPSLastSecond, _, _ = qps(time.Second)
+	stats.QPS5s, _, _ = qps(5 * time.Second)
+
+	var hits, misses int64
+	stats.QPS1m, hits, misses = qps(time.Minute)
+	if total := hits + misses; total > 0 {
+		stats.HitRateLastMinute = float64(hits) / float64(total)
+	}
+
+	return stats
+}
+
+// healthRateWindow is the window GetHealthStats measures eviction rate over
+const healthRateWindow = 5 * time.Second
+
+// Pressure is a coarse memory pressure level reported by GetHealthStats
+type Pressure int
+
+const (
+	// PressureLow means memory is below MemoryPressureThresholdPercent and
+	// nothing was evicted recently
+	PressureLow Pressure = iota
+
+	// PressureMedium means memory is at or above
+	// MemoryPressureThresholdPercent, but nothing was evicted recently
+	PressureMedium
+
+	// PressureHigh means entries were evicted recently, or memory is over
+	// MaxMemoryBytes, so the cache is too small for its working set
+	PressureHigh
+)
+
+// String returns the name of the pressure level
+func (p Pressure) String() string {
+	switch p {
+	case PressureLow:
+		return "low"
+	case PressureMedium:
+		return "medium"
+	case PressureHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthStats holds derived signals for autoscaling decisions
+type HealthStats struct {
+	MemoryPercent       float64  `json:"memory_percent"`
+	MemoryHeadroomBytes int64    `json:"memory_headroom_bytes"`
+	EvictionRatePerSec  float64  `json:"eviction_rate_per_sec"`
+	Pressure            Pressure `json:"pressure"`
+}
+
+// GetHealthStats returns memory headroom, the eviction rate over the last
+// few seconds and the resulting pressure level. The eviction rate, and so
+// PressureHigh from evictions, needs Config.EnableRateStats; without it the
+// rate is zero and pressure reflects memory use alone.
+func (c *Cache) GetHealthStats() *HealthStats {
+	used := atomic.LoadInt64(&c.totalSize)
+	limit := c.config.MaxMemoryBytes
+	headroom := limit - used
+	if headroom < 0 {
+		headroom = 0
+	}
+
+	var rate float64
+	if c.rates != nil {
+		now := time.Now().UnixNano()
+		evictions := atomic.LoadInt64(&c.evictions)
+		if base, ok := c.rates.baseline(now, healthRateWindow); ok && now > base.at && evictions >= base.evictions {
+			rate = float64(evictions-base.evictions) / time.Duration(now-base.at).Seconds()
+		}
+	}
+
+	pressure := PressureLow
+	switch {
+	case rate > 0 || used > limit:
+		pressure = PressureHigh
+	case used*100 >= limit*int64(c.pressureThreshold()):
+		pressure = PressureMedium
+	}
+
+	return &HealthStats{
+		MemoryPercent:       safeRatio(float64(used), float64(limit)) * 100,
+		MemoryHeadroomBytes: headroom,
+		EvictionRatePerSec:  rate,
+		Pressure:            pressure,
+	}
+}
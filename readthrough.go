@@ -0,0 +1,294 @@
+package fastcache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flightCall is an in-flight or completed call in a flightGroup
+type flightCall struct {
+	wg    sync.WaitGroup
+	value interface{}
+	found bool
+	err   error
+}
+
+// flightGroup deduplicates concurrent calls for the same key so that only one
+// runs at a time and its result is shared with every waiting caller
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn once for key among concurrent callers and returns its result. If
+// fn panics, waiting callers get ErrLoaderPanicked and the panic is re-raised
+// in the caller that ran fn.
+func (g *flightGroup) do(key string, fn func() (interface{}, bool, error)) (interface{}, bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.found, call.err
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.value, call.found = nil, false
+			call.err = fmt.Errorf("%w: %v", ErrLoaderPanicked, r)
+			g.finish(key, call)
+			panic(r)
+		}
+	}()
+	call.value, call.found, call.err = fn()
+	g.finish(key, call)
+
+	return call.value, call.found, call.err
+}
+
+// finish releases the callers waiting on call and forgets it, so the next call
+// for key runs fn again
+func (g *flightGroup) finish(key string, call *flightCall) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	call.wg.Done()
+}
+
+const (
+	// defaultLoaderOpenDuration is used when LoaderOpenDuration is zero
+	defaultLoaderOpenDuration = 5 * time.Second
+
+	// defaultLoaderFailureWindow is used when LoaderFailureWindow is zero
+	defaultLoaderFailureWindow = time.Minute
+)
+
+// loaderBreaker is a circuit breaker around GetOrCompute's compute function.
+// It opens after a run of consecutive failures, each within the failure
+// window of the previous one, then lets a single probe through once the open
+// duration has elapsed.
+type loaderBreaker struct {
+	mu          sync.Mutex
+	failures    int
+	lastFailure time.Time
+	openUntil   time.Time
+	probing     bool
+}
+
+// allow reports whether a compute call may proceed, and whether it is the
+// probe of an open circuit, which the caller must end with endProbe once the
+// call returns or panics
+func (b *loaderBreaker) allow(threshold int) (ok, probe bool) {
+	if threshold <= 0 {
+		return true, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < threshold {
+		return true, false
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// endProbe lets the next probe through once the circuit is due for one
+func (b *loaderBreaker) endProbe() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record updates the breaker with the outcome of a compute call and reports
+// whether the failure opened the circuit
+func (b *loaderBreaker) record(threshold int, window, openDuration time.Duration, err error) bool {
+	if threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return false
+	}
+
+	// A failure long after the previous one starts a new run, unless it is a
+	// failed probe of an open circuit
+	now := time.Now()
+	if window <= 0 {
+		window = defaultLoaderFailureWindow
+	}
+	if b.failures < threshold && now.Sub(b.lastFailure) > window {
+		b.failures = 0
+	}
+	b.lastFailure = now
+
+	b.failures++
+	if b.failures < threshold {
+		return false
+	}
+	if openDuration <= 0 {
+		openDuration = defaultLoaderOpenDuration
+	}
+	b.openUntil = now.Add(openDuration)
+	return true
+}
+
+// reset closes the breaker
+func (b *loaderBreaker) reset() {
+	b.mu.Lock()
+	b.failures = 0
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// isNegative reports whether key has a live negative-cache entry
+func (c *Cache) isNegative(key string) bool {
+	expiry, ok := c.negatives.Load(key)
+	if !ok {
+		return false
+	}
+	if c.now() > expiry.(int64) {
+		c.negatives.Delete(key)
+		return false
+	}
+	return true
+}
+
+// pruneNegatives removes expired negative-cache entries
+func (c *Cache) pruneNegatives() {
+	now := c.now()
+	c.negatives.Range(func(key, expiry interface{}) bool {
+		if now > expiry.(int64) {
+			c.negatives.Delete(key)
+		}
+		return true
+	})
+}
+
+// GetOrCompute returns the cached value for key, or calls compute to produce it
+// on a miss. compute returns the value, its TTL, whether it was found, and an
+// error. Concurrent callers for the same key share a single compute call.
+// A not-found result is negative-cached for Config.NegativeTTL so repeated
+// lookups of missing keys do not recompute; errors are never cached. With
+// LoaderFailureThreshold set, repeated compute errors open a circuit breaker
+// and misses fail with ErrLoaderUnavailable until it recovers.
+func (c *Cache) GetOrCompute(key string, compute func() (interface{}, time.Duration, bool, error)) (interface{}, bool, error) {
+	if value, ok := c.Get(key); ok {
+		return value, true, nil
+	}
+	if c.isClosed() {
+		return nil, false, opError("get_or_compute", key, ErrCacheClosed)
+	}
+	if c.isNegative(key) {
+		return nil, false, nil
+	}
+
+	return c.flights.do(key, func() (interface{}, bool, error) {
+		threshold := c.config.LoaderFailureThreshold
+		ok, probe := c.breaker.allow(threshold)
+		if !ok {
+			return nil, false, opError("get_or_compute", key, ErrLoaderUnavailable)
+		}
+		if probe {
+			// Deferred so a panicking probe does not keep the circuit open
+			defer c.breaker.endProbe()
+		}
+
+		value, ttl, found, err := compute()
+		if c.breaker.record(threshold, c.config.LoaderFailureWindow, c.config.LoaderOpenDuration, err) {
+			c.logs.log(logLoaderCircuitOpen, LogLevelError, "loader circuit opened",
+				"key", key, "error", err)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !found {
+			if c.config.NegativeTTL > 0 {
+				c.negatives.Store(key, c.now()+int64(c.config.NegativeTTL))
+			}
+			return nil, false, nil
+		}
+
+		if err := c.Set(key, value, ttl); err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	})
+}
+
+// retainsStale reports whether an entry that expired at expiry is still kept
+// for GetStale at now
+func (c *Cache) retainsStale(expiry, now int64) bool {
+	return c.config.StaleWindow > 0 && now-expiry <= int64(c.config.StaleWindow)
+}
+
+// GetStale is GetOrCompute with stale-while-revalidate: when key has expired
+// but is still within Config.StaleWindow, its old value is returned at once
+// with stale set, and a single background call to compute replaces it. A
+// background compute that reports the key as not found removes the stale
+// entry; one that fails leaves it in place. Without StaleWindow, or once the
+// window has passed, GetStale behaves like GetOrCompute.
+func (c *Cache) GetStale(key string, compute func() (interface{}, time.Duration, bool, error)) (value interface{}, found, stale bool, err error) {
+	if value, ok := c.Get(key); ok {
+		return value, true, false, nil
+	}
+
+	if shard, entry, value, ok := c.peekStale(key); ok {
+		if _, running := c.refreshes.LoadOrStore(key, struct{}{}); !running {
+			c.spawn(func() { c.refreshStale(shard, entry, compute) })
+		}
+		return value, true, true, nil
+	}
+
+	value, found, err = c.GetOrCompute(key, compute)
+	return value, found, false, err
+}
+
+// peekStale returns the value of an expired entry retained for GetStale
+func (c *Cache) peekStale(key string) (*Shard, *Entry, interface{}, bool) {
+	if c.config.StaleWindow <= 0 || c.isClosed() {
+		return nil, nil, nil, false
+	}
+
+	shard := c.rlockShard(key)
+	entry, exists := shard.data[key]
+	var p payload
+	var expiry int64
+	if exists {
+		p, expiry = entry.payload, entry.expiry
+	}
+	shard.mu.RUnlock()
+
+	now := c.now()
+	if !exists || expiry == 0 || now <= expiry || !c.retainsStale(expiry, now) {
+		return nil, nil, nil, false
+	}
+	value, ok := c.loadValue(p.get())
+	return shard, entry, value, ok
+}
+
+// refreshStale recomputes a stale entry in the background
+func (c *Cache) refreshStale(shard *Shard, entry *Entry, compute func() (interface{}, time.Duration, bool, error)) {
+	defer c.refreshes.Delete(entry.key)
+
+	_, found, err := c.GetOrCompute(entry.key, compute)
+	if err == nil && !found {
+		c.expireEntry(shard, entry)
+	}
+}
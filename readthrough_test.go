@@ -0,0 +1,419 @@
+package fastcache
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runConcurrently calls fn from n goroutines started at the same time
+func runConcurrently(n int, fn func()) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			fn()
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+func TestGetOrComputeFound(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	var calls int64
+	compute := func() (interface{}, time.Duration, bool, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "computed", time.Minute, true, nil
+	}
+
+	runConcurrently(50, func() {
+		value, found, err := cache.GetOrCompute("key", compute)
+		if err != nil || !found || value != "computed" {
+			t.Errorf("Expected computed value, got %v, %v, %v", value, found, err)
+		}
+	})
+
+	if calls != 1 {
+		t.Errorf("Expected compute to run once, ran %d times", calls)
+	}
+
+	// Subsequent calls are served from the cache
+	_, _, _ = cache.GetOrCompute("key", compute)
+	if calls != 1 {
+		t.Errorf("Expected cached value to be reused, compute ran %d times", calls)
+	}
+}
+
+func TestGetOrComputeNotFoundCached(t *testing.T) {
+	config := DefaultConfig()
+	config.NegativeTTL = 50 * time.Millisecond
+	cache := New(config)
+	defer cache.Close()
+
+	var calls int64
+	compute := func() (interface{}, time.Duration, bool, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil, 0, false, nil
+	}
+
+	runConcurrently(50, func() {
+		value, found, err := cache.GetOrCompute("missing", compute)
+		if err != nil || found || value != nil {
+			t.Errorf("Expected not found, got %v, %v, %v", value, found, err)
+		}
+	})
+
+	// The absence is negative-cached
+	for i := 0; i < 10; i++ {
+		_, _, _ = cache.GetOrCompute("missing", compute)
+	}
+	if calls != 1 {
+		t.Errorf("Expected compute to run once, ran %d times", calls)
+	}
+
+	// After the negative TTL the key is recomputed
+	time.Sleep(60 * time.Millisecond)
+	_, _, _ = cache.GetOrCompute("missing", compute)
+	if calls != 2 {
+		t.Errorf("Expected recompute after negative TTL, ran %d times", calls)
+	}
+}
+
+func TestGetOrComputeErrorNotCached(t *testing.T) {
+	config := DefaultConfig()
+	config.NegativeTTL = time.Minute
+	cache := New(config)
+	defer cache.Close()
+
+	errBackend := errors.New("backend down")
+	var calls int64
+	compute := func() (interface{}, time.Duration, bool, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil, 0, false, errBackend
+	}
+
+	runConcurrently(50, func() {
+		if _, _, err := cache.GetOrCompute("flaky", compute); err != errBackend {
+			t.Errorf("Expected backend error, got %v", err)
+		}
+	})
+	if calls != 1 {
+		t.Errorf("Expected concurrent callers to share one compute, ran %d times", calls)
+	}
+
+	// Errors are not cached, so the next call recomputes
+	_, _, _ = cache.GetOrCompute("flaky", compute)
+	if calls != 2 {
+		t.Errorf("Expected recompute after error, ran %d times", calls)
+	}
+}
+
+func TestGetOrComputePanic(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	leaderDone := make(chan interface{})
+	go func() {
+		defer func() { leaderDone <- recover() }()
+		_, _, _ = cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+			close(started)
+			<-release
+			panic("backend exploded")
+		})
+	}()
+	<-started
+
+	waiterErr := make(chan error)
+	go func() {
+		_, _, err := cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+			return "unexpected", 0, true, nil
+		})
+		waiterErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // let the waiter join the in-flight call
+	close(release)
+
+	if r := <-leaderDone; r != "backend exploded" {
+		t.Errorf("Expected the panic to reach the loading caller, got %v", r)
+	}
+	if err := <-waiterErr; !errors.Is(err, ErrLoaderPanicked) {
+		t.Errorf("Expected ErrLoaderPanicked for the waiting caller, got %v", err)
+	}
+
+	// The key is not stuck: the next call runs its own compute
+	value, found, err := cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+		return "recovered", 0, true, nil
+	})
+	if err != nil || !found || value != "recovered" {
+		t.Errorf("Expected a fresh compute after the panic, got %v, %v, %v", value, found, err)
+	}
+}
+
+func TestGetOrComputeCircuitBreaker(t *testing.T) {
+	config := DefaultConfig()
+	config.LoaderFailureThreshold = 3
+	config.LoaderOpenDuration = 50 * time.Millisecond
+	cache := New(config)
+	defer cache.Close()
+
+	var calls int64
+	var healthy int32
+	loaderErr := errors.New("backend down")
+	compute := func() (interface{}, time.Duration, bool, error) {
+		atomic.AddInt64(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 1 {
+			return "value", time.Minute, true, nil
+		}
+		return nil, 0, false, loaderErr
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := cache.GetOrCompute("key", compute); !errors.Is(err, loaderErr) {
+			t.Fatalf("Expected loader error, got %v", err)
+		}
+	}
+
+	// The circuit is open: misses fail fast without calling the loader
+	for i := 0; i < 10; i++ {
+		_, _, err := cache.GetOrCompute("key", compute)
+		if !errors.Is(err, ErrLoaderUnavailable) {
+			t.Fatalf("Expected ErrLoaderUnavailable, got %v", err)
+		}
+		if !IsTemporaryError(err) {
+			t.Error("Expected ErrLoaderUnavailable to be temporary")
+		}
+	}
+	if n := atomic.LoadInt64(&calls); n != 3 {
+		t.Errorf("Expected loader calls to be suppressed, got %d calls", n)
+	}
+
+	// After the cooldown a single probe goes through and fails, reopening it
+	time.Sleep(60 * time.Millisecond)
+	if _, _, err := cache.GetOrCompute("key", compute); !errors.Is(err, loaderErr) {
+		t.Fatalf("Expected probe to reach the loader, got %v", err)
+	}
+	if _, _, err := cache.GetOrCompute("key", compute); !errors.Is(err, ErrLoaderUnavailable) {
+		t.Fatalf("Expected circuit to reopen after failed probe, got %v", err)
+	}
+	if n := atomic.LoadInt64(&calls); n != 4 {
+		t.Errorf("Expected 4 loader calls, got %d", n)
+	}
+
+	// A successful probe closes the circuit
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+	value, found, err := cache.GetOrCompute("key", compute)
+	if err != nil || !found || value != "value" {
+		t.Fatalf("Expected probe to succeed, got %v, %v, %v", value, found, err)
+	}
+	if _, _, err := cache.GetOrCompute("other", compute); err != nil {
+		t.Errorf("Expected closed circuit, got %v", err)
+	}
+}
+
+func TestGetOrComputeCircuitBreakerWindow(t *testing.T) {
+	config := DefaultConfig()
+	config.LoaderFailureThreshold = 2
+	config.LoaderFailureWindow = 20 * time.Millisecond
+	cache := New(config)
+	defer cache.Close()
+
+	var calls int64
+	loaderErr := errors.New("backend down")
+	compute := func() (interface{}, time.Duration, bool, error) {
+		atomic.AddInt64(&calls, 1)
+		return nil, 0, false, loaderErr
+	}
+
+	// Failures further apart than the window never add up to the threshold
+	for i := 0; i < 3; i++ {
+		if _, _, err := cache.GetOrCompute("key", compute); !errors.Is(err, loaderErr) {
+			t.Fatalf("Expected loader error for spread out failure %d, got %v", i, err)
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+
+	// Failures in quick succession still open the circuit
+	_, _, _ = cache.GetOrCompute("key", compute)
+	_, _, _ = cache.GetOrCompute("key", compute)
+	if _, _, err := cache.GetOrCompute("key", compute); !errors.Is(err, ErrLoaderUnavailable) {
+		t.Errorf("Expected ErrLoaderUnavailable, got %v", err)
+	}
+	if n := atomic.LoadInt64(&calls); n != 5 {
+		t.Errorf("Expected 5 loader calls, got %d", n)
+	}
+}
+
+func TestGetOrComputeCircuitBreakerProbePanic(t *testing.T) {
+	config := DefaultConfig()
+	config.LoaderFailureThreshold = 1
+	config.LoaderOpenDuration = 20 * time.Millisecond
+	cache := New(config)
+	defer cache.Close()
+
+	_, _, _ = cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+		return nil, 0, false, errors.New("backend down")
+	})
+
+	// A probe that panics must not leave the circuit waiting on it forever
+	time.Sleep(30 * time.Millisecond)
+	func() {
+		defer func() { _ = recover() }()
+		_, _, _ = cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+			panic("backend exploded")
+		})
+	}()
+
+	value, found, err := cache.GetOrCompute("key", func() (interface{}, time.Duration, bool, error) {
+		return "value", time.Minute, true, nil
+	})
+	if err != nil || !found || value != "value" {
+		t.Errorf("Expected the next probe to close the circuit, got %v, %v, %v", value, found, err)
+	}
+}
+
+func TestGetStale(t *testing.T) {
+	config := DefaultConfig()
+	config.StaleWindow = time.Minute
+	cache := New(config)
+	defer cache.Close()
+
+	var calls int64
+	release := make(chan struct{})
+	compute := func() (interface{}, time.Duration, bool, error) {
+		<-release
+		return fmt.Sprintf("fresh_%d", atomic.AddInt64(&calls, 1)), time.Minute, true, nil
+	}
+
+	_ = cache.Set("key", "old", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// Expired entries within the window are kept, but Get still misses
+	if _, ok := cache.Get("key"); ok {
+		t.Error("Expected Get to treat the stale entry as expired")
+	}
+	cache.DeleteExpired()
+
+	// Concurrent stale reads return at once and start a single refresh
+	runConcurrently(20, func() {
+		value, found, stale, err := cache.GetStale("key", compute)
+		if err != nil || !found || !stale || value != "old" {
+			t.Errorf("Expected stale old value, got %v, %v, %v, %v", value, found, stale, err)
+		}
+	})
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, found, stale, err := cache.GetStale("key", compute)
+		if err != nil || !found {
+			t.Fatalf("Expected a value, got %v, %v, %v", value, found, err)
+		}
+		if !stale {
+			if value != "fresh_1" {
+				t.Errorf("Expected refreshed value, got %v", value)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the background refresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Errorf("Expected a single refresh, got %d", n)
+	}
+}
+
+func TestGetStaleWithoutWindow(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.Set("key", "old", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	value, found, stale, err := cache.GetStale("key", func() (interface{}, time.Duration, bool, error) {
+		return "fresh", time.Minute, true, nil
+	})
+	if err != nil || !found || stale || value != "fresh" {
+		t.Errorf("Expected a blocking load without StaleWindow, got %v, %v, %v, %v", value, found, stale, err)
+	}
+}
+
+func TestMaxBackgroundGoroutines(t *testing.T) {
+	config := DefaultConfig()
+	config.StaleWindow = time.Minute
+	config.MaxBackgroundGoroutines = 4
+	config.DisableBackgroundCleanup = true
+	cache, clock := newWithFakeClock(config)
+	defer cache.Close()
+
+	const keys = 200
+	for i := 0; i < keys; i++ {
+		_ = cache.Set(fmt.Sprintf("stale_%d", i), i, time.Second)
+	}
+	clock.Advance(2 * time.Second)
+
+	base := runtime.NumGoroutine()
+	var running, peakRunning, peakGoroutines int64
+	compute := func() (interface{}, time.Duration, bool, error) {
+		raisePeak(&peakRunning, atomic.AddInt64(&running, 1))
+		defer atomic.AddInt64(&running, -1)
+		raisePeak(&peakGoroutines, int64(runtime.NumGoroutine()))
+		time.Sleep(2 * time.Millisecond) // slow loader
+		return "fresh", time.Minute, true, nil
+	}
+
+	// Every stale read wants a background refresh
+	for i := 0; i < keys; i++ {
+		if _, found, _, err := cache.GetStale(fmt.Sprintf("stale_%d", i), compute); err != nil || !found {
+			t.Fatalf("GetStale failed: found=%v err=%v", found, err)
+		}
+	}
+
+	// Wait for spawned refreshes to finish
+	deadline := time.Now().Add(5 * time.Second)
+	for len(cache.spawnSlots) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The cap plus the caller running a refresh itself once saturated
+	if peak := atomic.LoadInt64(&peakRunning); peak > int64(config.MaxBackgroundGoroutines)+1 {
+		t.Errorf("Expected at most %d concurrent refreshes, got %d", config.MaxBackgroundGoroutines+1, peak)
+	}
+	if peak := atomic.LoadInt64(&peakGoroutines); peak > int64(base+config.MaxBackgroundGoroutines) {
+		t.Errorf("Expected at most %d goroutines, got %d", base+config.MaxBackgroundGoroutines, peak)
+	}
+	t.Logf("peak %d goroutines from a base of %d", atomic.LoadInt64(&peakGoroutines), base)
+
+	for i := 0; i < keys; i++ {
+		if value, found := cache.Get(fmt.Sprintf("stale_%d", i)); !found || value != "fresh" {
+			t.Fatalf("Expected stale_%d to be refreshed, got %v (found=%v)", i, value, found)
+		}
+	}
+}
+
+// raisePeak stores n in peak if it is larger
+func raisePeak(peak *int64, n int64) {
+	for {
+		current := atomic.LoadInt64(peak)
+		if n <= current || atomic.CompareAndSwapInt64(peak, current, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheEventType identifies the kind of write a CacheEvent describes
+type CacheEventType int
+
+const (
+	// EventSet is a stored value
+	EventSet CacheEventType = iota
+	// EventDelete is a removed key
+	EventDelete
+	// EventExpire is a new expiry for a key whose value did not change, sent
+	// by SetIfChanged; Value is nil
+	EventExpire
+)
+
+// CacheEvent is a write forwarded to replicas registered with AddReplica
+type CacheEvent struct {
+	Type   CacheEventType
+	Key    string
+	Value  interface{} // the value as passed to the write; nil for EventDelete
+	Expiry time.Time   // absolute expiry; zero if the entry never expires
+}
+
+// replicaSet holds the apply functions registered with AddReplica. The slice
+// is replaced rather than modified, so writers read it without locking.
+// Events are queued under the written key's shard lock, so the queue holds
+// them in the order the writes were applied, and are delivered from the queue
+// by one writer at a time.
+type replicaSet struct {
+	mu        sync.Mutex
+	apply     atomic.Value // []func(CacheEvent)
+	queueMu   sync.Mutex
+	queue     []CacheEvent
+	deliverMu sync.Mutex // held by the writer delivering queued events
+}
+
+// AddReplica registers apply to receive every successful Set, SetReport,
+// SetExpireAt, SetBytes, SetIfChanged and Delete as a CacheEvent, e.g. to
+// forward writes to peer caches over a network transport. Other writes, such
+// as SetItems, SetBatch, SetWithTags, SetWithIndex, SetTransaction,
+// MultiUpdate and CompareAndSwap, and removals by expiry or eviction, are not
+// forwarded. A SetIfChanged that leaves the value unchanged still resets its
+// expiry, and is sent as an EventExpire.
+//
+// apply receives events one at a time in the order the writes were applied,
+// so replicas converge on the same value for each key. It runs on a writing
+// goroutine outside any cache lock, usually the one that made the write; while
+// another writer is delivering, a write may return before its event has been
+// passed to apply. apply should hand events off quickly rather than block.
+// Peers apply received events with ApplyRemote, which does not forward them
+// again, so writes never loop between caches.
+func (c *Cache) AddReplica(apply func(ev CacheEvent)) {
+	c.replicas.mu.Lock()
+	defer c.replicas.mu.Unlock()
+
+	current, _ := c.replicas.apply.Load().([]func(CacheEvent))
+	next := make([]func(CacheEvent), len(current), len(current)+1)
+	copy(next, current)
+	c.replicas.apply.Store(append(next, apply))
+}
+
+// replicate forwards ev to every registered replica
+func (c *Cache) replicate(ev CacheEvent) {
+	applies, _ := c.replicas.apply.Load().([]func(CacheEvent))
+	for _, apply := range applies {
+		apply(ev)
+	}
+}
+
+// replicating reports whether any replica is registered
+func (c *Cache) replicating() bool {
+	applies, _ := c.replicas.apply.Load().([]func(CacheEvent))
+	return len(applies) > 0
+}
+
+// queueSet queues a stored value with its absolute expiry for the replicas
+// and reports whether it was queued. The caller must hold the key's shard
+// write lock and, if it was queued, call deliverReplication after releasing it.
+func (c *Cache) queueSet(key string, value interface{}, expiry int64) bool {
+	if !c.replicating() {
+		return false
+	}
+	return c.queueEvent(newCacheEvent(EventSet, key, value, expiry))
+}
+
+// queueExpire queues a new expiry for an unchanged key, like queueSet
+func (c *Cache) queueExpire(key string, expiry int64) bool {
+	if !c.replicating() {
+		return false
+	}
+	return c.queueEvent(newCacheEvent(EventExpire, key, nil, expiry))
+}
+
+// newCacheEvent returns an event carrying expiry as an absolute time
+func newCacheEvent(typ CacheEventType, key string, value interface{}, expiry int64) CacheEvent {
+	ev := CacheEvent{Type: typ, Key: key, Value: value}
+	if expiry > 0 {
+		ev.Expiry = time.Unix(0, expiry)
+	}
+	return ev
+}
+
+// queueEvent queues ev for the replicas and reports whether it was queued.
+// The caller must hold the shard write lock of ev's key and, if it was
+// queued, call deliverReplication after releasing it.
+func (c *Cache) queueEvent(ev CacheEvent) bool {
+	if !c.replicating() {
+		return false
+	}
+	c.replicas.queueMu.Lock()
+	c.replicas.queue = append(c.replicas.queue, ev)
+	c.replicas.queueMu.Unlock()
+	return true
+}
+
+// deliverReplication passes queued events to the replicas in order, unless
+// another writer is already delivering them
+func (c *Cache) deliverReplication() {
+	r := &c.replicas
+	for r.deliverMu.TryLock() {
+		func() {
+			defer r.deliverMu.Unlock()
+			for {
+				r.queueMu.Lock()
+				events := r.queue
+				r.queue = nil
+				r.queueMu.Unlock()
+				if len(events) == 0 {
+					return
+				}
+				for _, ev := range events {
+					c.replicate(ev)
+				}
+			}
+		}()
+
+		// An event queued after the queue was found empty but before
+		// deliverMu was released was left to this writer
+		r.queueMu.Lock()
+		pending := len(r.queue) > 0
+		r.queueMu.Unlock()
+		if !pending {
+			return
+		}
+	}
+}
+
+// ApplyRemote applies an event received from a peer's replica hook. The write
+// is not forwarded to this cache's own replicas, which guards against
+// replication loops. A set or expiry that has already passed removes the key;
+// an EventExpire for a key this cache does not hold is ignored.
+func (c *Cache) ApplyRemote(ev CacheEvent) error {
+	switch ev.Type {
+	case EventSet:
+		var expiry int64
+		if !ev.Expiry.IsZero() {
+			if ev.Expiry.UnixNano() <= c.now() {
+				c.delete(ev.Key, false)
+				return nil
+			}
+			expiry = ev.Expiry.UnixNano()
+		}
+		_, err := c.setWith("apply_remote", ev.Key, ev.Value, c.clampExpiry(expiry), false, nil)
+		return err
+	case EventDelete:
+		if c.isClosed() {
+			return opError("apply_remote", ev.Key, ErrCacheClosed)
+		}
+		c.delete(ev.Key, false)
+		return nil
+	case EventExpire:
+		if c.isClosed() {
+			return opError("apply_remote", ev.Key, ErrCacheClosed)
+		}
+		if !ev.Expiry.IsZero() && ev.Expiry.UnixNano() <= c.now() {
+			c.delete(ev.Key, false)
+			return nil
+		}
+		c.applyExpiry(ev.Key, ev.Expiry)
+		return nil
+	default:
+		return opError("apply_remote", ev.Key, ErrInvalidEvent)
+	}
+}
+
+// applyExpiry sets the expiry of a live key received in an EventExpire. A key
+// that is missing here has no value to keep, so it is left missing.
+func (c *Cache) applyExpiry(key string, at time.Time) {
+	var expiry int64
+	if !at.IsZero() {
+		expiry = at.UnixNano()
+	}
+	expiry = c.clampExpiry(expiry)
+
+	shard := c.lockShard(key)
+	defer shard.mu.Unlock()
+	if entry, exists := shard.data[key]; exists && !entry.isExpired(c.now()) {
+		entry.expiry = expiry
+		entry.lifetime = c.lifetimeFor(expiry)
+	}
+}
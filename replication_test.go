@@ -0,0 +1,143 @@
+package fastcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReplication(t *testing.T) {
+	primary := New(DefaultConfig())
+	defer primary.Close()
+	peer := New(DefaultConfig())
+	defer peer.Close()
+
+	// Mirror both ways, as a two-node cluster would
+	var forwarded int32
+	primary.AddReplica(func(ev CacheEvent) {
+		atomic.AddInt32(&forwarded, 1)
+		if err := peer.ApplyRemote(ev); err != nil {
+			t.Errorf("ApplyRemote failed: %v", err)
+		}
+	})
+	peer.AddReplica(func(ev CacheEvent) {
+		atomic.AddInt32(&forwarded, 1)
+		if err := primary.ApplyRemote(ev); err != nil {
+			t.Errorf("ApplyRemote failed: %v", err)
+		}
+	})
+
+	if err := primary.Set("user:1", "alice", time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, found := peer.Get("user:1"); !found || value != "alice" {
+		t.Errorf("Expected replicated value on peer, got %v, %v", value, found)
+	}
+	if atomic.LoadInt32(&forwarded) != 1 {
+		t.Errorf("Expected the replicated write not to be forwarded back, got %d events", forwarded)
+	}
+
+	// The expiry travels with the event
+	info, _ := peer.EntryInfo("user:1")
+	if info == nil || info.TTL <= 0 || info.TTL > time.Minute {
+		t.Errorf("Expected replicated TTL within a minute, got %+v", info)
+	}
+
+	_ = peer.SetBytes("blob", []byte("data"))
+	if value, found := primary.GetBytes("blob"); !found || string(value) != "data" {
+		t.Errorf("Expected SetBytes on peer to reach primary, got %q, %v", value, found)
+	}
+
+	primary.Delete("user:1")
+	if _, found := peer.Get("user:1"); found {
+		t.Error("Expected Delete to be replicated")
+	}
+
+	if err := peer.ApplyRemote(CacheEvent{Type: CacheEventType(99), Key: "k"}); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected ErrInvalidEvent, got %v", err)
+	}
+}
+
+func TestReplicationSetIfChangedExpiry(t *testing.T) {
+	primary := New(DefaultConfig())
+	defer primary.Close()
+	peer := New(DefaultConfig())
+	defer peer.Close()
+
+	var types []CacheEventType
+	primary.AddReplica(func(ev CacheEvent) {
+		types = append(types, ev.Type)
+		if err := peer.ApplyRemote(ev); err != nil {
+			t.Errorf("ApplyRemote failed: %v", err)
+		}
+	})
+
+	_, _ = primary.SetIfChanged("key", "value", time.Minute)
+	if changed, _ := primary.SetIfChanged("key", "value", time.Hour); changed {
+		t.Fatal("Expected an identical value to be unchanged")
+	}
+
+	// The peer follows the refreshed expiry rather than expiring the key early
+	if len(types) != 2 || types[0] != EventSet || types[1] != EventExpire {
+		t.Errorf("Expected a set then an expiry event, got %v", types)
+	}
+	if info, _ := peer.EntryInfo("key"); info == nil || info.TTL <= time.Minute {
+		t.Errorf("Expected the peer's TTL to be refreshed to about an hour, got %+v", info)
+	}
+	if value, found := peer.Get("key"); !found || value != "value" {
+		t.Errorf("Expected the peer to keep the value, got %v, %v", value, found)
+	}
+
+	// An expiry for a key the peer does not hold does not create it
+	if err := peer.ApplyRemote(CacheEvent{Type: EventExpire, Key: "missing", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Errorf("ApplyRemote failed: %v", err)
+	}
+	if peer.Has("missing") {
+		t.Error("Expected an expiry event not to create a key")
+	}
+}
+
+func TestReplicationOrder(t *testing.T) {
+	primary := New(DefaultConfig())
+	defer primary.Close()
+	peer := New(DefaultConfig())
+	defer peer.Close()
+
+	var events int64
+	primary.AddReplica(func(ev CacheEvent) {
+		// Stall some deliveries to give racing writers a chance to overtake
+		if atomic.AddInt64(&events, 1)%3 == 0 {
+			time.Sleep(10 * time.Microsecond)
+		}
+		if err := peer.ApplyRemote(ev); err != nil {
+			t.Errorf("ApplyRemote failed: %v", err)
+		}
+	})
+
+	// Racing writes of one key must reach the peer in the order they were
+	// applied, or the peer keeps a different final value
+	for round := 0; round < 50; round++ {
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					if i%5 == 4 {
+						primary.Delete("key")
+						continue
+					}
+					_ = primary.Set("key", g*100+i)
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		want, wantFound := primary.Get("key")
+		if got, found := peer.Get("key"); got != want || found != wantFound {
+			t.Fatalf("Round %d: peer has %v (found=%v), primary has %v (found=%v)", round, got, found, want, wantFound)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package fastcache
+
+import "sync/atomic"
+
+// jumpHash maps key to one of buckets using Lamping and Veach's jump
+// consistent hash. Growing from n to m buckets moves only the keys that land
+// in the new buckets, about (m-n)/m of them.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// ReshardTo changes the number of shards to n at runtime, moving entries whose
+// shard changes. Shards that exist in both layouts are reused, so only moved
+// entries are touched; with ConsistentHashing that is the minimal fraction of
+// keys, while hash modulo moves most keys unless n is a multiple of the
+// current count. Every shard is locked while entries move, so operations
+// block for the duration. Moved entries lose their recency order.
+func (c *Cache) ReshardTo(n int) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrCacheClosed
+	}
+	if n <= 0 || n > 65536 {
+		return ErrInvalidConfig{Field: "ShardCount", Message: "must be between 1 and 65536"}
+	}
+
+	c.reshardMu.Lock()
+	defer c.reshardMu.Unlock()
+
+	old := c.currentLayout()
+	if n == len(old.shards) {
+		return nil
+	}
+
+	for _, shard := range old.shards {
+		shard.mu.Lock()
+	}
+	defer func() {
+		for _, shard := range old.shards {
+			shard.mu.Unlock()
+		}
+	}()
+
+	config := *c.config
+	config.ShardCount = n
+	shards := make([]*Shard, n)
+	for i := range shards {
+		if i < len(old.shards) {
+			shards[i] = old.shards[i]
+		} else {
+			shards[i] = newShard(&config)
+		}
+	}
+
+	for _, src := range old.shards {
+		for key, entry := range src.data {
+			dst := shards[c.shardIndexIn(key, n)]
+			if dst == src {
+				continue
+			}
+
+			src.unlink(entry)
+			delete(src.data, key)
+			atomic.AddInt64(&src.size, -entry.size)
+
+			dst.data[key] = entry
+			dst.link(entry)
+			atomic.AddInt64(&dst.size, entry.size)
+		}
+	}
+
+	// Operations waiting on an old shard's lock notice the new layout and retry
+	c.layout.Store(&shardLayout{shards: shards})
+
+	// Keep the counts of dropped shards in the cache-wide totals
+	if c.config.ShardedStatsOnly && n < len(old.shards) {
+		for _, shard := range old.shards[n:] {
+			atomic.AddInt64(&c.totalHits, atomic.LoadInt64(&shard.hitCount))
+			atomic.AddInt64(&c.totalMiss, atomic.LoadInt64(&shard.missCount))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package fastcache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newReshardCache(shards int, consistent bool) *Cache {
+	return New(&Config{
+		MaxMemoryBytes:    64 * 1024 * 1024,
+		ShardCount:        shards,
+		CleanupInterval:   time.Minute,
+		ConsistentHashing: consistent,
+	})
+}
+
+// reshardAndCount fills a cache, reshards it to n, verifies every key is still
+// readable and returns the fraction of keys that kept their shard index
+func reshardAndCount(t *testing.T, cache *Cache, n int) float64 {
+	const keys = 10000
+	before := make([]int, keys)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		cache.Set(key, i)
+		before[i] = cache.ShardIndex(key)
+	}
+	size := cache.GetStats().TotalSize
+
+	if err := cache.ReshardTo(n); err != nil {
+		t.Fatalf("ReshardTo(%d) failed: %v", n, err)
+	}
+
+	stayed := 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		if value, exists := cache.Get(key); !exists || value.(int) != i {
+			t.Fatalf("Key %s not retrievable after resharding: %v, %v", key, value, exists)
+		}
+		idx := cache.ShardIndex(key)
+		if _, exists := cache.allShards()[idx].data[key]; !exists {
+			t.Fatalf("Key %s is not stored in its shard %d", key, idx)
+		}
+		if idx == before[i] {
+			stayed++
+		}
+	}
+
+	stats := cache.GetStats()
+	if stats.ShardCount != n || stats.TotalEntries != keys || stats.TotalSize != size {
+		t.Errorf("Unexpected stats after resharding: %+v", stats)
+	}
+	var shardSizes int64
+	for _, shard := range cache.allShards() {
+		shardSizes += shard.size
+	}
+	if shardSizes != size {
+		t.Errorf("Shard sizes sum to %d, expected %d", shardSizes, size)
+	}
+
+	return float64(stayed) / keys
+}
+
+func TestReshardToGrow(t *testing.T) {
+	cache := newReshardCache(256, true)
+	defer cache.Close()
+
+	// Doubling has to move half the keys to fill the new shards; consistent
+	// hashing moves no more than that
+	if stayed := reshardAndCount(t, cache, 512); stayed < 0.45 {
+		t.Errorf("Expected about half of the keys to stay, got %.2f", stayed)
+	}
+}
+
+func TestReshardToMinimalMovement(t *testing.T) {
+	consistent := newReshardCache(256, true)
+	defer consistent.Close()
+	modulo := newReshardCache(256, false)
+	defer modulo.Close()
+
+	// Growing by a quarter should leave about 80% of keys in place
+	stayedConsistent := reshardAndCount(t, consistent, 320)
+	stayedModulo := reshardAndCount(t, modulo, 320)
+	if stayedConsistent < 0.75 {
+		t.Errorf("Expected most keys to stay with consistent hashing, got %.2f", stayedConsistent)
+	}
+	if stayedModulo >= stayedConsistent {
+		t.Errorf("Expected modulo (%.2f) to move more keys than consistent hashing (%.2f)",
+			stayedModulo, stayedConsistent)
+	}
+}
+
+func TestReshardToShrink(t *testing.T) {
+	cache := newReshardCache(512, true)
+	defer cache.Close()
+
+	reshardAndCount(t, cache, 256)
+
+	if err := cache.ReshardTo(0); err == nil {
+		t.Error("Expected an error for zero shards")
+	}
+}
+
+func TestReshardToConcurrent(t *testing.T) {
+	cache := newReshardCache(16, true)
+	defer cache.Close()
+
+	var (
+		wg      sync.WaitGroup
+		stop    int32
+		written [4]int
+	)
+	for w := 0; w < len(written); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; atomic.LoadInt32(&stop) == 0; i++ {
+				key := fmt.Sprintf("w%d_%d", w, i)
+				cache.Set(key, i)
+				if _, exists := cache.Get(key); !exists {
+					t.Errorf("Key %s missing right after Set", key)
+					return
+				}
+				written[w] = i + 1
+			}
+		}(w)
+	}
+
+	for _, n := range []int{32, 64, 24, 128, 16} {
+		if err := cache.ReshardTo(n); err != nil {
+			t.Fatalf("ReshardTo(%d) failed: %v", n, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+
+	for w, n := range written {
+		for i := 0; i < n; i++ {
+			if _, exists := cache.Get(fmt.Sprintf("w%d_%d", w, i)); !exists {
+				t.Fatalf("Key w%d_%d lost during resharding", w, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package fastcache
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// sizeSampler estimates value sizes for Config.SizeSamplingRate. For each
+// value type, one write in every `every` is measured by walking the value;
+// the others are charged the running average of the measurements so far.
+type sizeSampler struct {
+	every uint64
+	types sync.Map // reflect.Type -> *typeSizes
+}
+
+// typeSizes holds the measurements of one value type
+type typeSizes struct {
+	writes  uint64
+	samples int64
+	total   int64
+}
+
+// newSizeSampler returns a sampler measuring a rate fraction of writes
+func newSizeSampler(rate float64) *sizeSampler {
+	every := uint64(math.Round(1 / rate))
+	if every < 1 {
+		every = 1
+	}
+	return &sizeSampler{every: every}
+}
+
+// size returns the measured or average size of value
+func (s *sizeSampler) size(value interface{}) int64 {
+	t := reflect.TypeOf(value)
+	v, ok := s.types.Load(t)
+	if !ok {
+		v, _ = s.types.LoadOrStore(t, &typeSizes{})
+	}
+	sizes := v.(*typeSizes)
+
+	if atomic.AddUint64(&sizes.writes, 1)%s.every != 1%s.every {
+		if samples := atomic.LoadInt64(&sizes.samples); samples > 0 {
+			return atomic.LoadInt64(&sizes.total) / samples
+		}
+	}
+	size := deepValueSize(value)
+	atomic.AddInt64(&sizes.total, size)
+	atomic.AddInt64(&sizes.samples, 1)
+	return size
+}
+
+// entrySize returns the size charged for an entry. Values whose size is cheap
+// to compute exactly always use calculateSize; with SizeSamplingRate set,
+// other values are sized by the sampler.
+func (c *Cache) entrySize(key string, value interface{}) int64 {
+	if c.sizes == nil {
+		return calculateSize(key, value)
+	}
+	switch value.(type) {
+	case nil, string, []byte, *compressedValue, *encodedValue, []interface{},
+		int, int32, int64, uint, uint32, uint64, float32, float64, bool:
+		return calculateSize(key, value)
+	}
+	return int64(len(key)) + c.sizes.size(value) + 64
+}
+
+// deepValueSize returns the memory used by value and everything it references,
+// counting memory shared through pointers, slices and maps once
+func deepValueSize(value interface{}) int64 {
+	if value == nil {
+		return 0
+	}
+	v := reflect.ValueOf(value)
+	return int64(v.Type().Size()) + referencedSize(v, make(map[uintptr]struct{}))
+}
+
+// referencedSize returns the memory referenced by v beyond its own inline
+// size, skipping memory already in seen
+func referencedSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !markSeen(seen, v.Pointer()) {
+			return 0
+		}
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + referencedSize(elem, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + referencedSize(elem, seen)
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || !markSeen(seen, v.Pointer()) {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if hasReferences(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				size += referencedSize(v.Index(i), seen)
+			}
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		if hasReferences(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				size += referencedSize(v.Index(i), seen)
+			}
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += referencedSize(v.Field(i), seen)
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() || !markSeen(seen, v.Pointer()) {
+			return 0
+		}
+		t := v.Type()
+		size := int64(v.Len()) * int64(t.Key().Size()+t.Elem().Size())
+		for iter := v.MapRange(); iter.Next(); {
+			size += referencedSize(iter.Key(), seen) + referencedSize(iter.Value(), seen)
+		}
+		return size
+	default:
+		// Numbers and bools are inline; channels, funcs and unsafe pointers
+		// are not followed
+		return 0
+	}
+}
+
+// markSeen records addr and reports whether it was not already seen
+func markSeen(seen map[uintptr]struct{}, addr uintptr) bool {
+	if _, ok := seen[addr]; ok {
+		return false
+	}
+	seen[addr] = struct{}{}
+	return true
+}
+
+// hasReferences reports whether values of type t can reference other memory
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return hasReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,58 @@
+package fastcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSizeSampling(t *testing.T) {
+	totalSize := func(rate float64) int64 {
+		config := &Config{
+			MaxMemoryBytes:   64 * 1024 * 1024,
+			ShardCount:       16,
+			CleanupInterval:  time.Minute,
+			SizeSamplingRate: rate,
+		}
+		cache := New(config)
+		defer cache.Close()
+
+		for i := 0; i < 1000; i++ {
+			if err := cache.Set(fmt.Sprintf("key_%d", i), newSizedRecord(i)); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+		}
+		return cache.GetStats().TotalSize
+	}
+
+	estimated, exact, sampled := totalSize(0), totalSize(1), totalSize(0.05)
+	if exact <= 2*estimated {
+		t.Errorf("Expected exact sizing to see referenced memory, got %d vs fixed estimate %d", exact, estimated)
+	}
+	if diff := sampled - exact; diff < -exact/10 || diff > exact/10 {
+		t.Errorf("Expected sampled total within 10%% of exact %d, got %d", exact, sampled)
+	}
+}
+
+func TestDeepValueSize(t *testing.T) {
+	type node struct {
+		Next  *node
+		Value [64]byte
+	}
+	a := &node{}
+	b := &node{Next: a}
+	a.Next = b
+	if size := deepValueSize(a); size < 128 || size > 256 {
+		t.Errorf("Expected a cycle to be counted once, got %d", size)
+	}
+
+	shared := make([]byte, 1024)
+	pair := struct{ A, B []byte }{shared, shared}
+	if size := deepValueSize(pair); size < 1024 || size >= 2048 {
+		t.Errorf("Expected shared memory to be counted once, got %d", size)
+	}
+
+	if size := deepValueSize(map[string]string{"key": "value"}); size <= int64(len("keyvalue")) {
+		t.Errorf("Expected map entries to be counted, got %d", size)
+	}
+}
@@ -1,34 +1,47 @@
 package fastcache
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Stats represents cache statistics
 type Stats struct {
-	TotalSize     int64   `json:"total_size"`
-	TotalEntries  int64   `json:"total_entries"`
-	HitCount      int64   `json:"hit_count"`
-	MissCount     int64   `json:"miss_count"`
-	HitRatio      float64 `json:"hit_ratio"`
-	MemoryUsage   string  `json:"memory_usage"`
-	ShardCount    int     `json:"shard_count"`
-	MaxMemory     int64   `json:"max_memory"`
-	MemoryPercent float64 `json:"memory_percent"`
+	TotalSize        int64   `json:"total_size"`
+	TotalEntries     int64   `json:"total_entries"`
+	HitCount         int64   `json:"hit_count"`
+	MissCount        int64   `json:"miss_count"`
+	HitRatio         float64 `json:"hit_ratio"`
+	MemoryUsage      string  `json:"memory_usage"`
+	ShardCount       int     `json:"shard_count"`
+	MaxMemory        int64   `json:"max_memory"`
+	MemoryPercent    float64 `json:"memory_percent"`
+	AverageEntrySize int64   `json:"average_entry_size"`
+	Evictions        int64   `json:"evictions"`
+	Expirations      int64   `json:"expirations"`
+
+	// DroppedEvictCallbacks counts OnEvict calls skipped because the
+	// EvictCallbackAsync queue was full
+	DroppedEvictCallbacks int64 `json:"dropped_evict_callbacks"`
 }
 
 // GetStats returns current cache statistics
 func (c *Cache) GetStats() *Stats {
+	shards := c.allShards()
 	totalEntries := int64(0)
-	for _, shard := range c.shards {
+	for _, shard := range shards {
 		shard.mu.RLock()
 		totalEntries += int64(len(shard.data))
 		shard.mu.RUnlock()
 	}
 
-	hits := atomic.LoadInt64(&c.totalHits)
-	misses := atomic.LoadInt64(&c.totalMiss)
+	hits, misses := c.hitsAndMisses()
 	total := hits + misses
 
 	var hitRatio float64
@@ -37,19 +50,94 @@ func (c *Cache) GetStats() *Stats {
 	}
 
 	size := atomic.LoadInt64(&c.totalSize)
-	memoryPercent := float64(size) / float64(c.config.MaxMemoryBytes) * 100
+	memoryPercent := safeRatio(float64(size), float64(c.config.MaxMemoryBytes)) * 100
+
+	var averageEntrySize int64
+	if totalEntries > 0 {
+		averageEntrySize = size / totalEntries
+	}
 
 	return &Stats{
-		TotalSize:     size,
-		TotalEntries:  totalEntries,
-		HitCount:      hits,
-		MissCount:     misses,
-		HitRatio:      hitRatio,
-		MemoryUsage:   formatBytes(size),
-		ShardCount:    c.config.ShardCount,
-		MaxMemory:     c.config.MaxMemoryBytes,
-		MemoryPercent: memoryPercent,
+		TotalSize:        size,
+		TotalEntries:     totalEntries,
+		HitCount:         hits,
+		MissCount:        misses,
+		HitRatio:         hitRatio,
+		MemoryUsage:      formatBytes(size),
+		ShardCount:       len(shards),
+		MaxMemory:        c.config.MaxMemoryBytes,
+		MemoryPercent:    memoryPercent,
+		AverageEntrySize: averageEntrySize,
+		Evictions:        atomic.LoadInt64(&c.evictions),
+		Expirations:      atomic.LoadInt64(&c.expirations),
+
+		DroppedEvictCallbacks: atomic.LoadInt64(&c.dropped),
+	}
+}
+
+// AgeStats returns the write times of the least and most recently written
+// live entries. Both are zero when the cache is empty or TrackEntryAge is off.
+func (c *Cache) AgeStats() (oldest, newest time.Time) {
+	if !c.config.TrackEntryAge {
+		return time.Time{}, time.Time{}
+	}
+
+	var minWritten, maxWritten int64
+	now := c.now()
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		for _, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+			if minWritten == 0 || entry.written < minWritten {
+				minWritten = entry.written
+			}
+			if entry.written > maxWritten {
+				maxWritten = entry.written
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	if maxWritten == 0 {
+		return time.Time{}, time.Time{}
 	}
+	return time.Unix(0, minWritten), time.Unix(0, maxWritten)
+}
+
+// SizeHistogram buckets live entries by size. Each entry is tallied under the
+// smallest boundary in buckets that is >= its size; entries larger than every
+// boundary are tallied under math.MaxInt64.
+func (c *Cache) SizeHistogram(buckets []int64) map[int64]int64 {
+	bounds := make([]int64, len(buckets))
+	copy(bounds, buckets)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	histogram := make(map[int64]int64, len(bounds)+1)
+	for _, b := range bounds {
+		histogram[b] = 0
+	}
+
+	now := c.now()
+	for _, shard := range c.allShards() {
+		shard.mu.RLock()
+		for _, entry := range shard.data {
+			if entry.expiry > 0 && now > entry.expiry {
+				continue
+			}
+
+			i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= entry.size })
+			if i < len(bounds) {
+				histogram[bounds[i]]++
+			} else {
+				histogram[math.MaxInt64]++
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	return histogram
 }
 
 // ShardStats represents statistics for a single shard
@@ -61,18 +149,30 @@ type ShardStats struct {
 	MissCount   int64   `json:"miss_count"`
 	HitRatio    float64 `json:"hit_ratio"`
 	MemoryUsage string  `json:"memory_usage"`
+
+	// Under PolicyGDSF, MeanPriority is the average priority of the shard's
+	// evictable entries and Inflation is the priority of its last victim, the
+	// floor that newly accessed entries start from. Both are 0 under other
+	// policies.
+	MeanPriority float64 `json:"mean_priority,omitempty"`
+	Inflation    float64 `json:"inflation,omitempty"`
 }
 
 // GetShardStats returns statistics for all shards
 func (c *Cache) GetShardStats() []ShardStats {
-	stats := make([]ShardStats, len(c.shards))
+	shards := c.allShards()
+	stats := make([]ShardStats, len(shards))
 
-	for i, shard := range c.shards {
+	for i, shard := range shards {
 		shard.mu.RLock()
 		entryCount := len(shard.data)
 		size := atomic.LoadInt64(&shard.size)
 		hits := atomic.LoadInt64(&shard.hitCount)
 		misses := atomic.LoadInt64(&shard.missCount)
+		var meanPriority, inflation float64
+		if shard.gdsf != nil {
+			meanPriority, inflation = shard.gdsf.meanPriority(), shard.gdsf.inflation
+		}
 		shard.mu.RUnlock()
 
 		total := hits + misses
@@ -89,21 +189,203 @@ func (c *Cache) GetShardStats() []ShardStats {
 			MissCount:   misses,
 			HitRatio:    hitRatio,
 			MemoryUsage: formatBytes(size),
+
+			MeanPriority: meanPriority,
+			Inflation:    inflation,
 		}
 	}
 
 	return stats
 }
 
+// ShardSortKey selects the field TopShards ranks shards by
+type ShardSortKey int
+
+const (
+	// SortByEntries ranks shards by entry count
+	SortByEntries ShardSortKey = iota
+	// SortBySize ranks shards by memory size
+	SortBySize
+	// SortByHits ranks shards by hit count
+	SortByHits
+	// SortByMisses ranks shards by miss count
+	SortByMisses
+)
+
+// TopShards returns up to n shards ranked in descending order by the given key
+func (c *Cache) TopShards(n int, by ShardSortKey) []ShardStats {
+	stats := c.GetShardStats()
+
+	metric := func(s ShardStats) int64 {
+		switch by {
+		case SortBySize:
+			return s.Size
+		case SortByHits:
+			return s.HitCount
+		case SortByMisses:
+			return s.MissCount
+		default:
+			return int64(s.EntryCount)
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return metric(stats[i]) > metric(stats[j])
+	})
+
+	if n < 0 {
+		n = 0
+	}
+	if n < len(stats) {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// HotShards returns shards whose entry count or size exceeds threshold times
+// the average across all shards. Use it to diagnose a poorly distributed key space.
+func (c *Cache) HotShards(threshold float64) []ShardStats {
+	stats := c.GetShardStats()
+	if len(stats) == 0 {
+		return nil
+	}
+
+	var totalEntries, totalSize int64
+	for _, s := range stats {
+		totalEntries += int64(s.EntryCount)
+		totalSize += s.Size
+	}
+	avgEntries := float64(totalEntries) / float64(len(stats))
+	avgSize := float64(totalSize) / float64(len(stats))
+
+	var hot []ShardStats
+	for _, s := range stats {
+		if float64(s.EntryCount) > threshold*avgEntries || float64(s.Size) > threshold*avgSize {
+			hot = append(hot, s)
+		}
+	}
+	return hot
+}
+
 // ResetStats resets all statistics counters
 func (c *Cache) ResetStats() {
 	atomic.StoreInt64(&c.totalHits, 0)
 	atomic.StoreInt64(&c.totalMiss, 0)
+	atomic.StoreInt64(&c.evictions, 0)
+	atomic.StoreInt64(&c.expirations, 0)
+	atomic.StoreInt64(&c.dropped, 0)
 
-	for _, shard := range c.shards {
+	for _, shard := range c.allShards() {
 		atomic.StoreInt64(&shard.hitCount, 0)
 		atomic.StoreInt64(&shard.missCount, 0)
 	}
+
+	c.prefixes.Range(func(prefix, _ interface{}) bool {
+		c.prefixes.Delete(prefix)
+		return true
+	})
+
+	if c.rates != nil {
+		c.rates.reset()
+	}
+
+	if c.latency != nil {
+		c.latency.get.reset()
+		c.latency.set.reset()
+		c.latency.delete.reset()
+	}
+}
+
+// recordHit counts a cache hit for key
+func (c *Cache) recordHit(shard *Shard, key string) {
+	atomic.AddInt64(&shard.hitCount, 1)
+	if !c.config.ShardedStatsOnly {
+		atomic.AddInt64(&c.totalHits, 1)
+	}
+	if c.config.PrefixDelimiter != "" {
+		atomic.AddInt64(&c.prefixCounterFor(key).hits, 1)
+	}
+}
+
+// hitsAndMisses returns the cache-wide hit and miss counts. With
+// ShardedStatsOnly the cache-wide counters only hold counts retired from
+// shards dropped by ReshardTo, and the live shards' counters are added.
+func (c *Cache) hitsAndMisses() (hits, misses int64) {
+	hits = atomic.LoadInt64(&c.totalHits)
+	misses = atomic.LoadInt64(&c.totalMiss)
+	if !c.config.ShardedStatsOnly {
+		return hits, misses
+	}
+	for _, shard := range c.allShards() {
+		hits += atomic.LoadInt64(&shard.hitCount)
+		misses += atomic.LoadInt64(&shard.missCount)
+	}
+	return hits, misses
+}
+
+// recordMiss counts a cache miss for key
+func (c *Cache) recordMiss(shard *Shard, key string) {
+	atomic.AddInt64(&shard.missCount, 1)
+	if !c.config.ShardedStatsOnly {
+		atomic.AddInt64(&c.totalMiss, 1)
+	}
+	if c.config.PrefixDelimiter != "" {
+		atomic.AddInt64(&c.prefixCounterFor(key).misses, 1)
+	}
+}
+
+// prefixCounter holds hit/miss counters for a key prefix
+type prefixCounter struct {
+	hits   int64
+	misses int64
+}
+
+// prefixCounterFor returns the counter for the prefix of key, creating it if needed.
+// Keys without the delimiter are grouped under the empty prefix.
+func (c *Cache) prefixCounterFor(key string) *prefixCounter {
+	prefix := ""
+	if i := strings.Index(key, c.config.PrefixDelimiter); i >= 0 {
+		prefix = key[:i]
+	}
+
+	if counter, ok := c.prefixes.Load(prefix); ok {
+		return counter.(*prefixCounter)
+	}
+	counter, _ := c.prefixes.LoadOrStore(prefix, &prefixCounter{})
+	return counter.(*prefixCounter)
+}
+
+// PrefixStat represents hit/miss statistics for a key prefix
+type PrefixStat struct {
+	HitCount  int64   `json:"hit_count"`
+	MissCount int64   `json:"miss_count"`
+	HitRatio  float64 `json:"hit_ratio"`
+}
+
+// PrefixStats returns hit/miss statistics grouped by key prefix. It is empty
+// unless Config.PrefixDelimiter is set.
+func (c *Cache) PrefixStats() map[string]PrefixStat {
+	stats := make(map[string]PrefixStat)
+
+	c.prefixes.Range(func(prefix, value interface{}) bool {
+		counter := value.(*prefixCounter)
+		hits := atomic.LoadInt64(&counter.hits)
+		misses := atomic.LoadInt64(&counter.misses)
+
+		var hitRatio float64
+		if total := hits + misses; total > 0 {
+			hitRatio = float64(hits) / float64(total)
+		}
+
+		stats[prefix.(string)] = PrefixStat{
+			HitCount:  hits,
+			MissCount: misses,
+			HitRatio:  hitRatio,
+		}
+		return true
+	})
+
+	return stats
 }
 
 // MemoryInfo provides detailed memory information
@@ -125,10 +407,11 @@ func (c *Cache) GetMemoryInfo() *MemoryInfo {
 	if available < 0 {
 		available = 0
 	}
-	percent := float64(used) / float64(c.config.MaxMemoryBytes) * 100
+	percent := safeRatio(float64(used), float64(c.config.MaxMemoryBytes)) * 100
 
-	shardSizes := make([]int64, len(c.shards))
-	for i, shard := range c.shards {
+	shards := c.allShards()
+	shardSizes := make([]int64, len(shards))
+	for i, shard := range shards {
 		shardSizes[i] = atomic.LoadInt64(&shard.size)
 	}
 
@@ -157,8 +440,7 @@ type PerformanceMetrics struct {
 
 // GetPerformanceMetrics returns performance metrics
 func (c *Cache) GetPerformanceMetrics() *PerformanceMetrics {
-	hits := atomic.LoadInt64(&c.totalHits)
-	misses := atomic.LoadInt64(&c.totalMiss)
+	hits, misses := c.hitsAndMisses()
 	total := hits + misses
 
 	var hitRate, missRate float64
@@ -170,9 +452,10 @@ func (c *Cache) GetPerformanceMetrics() *PerformanceMetrics {
 	// Calculate shard load distribution
 	var totalEntries int64
 	var maxLoad, minLoad int
-	loads := make([]int, len(c.shards))
+	shards := c.allShards()
+	loads := make([]int, len(shards))
 
-	for i, shard := range c.shards {
+	for i, shard := range shards {
 		shard.mu.RLock()
 		load := len(shard.data)
 		loads[i] = load
@@ -187,7 +470,7 @@ func (c *Cache) GetPerformanceMetrics() *PerformanceMetrics {
 		shard.mu.RUnlock()
 	}
 
-	avgLoad := float64(totalEntries) / float64(len(c.shards))
+	avgLoad := safeRatio(float64(totalEntries), float64(len(shards)))
 
 	// Calculate standard deviation for load balance
 	var variance float64
@@ -195,7 +478,7 @@ func (c *Cache) GetPerformanceMetrics() *PerformanceMetrics {
 		diff := float64(load) - avgLoad
 		variance += diff * diff
 	}
-	variance /= float64(len(loads))
+	variance = safeRatio(variance, float64(len(loads)))
 	loadBalance := variance // Using variance as load balance metric
 
 	return &PerformanceMetrics{
@@ -209,6 +492,19 @@ func (c *Cache) GetPerformanceMetrics() *PerformanceMetrics {
 	}
 }
 
+// safeRatio returns part/whole, or 0 when whole is zero or the result is not
+// finite, so stats never hold NaN or Inf, which encoding/json rejects
+func safeRatio(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	r := part / whole
+	if math.IsNaN(r) || math.IsInf(r, 0) {
+		return 0
+	}
+	return r
+}
+
 // formatBytes formats bytes into human readable format
 func formatBytes(bytes int64) string {
 	const unit = 1024
@@ -228,3 +524,72 @@ func (s *Stats) String() string {
 	return fmt.Sprintf("Entries: %d, Memory: %s (%.1f%%), Hit Ratio: %.2f%%, Operations: %d",
 		s.TotalEntries, s.MemoryUsage, s.MemoryPercent, s.HitRatio*100, s.HitCount+s.MissCount)
 }
+
+// StartStatsReporter calls sink with a GetStats snapshot every interval until
+// the returned stop function is called or the cache is closed. stop waits for
+// an in-progress sink call to return, so it must not be called from sink. It
+// is a no-op when interval is not positive or the cache is already closed.
+func (c *Cache) StartStatsReporter(interval time.Duration, sink func(*Stats)) (stop func()) {
+	if interval <= 0 || sink == nil {
+		return func() {}
+	}
+
+	// Checking closed under startMu keeps Close from waiting on c.wg before
+	// the reporter is counted in it
+	c.startMu.Lock()
+	if atomic.LoadInt32(&c.closed) == 1 {
+		c.startMu.Unlock()
+		return func() {}
+	}
+	c.wg.Add(1)
+	c.startMu.Unlock()
+
+	quit := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer c.wg.Done()
+		defer close(exited)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stopCh:
+				return
+			case <-quit:
+				return
+			case <-ticker.C:
+				sink(c.GetStats())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+		<-exited
+	}
+}
+
+// Metrics bundles every statistics getter into one snapshot for MetricsJSON
+type Metrics struct {
+	Timestamp   time.Time           `json:"timestamp"`
+	Stats       *Stats              `json:"stats"`
+	Memory      *MemoryInfo         `json:"memory"`
+	Performance *PerformanceMetrics `json:"performance"`
+	Shards      []ShardStats        `json:"shards"`
+}
+
+// MetricsJSON returns GetStats, GetMemoryInfo, GetPerformanceMetrics and
+// GetShardStats as a single JSON document, for offline analysis. The getters
+// are read one after another, so the sections are not an atomic snapshot.
+func (c *Cache) MetricsJSON() ([]byte, error) {
+	return json.Marshal(&Metrics{
+		Timestamp:   time.Now(),
+		Stats:       c.GetStats(),
+		Memory:      c.GetMemoryInfo(),
+		Performance: c.GetPerformanceMetrics(),
+		Shards:      c.GetShardStats(),
+	})
+}
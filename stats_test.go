@@ -0,0 +1,494 @@
+package fastcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAverageEntrySize(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	if avg := cache.GetStats().AverageEntrySize; avg != 0 {
+		t.Errorf("Expected 0 average on empty cache, got %d", avg)
+	}
+
+	// Each entry is 4 (key) + 100 (value) + 64 (overhead) bytes
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(fmt.Sprintf("k_%02d", i), make([]byte, 100))
+	}
+
+	if avg := cache.GetStats().AverageEntrySize; avg != 168 {
+		t.Errorf("Expected average entry size 168, got %d", avg)
+	}
+}
+
+func TestSizeHistogram(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	// Sizes are 4 (key) + value + 64 (overhead)
+	for i := 0; i < 5; i++ {
+		_ = cache.Set(fmt.Sprintf("s_%02d", i), make([]byte, 10)) // 78 bytes
+	}
+	for i := 0; i < 3; i++ {
+		_ = cache.Set(fmt.Sprintf("m_%02d", i), make([]byte, 500)) // 568 bytes
+	}
+	for i := 0; i < 2; i++ {
+		_ = cache.Set(fmt.Sprintf("l_%02d", i), make([]byte, 5000)) // 5068 bytes
+	}
+
+	histogram := cache.SizeHistogram([]int64{1024, 128})
+
+	expected := map[int64]int64{
+		128:           5,
+		1024:          3,
+		math.MaxInt64: 2,
+	}
+	for bucket, count := range expected {
+		if histogram[bucket] != count {
+			t.Errorf("Bucket %d: expected %d entries, got %d", bucket, count, histogram[bucket])
+		}
+	}
+}
+
+func TestPrefixStats(t *testing.T) {
+	config := DefaultConfig()
+	config.PrefixDelimiter = ":"
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(fmt.Sprintf("user:%d", i), i)
+		_ = cache.Set(fmt.Sprintf("product:%d", i), i)
+	}
+
+	// user: 10 hits, 10 misses; product: 5 hits, 15 misses
+	for i := 0; i < 20; i++ {
+		cache.Get(fmt.Sprintf("user:%d", i))
+	}
+	for i := 5; i < 25; i++ {
+		cache.Get(fmt.Sprintf("product:%d", i))
+	}
+
+	stats := cache.PrefixStats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 prefixes, got %d: %v", len(stats), stats)
+	}
+
+	user := stats["user"]
+	if user.HitCount != 10 || user.MissCount != 10 || user.HitRatio != 0.5 {
+		t.Errorf("Unexpected user stats: %+v", user)
+	}
+
+	product := stats["product"]
+	if product.HitCount != 5 || product.MissCount != 15 || product.HitRatio != 0.25 {
+		t.Errorf("Unexpected product stats: %+v", product)
+	}
+
+	cache.ResetStats()
+	if len(cache.PrefixStats()) != 0 {
+		t.Error("Expected prefix stats to be cleared by ResetStats")
+	}
+}
+
+func TestPrefixStatsDisabled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.Set("user:1", 1)
+	cache.Get("user:1")
+
+	if len(cache.PrefixStats()) != 0 {
+		t.Error("Expected no prefix stats without PrefixDelimiter")
+	}
+}
+
+func TestRateStats(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableRateStats = true
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.Set("rate_key", "value")
+
+	// Generate roughly 2000 ops/sec for 1.2 seconds, half hits and half misses
+	start := time.Now()
+	for time.Since(start) < 1200*time.Millisecond {
+		cache.Get("rate_key")
+		cache.Get("missing_key")
+		time.Sleep(time.Millisecond)
+	}
+
+	stats := cache.GetRateStats()
+	elapsed := time.Since(start).Seconds()
+	expected := float64(cache.GetStats().HitCount+cache.GetStats().MissCount) / elapsed
+
+	if stats.QPSLastSecond < expected/2 || stats.QPSLastSecond > expected*2 {
+		t.Errorf("QPSLastSecond %.0f not near expected %.0f", stats.QPSLastSecond, expected)
+	}
+	if stats.QPS1m < expected/2 || stats.QPS1m > expected*2 {
+		t.Errorf("QPS1m %.0f not near expected %.0f", stats.QPS1m, expected)
+	}
+	if stats.HitRateLastMinute < 0.4 || stats.HitRateLastMinute > 0.6 {
+		t.Errorf("Expected hit rate near 0.5, got %f", stats.HitRateLastMinute)
+	}
+}
+
+func TestRateStatsDisabled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	cache.Get("missing_key")
+	if stats := cache.GetRateStats(); stats != (RateStats{}) {
+		t.Errorf("Expected zero rate stats when disabled, got %+v", stats)
+	}
+}
+
+func TestHotShards(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 16
+	cache := New(config)
+	defer cache.Close()
+
+	// Spread a few keys evenly, then pile many keys onto two shards
+	hotShards := map[int]bool{3: true, 11: true}
+	perShard := make(map[int]int)
+	for i := 0; perShard[3] < 100 || perShard[11] < 100; i++ {
+		key := fmt.Sprintf("skew_key_%d", i)
+		shardID := int(cache.hash(key) % uint32(config.ShardCount))
+		if hotShards[shardID] || perShard[shardID] < 5 {
+			_ = cache.Set(key, i)
+			perShard[shardID]++
+		}
+	}
+
+	hot := cache.HotShards(2.0)
+	if len(hot) != len(hotShards) {
+		t.Fatalf("Expected %d hot shards, got %d: %+v", len(hotShards), len(hot), hot)
+	}
+	for _, s := range hot {
+		if !hotShards[s.ShardID] {
+			t.Errorf("Shard %d unexpectedly flagged as hot", s.ShardID)
+		}
+	}
+}
+
+func TestTopShards(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 8
+	cache := New(config)
+	defer cache.Close()
+
+	// Put 40 entries in shard 5, 20 in shard 2, 10 in shard 7
+	want := map[int]int{5: 40, 2: 20, 7: 10}
+	got := make(map[int]int)
+	for i := 0; got[5] < 40 || got[2] < 20 || got[7] < 10; i++ {
+		key := fmt.Sprintf("top_key_%d", i)
+		shardID := int(cache.hash(key) % uint32(config.ShardCount))
+		if got[shardID] < want[shardID] {
+			_ = cache.Set(key, i)
+			got[shardID]++
+		}
+	}
+
+	top := cache.TopShards(2, SortByEntries)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 shards, got %d", len(top))
+	}
+	if top[0].ShardID != 5 || top[1].ShardID != 2 {
+		t.Errorf("Expected shards [5 2] by entries, got [%d %d]", top[0].ShardID, top[1].ShardID)
+	}
+
+	top = cache.TopShards(3, SortBySize)
+	if top[0].ShardID != 5 || top[1].ShardID != 2 || top[2].ShardID != 7 {
+		t.Errorf("Expected shards [5 2 7] by size, got [%d %d %d]", top[0].ShardID, top[1].ShardID, top[2].ShardID)
+	}
+
+	if n := len(cache.TopShards(100, SortByHits)); n != config.ShardCount {
+		t.Errorf("Expected n to be capped at %d shards, got %d", config.ShardCount, n)
+	}
+}
+
+func TestStartStatsReporter(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+	_ = cache.Set("key", "value")
+
+	var calls int64
+	stop := cache.StartStatsReporter(20*time.Millisecond, func(stats *Stats) {
+		if stats.TotalEntries != 1 {
+			t.Errorf("Expected snapshot with 1 entry, got %d", stats.TotalEntries)
+		}
+		atomic.AddInt64(&calls, 1)
+	})
+
+	time.Sleep(110 * time.Millisecond)
+	stop()
+	n := atomic.LoadInt64(&calls)
+	if n < 3 || n > 6 {
+		t.Errorf("Expected about 5 reports, got %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if after := atomic.LoadInt64(&calls); after != n {
+		t.Errorf("Expected no reports after stop, got %d more", after-n)
+	}
+	stop() // stopping twice is safe
+}
+
+func TestStatsReporterStopsOnClose(t *testing.T) {
+	cache := New(DefaultConfig())
+
+	var calls int64
+	cache.StartStatsReporter(10*time.Millisecond, func(*Stats) {
+		atomic.AddInt64(&calls, 1)
+	})
+	time.Sleep(30 * time.Millisecond)
+
+	// Close waits for the reporter goroutine to exit
+	cache.Close()
+	n := atomic.LoadInt64(&calls)
+	time.Sleep(30 * time.Millisecond)
+	if after := atomic.LoadInt64(&calls); after != n {
+		t.Errorf("Expected no reports after Close, got %d more", after-n)
+	}
+}
+
+func TestStatsReporterConcurrentClose(t *testing.T) {
+	// With no background goroutines, Close's wait can race a reporter being
+	// counted from zero
+	config := DefaultConfig()
+	config.DisableBackgroundCleanup = true
+	for i := 0; i < 200; i++ {
+		cache := New(config)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		var stop func()
+		go func() {
+			defer wg.Done()
+			stop = cache.StartStatsReporter(time.Millisecond, func(*Stats) {})
+		}()
+		go func() {
+			defer wg.Done()
+			cache.Close()
+		}()
+		wg.Wait()
+
+		// Either the reporter never started or Close stopped it
+		stop()
+	}
+}
+
+func TestAgeStats(t *testing.T) {
+	config := DefaultConfig()
+	config.TrackEntryAge = true
+	cache := New(config)
+	defer cache.Close()
+
+	if oldest, newest := cache.AgeStats(); !oldest.IsZero() || !newest.IsZero() {
+		t.Errorf("Expected zero times for an empty cache, got %v, %v", oldest, newest)
+	}
+
+	before := time.Now()
+	_ = cache.Set("first", 1)
+	time.Sleep(30 * time.Millisecond)
+	_ = cache.Set("second", 2)
+	time.Sleep(30 * time.Millisecond)
+	_ = cache.Set("third", 3)
+	after := time.Now()
+
+	oldest, newest := cache.AgeStats()
+	if oldest.Before(before) || newest.After(after) {
+		t.Errorf("Expected times within [%v, %v], got %v, %v", before, after, oldest, newest)
+	}
+	if span := newest.Sub(oldest); span < 60*time.Millisecond || span > after.Sub(before) {
+		t.Errorf("Expected a span of at least 60ms, got %v", span)
+	}
+
+	// Rewriting the oldest entry moves the span forward
+	_ = cache.Set("first", 10)
+	if o, _ := cache.AgeStats(); o.Sub(oldest) < 30*time.Millisecond {
+		t.Errorf("Expected oldest to move to the second write, got %v", o)
+	}
+}
+
+func TestAgeStatsDisabled(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.Set("key", "value")
+	if oldest, newest := cache.AgeStats(); !oldest.IsZero() || !newest.IsZero() {
+		t.Errorf("Expected zero times without TrackEntryAge, got %v, %v", oldest, newest)
+	}
+}
+
+func TestStatsZeroMaxMemory(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.Set("key", "value")
+
+	// Simulate a config that bypassed validation
+	cache.config.MaxMemoryBytes = 0
+
+	stats := cache.GetStats()
+	info := cache.GetMemoryInfo()
+	metrics := cache.GetPerformanceMetrics()
+	for name, value := range map[string]float64{
+		"MemoryPercent": stats.MemoryPercent,
+		"Percent":       info.Percent,
+		"AvgShardLoad":  metrics.AvgShardLoad,
+		"LoadBalance":   metrics.LoadBalance,
+	} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			t.Errorf("Expected finite %s, got %v", name, value)
+		}
+	}
+	if stats.MemoryPercent != 0 || info.Percent != 0 {
+		t.Errorf("Expected 0%% of a zero limit, got %v and %v", stats.MemoryPercent, info.Percent)
+	}
+
+	for _, v := range []interface{}{stats, info, metrics} {
+		if _, err := json.Marshal(v); err != nil {
+			t.Errorf("Expected %T to encode as JSON, got %v", v, err)
+		}
+	}
+}
+
+func TestMetricsJSON(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 4
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(fmt.Sprintf("metrics_key_%d", i), i)
+	}
+	cache.Get("metrics_key_1")
+	cache.Get("missing")
+
+	data, err := cache.MetricsJSON()
+	if err != nil {
+		t.Fatalf("MetricsJSON failed: %v", err)
+	}
+
+	var metrics Metrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	if metrics.Timestamp.IsZero() {
+		t.Error("Expected a timestamp")
+	}
+	if metrics.Stats == nil || metrics.Stats.TotalEntries != 10 {
+		t.Errorf("Expected stats with 10 entries, got %+v", metrics.Stats)
+	}
+	if metrics.Stats != nil && (metrics.Stats.HitCount != 1 || metrics.Stats.MissCount != 1) {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", metrics.Stats.HitCount, metrics.Stats.MissCount)
+	}
+	if metrics.Memory == nil || metrics.Memory.Used == 0 || len(metrics.Memory.ShardSizes) != 4 {
+		t.Errorf("Expected memory info for 4 shards, got %+v", metrics.Memory)
+	}
+	if metrics.Performance == nil || metrics.Performance.TotalOperations != 2 {
+		t.Errorf("Expected 2 operations in performance metrics, got %+v", metrics.Performance)
+	}
+	if len(metrics.Shards) != 4 {
+		t.Fatalf("Expected 4 shard stats, got %d", len(metrics.Shards))
+	}
+	entries := 0
+	for _, shard := range metrics.Shards {
+		entries += shard.EntryCount
+	}
+	if entries != 10 {
+		t.Errorf("Expected shard entries to total 10, got %d", entries)
+	}
+}
+
+func TestShardedStatsOnly(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 8
+	config.ShardedStatsOnly = true
+	config.EnableRateStats = true
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("key_%d", i), i)
+	}
+	for i := 0; i < 150; i++ {
+		cache.Get(fmt.Sprintf("key_%d", i))
+	}
+
+	if atomic.LoadInt64(&cache.totalHits) != 0 || atomic.LoadInt64(&cache.totalMiss) != 0 {
+		t.Error("Expected the cache-wide counters to be left untouched")
+	}
+	stats := cache.GetStats()
+	if stats.HitCount != 100 || stats.MissCount != 50 {
+		t.Errorf("Expected 100 hits and 50 misses, got %d and %d", stats.HitCount, stats.MissCount)
+	}
+	if metrics := cache.GetPerformanceMetrics(); metrics.TotalOperations != 150 {
+		t.Errorf("Expected 150 operations, got %d", metrics.TotalOperations)
+	}
+
+	// Counts of shards dropped by resharding are kept
+	if err := cache.ReshardTo(2); err != nil {
+		t.Fatalf("ReshardTo failed: %v", err)
+	}
+	stats = cache.GetStats()
+	if stats.HitCount != 100 || stats.MissCount != 50 {
+		t.Errorf("Expected 100 hits and 50 misses after resharding, got %d and %d", stats.HitCount, stats.MissCount)
+	}
+
+	cache.ResetStats()
+	if stats := cache.GetStats(); stats.HitCount != 0 || stats.MissCount != 0 {
+		t.Errorf("Expected reset counters, got %d hits and %d misses", stats.HitCount, stats.MissCount)
+	}
+}
+
+func TestHealthStatsPressure(t *testing.T) {
+	config := &Config{
+		MaxMemoryBytes:  64 * 1024, // 64KB
+		ShardCount:      4,
+		CleanupInterval: time.Minute,
+		EnableRateStats: true,
+	}
+	cache := New(config)
+	defer cache.Close()
+
+	health := cache.GetHealthStats()
+	if health.Pressure != PressureLow || health.MemoryHeadroomBytes != config.MaxMemoryBytes {
+		t.Errorf("Expected low pressure and full headroom when empty, got %+v", health)
+	}
+
+	// Fill past the pressure threshold without evicting
+	i := 0
+	for ; cache.GetStats().MemoryPercent < 92; i++ {
+		_ = cache.Set(fmt.Sprintf("health_key_%d", i), make([]byte, 150))
+	}
+	if evictions := cache.GetStats().Evictions; evictions != 0 {
+		t.Fatalf("Expected no evictions yet, got %d", evictions)
+	}
+	health = cache.GetHealthStats()
+	if health.Pressure != PressureMedium {
+		t.Errorf("Expected medium pressure at %.1f%% memory, got %s", health.MemoryPercent, health.Pressure)
+	}
+	if want := config.MaxMemoryBytes - cache.GetStats().TotalSize; health.MemoryHeadroomBytes != want {
+		t.Errorf("Expected %d bytes of headroom, got %d", want, health.MemoryHeadroomBytes)
+	}
+
+	// Keep writing until the cache evicts
+	for ; cache.GetStats().Evictions == 0; i++ {
+		_ = cache.Set(fmt.Sprintf("health_key_%d", i), make([]byte, 150))
+	}
+	health = cache.GetHealthStats()
+	if health.Pressure != PressureHigh || health.EvictionRatePerSec <= 0 {
+		t.Errorf("Expected high pressure and a positive eviction rate, got %s at %.1f/s", health.Pressure, health.EvictionRatePerSec)
+	}
+}
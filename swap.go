@@ -0,0 +1,115 @@
+package fastcache
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// SwapContents atomically exchanges the entries of c and other, e.g. to
+// replace a live cache with a freshly built one. Every shard of both caches
+// is locked for the exchange, so each read sees either the old or the new
+// contents and never a half-swapped mix. Values move in their stored form,
+// so both caches should share Codec, Compressor and CompressValuesOver.
+// Per-shard statistics move with the entries; cache-wide hit/miss counters
+// stay with each cache. Both caches must have the same shard count and use
+// the same EvictionPolicy, HashSeed and ConsistentHashing so keys stay on
+// their shards, and equivalent ShardKeyFunc, which cannot be checked.
+func (c *Cache) SwapContents(other *Cache) error {
+	if c == other {
+		return nil
+	}
+	if c.isClosed() || other.isClosed() {
+		return ErrCacheClosed
+	}
+	if c.config.EvictionPolicy != other.config.EvictionPolicy {
+		return ErrInvalidConfig{Field: "EvictionPolicy", Message: "must match to swap contents"}
+	}
+	if c.seed != other.seed {
+		return ErrInvalidConfig{Field: "HashSeed", Message: "must match to swap contents"}
+	}
+	if c.config.ConsistentHashing != other.config.ConsistentHashing {
+		return ErrInvalidConfig{Field: "ConsistentHashing", Message: "must match to swap contents"}
+	}
+
+	// Lock in a fixed order so concurrent opposite swaps cannot deadlock
+	first, second := c, other
+	if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+		first, second = second, first
+	}
+	first.reshardMu.Lock()
+	defer first.reshardMu.Unlock()
+	second.reshardMu.Lock()
+	defer second.reshardMu.Unlock()
+
+	mine, theirs := first.allShards(), second.allShards()
+	if len(mine) != len(theirs) {
+		return ErrInvalidConfig{Field: "ShardCount", Message: "must match to swap contents"}
+	}
+
+	for _, shards := range [][]*Shard{mine, theirs} {
+		for _, shard := range shards {
+			shard.mu.Lock()
+		}
+	}
+
+	// Shards keep their identity and only exchange contents, so operations
+	// holding a shard pointer remain valid
+	for i := range mine {
+		mine[i].swap(theirs[i])
+	}
+
+	size := atomic.LoadInt64(&first.totalSize)
+	atomic.StoreInt64(&first.totalSize, atomic.LoadInt64(&second.totalSize))
+	atomic.StoreInt64(&second.totalSize, size)
+
+	first.tags.swap(&second.tags)
+	first.indexes.swap(&second.indexes)
+
+	// Each miss filter must now cover the keys the cache received
+	for _, side := range []struct {
+		cache  *Cache
+		shards []*Shard
+	}{{first, mine}, {second, theirs}} {
+		if side.cache.filter == nil {
+			continue
+		}
+		for _, shard := range side.shards {
+			for key := range shard.data {
+				side.cache.filter.add(key)
+			}
+		}
+	}
+
+	for _, shards := range [][]*Shard{mine, theirs} {
+		for _, shard := range shards {
+			shard.mu.Unlock()
+		}
+	}
+
+	// Either cache may now be over its own limit
+	c.triggerEviction()
+	other.triggerEviction()
+	return nil
+}
+
+// swap exchanges the contents of two shards. The caller must hold both
+// shards' write locks.
+func (s *Shard) swap(o *Shard) {
+	s.data, o.data = o.data, s.data
+	s.lruList, o.lruList = o.lruList, s.lruList
+	s.probation, o.probation = o.probation, s.probation
+	s.sketch, o.sketch = o.sketch, s.sketch
+	s.gdsf, o.gdsf = o.gdsf, s.gdsf
+
+	size := atomic.LoadInt64(&s.size)
+	atomic.StoreInt64(&s.size, atomic.LoadInt64(&o.size))
+	atomic.StoreInt64(&o.size, size)
+
+	hits := atomic.LoadInt64(&s.hitCount)
+	atomic.StoreInt64(&s.hitCount, atomic.LoadInt64(&o.hitCount))
+	atomic.StoreInt64(&o.hitCount, hits)
+
+	misses := atomic.LoadInt64(&s.missCount)
+	atomic.StoreInt64(&s.missCount, atomic.LoadInt64(&o.missCount))
+	atomic.StoreInt64(&o.missCount, misses)
+}
@@ -0,0 +1,130 @@
+package fastcache
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSwapContents(t *testing.T) {
+	live := New(DefaultConfig())
+	defer live.Close()
+	next := New(DefaultConfig())
+	defer next.Close()
+
+	live.SetWithTags("old_only", "old", []string{"v1"})
+	live.Set("shared", "old")
+	next.SetWithTags("new_only", "new", []string{"v2"})
+	next.Set("shared", "new")
+	next.Set("extra", "new")
+
+	liveSize, nextSize := live.GetStats().TotalSize, next.GetStats().TotalSize
+
+	if err := live.SwapContents(next); err != nil {
+		t.Fatalf("SwapContents failed: %v", err)
+	}
+
+	if value, _ := live.Get("shared"); value != "new" {
+		t.Errorf("Expected live cache to serve the new value, got %v", value)
+	}
+	if _, exists := live.Get("old_only"); exists {
+		t.Error("Old entries should no longer be in the live cache")
+	}
+	if value, _ := next.Get("shared"); value != "old" {
+		t.Errorf("Expected the other cache to hold the old contents, got %v", value)
+	}
+
+	if stats := live.GetStats(); stats.TotalEntries != 3 || stats.TotalSize != nextSize {
+		t.Errorf("Unexpected live stats after swap: %+v", stats)
+	}
+	if stats := next.GetStats(); stats.TotalEntries != 2 || stats.TotalSize != liveSize {
+		t.Errorf("Unexpected stats for the other cache after swap: %+v", stats)
+	}
+
+	if removed := live.InvalidateTag("v2"); removed != 1 {
+		t.Errorf("Expected tags to move with their entries, removed %d", removed)
+	}
+	if removed := live.InvalidateTag("v1"); removed != 0 {
+		t.Errorf("Expected old tags to leave the live cache, removed %d", removed)
+	}
+}
+
+func TestSwapContentsMismatch(t *testing.T) {
+	a := New(DefaultConfig())
+	defer a.Close()
+
+	config := DefaultConfig()
+	config.ShardCount = 16
+	b := New(config)
+	defer b.Close()
+
+	if err := a.SwapContents(b); err == nil {
+		t.Error("Expected an error swapping caches with different shard counts")
+	}
+}
+
+func TestSwapContentsConcurrentReads(t *testing.T) {
+	const keys = 1000
+	build := func(version string) *Cache {
+		config := DefaultConfig()
+		config.ShardCount = 64
+		cache := New(config)
+		for i := 0; i < keys; i++ {
+			cache.Set(fmt.Sprintf("key_%d", i), version)
+		}
+		return cache
+	}
+
+	live := build("old")
+	defer live.Close()
+	next := build("new")
+	defer next.Close()
+
+	var (
+		wg      sync.WaitGroup
+		stop    int32
+		sawNew  int32
+		readers = 8
+	)
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			seenNew := false
+			for atomic.LoadInt32(&stop) == 0 {
+				key := fmt.Sprintf("key_%d", rng.Intn(keys))
+				value, exists := live.Get(key)
+				if !exists {
+					t.Errorf("Reader missed %s during swap", key)
+					return
+				}
+				switch value {
+				case "new":
+					seenNew = true
+					atomic.StoreInt32(&sawNew, 1)
+				case "old":
+					if seenNew {
+						t.Errorf("Reader saw old %s after the swap was visible", key)
+						return
+					}
+				}
+			}
+		}(int64(r))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := live.SwapContents(next); err != nil {
+		t.Fatalf("SwapContents failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+
+	if atomic.LoadInt32(&sawNew) == 0 {
+		t.Error("Expected readers to observe the new contents")
+	}
+}
@@ -0,0 +1,149 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tagIndex maps tags to the keys carrying them. Its lock may be taken while a
+// shard lock is held, but a shard lock is never taken while holding it.
+type tagIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+// add records that key carries tags
+func (t *tagIndex) add(key string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.keys == nil {
+		t.keys = make(map[string]map[string]struct{})
+	}
+	for _, tag := range tags {
+		keys, ok := t.keys[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			t.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove drops key from tags
+func (t *tagIndex) remove(key string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tag := range tags {
+		keys := t.keys[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(t.keys, tag)
+		}
+	}
+}
+
+// keysFor returns a copy of the keys carrying tag
+func (t *tagIndex) keysFor(tag string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.keys[tag]))
+	for key := range t.keys[tag] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// reset drops all tags
+func (t *tagIndex) reset() {
+	t.mu.Lock()
+	t.keys = nil
+	t.mu.Unlock()
+}
+
+// swap exchanges the contents of two indexes
+func (t *tagIndex) swap(other *tagIndex) {
+	t.mu.Lock()
+	other.mu.Lock()
+	t.keys, other.keys = other.keys, t.keys
+	other.mu.Unlock()
+	t.mu.Unlock()
+}
+
+// untag removes an entry's tags from the index. The caller must hold the
+// entry's shard write lock.
+func (c *Cache) untag(entry *Entry) {
+	if len(entry.tags) == 0 {
+		return
+	}
+	c.tags.remove(entry.key, entry.tags)
+	entry.tags = nil
+}
+
+// SetWithTags stores a key-value pair tagged with one or more tags so it can
+// later be removed with InvalidateTag. Setting the key again without tags
+// removes its tags.
+func (c *Cache) SetWithTags(key string, value interface{}, tags []string, ttl ...time.Duration) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("set_with_tags", key, ErrCacheClosed)
+	}
+	if err := c.validateKey(key); err != nil {
+		return opError("set_with_tags", key, err)
+	}
+
+	expiry := c.expiryFor(firstTTL(ttl))
+	value, err := c.prepareValue(value)
+	if err != nil {
+		return opError("set_with_tags", key, err)
+	}
+	entryTags := make([]string, len(tags))
+	copy(entryTags, tags)
+
+	shard := c.lockShard(key)
+	sizeDiff, err := c.setLocked(shard, key, value, expiry)
+	if err == nil && len(entryTags) > 0 {
+		shard.data[key].tags = entryTags
+		c.tags.add(key, entryTags)
+	}
+	shard.mu.Unlock()
+	if err != nil {
+		return opError("set_with_tags", key, err)
+	}
+
+	// Trigger eviction if needed (outside of lock to avoid deadlock)
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
+
+// InvalidateTag removes every entry carrying tag and returns the number removed
+func (c *Cache) InvalidateTag(tag string) int {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return 0
+	}
+
+	removed := 0
+	for _, key := range c.tags.keysFor(tag) {
+		shard := c.lockShard(key)
+		if entry, exists := shard.data[key]; exists && entry.hasTag(tag) {
+			c.removeLocked(shard, entry)
+			removed++
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
+
+// hasTag reports whether the entry carries tag
+func (e *Entry) hasTag(tag string) bool {
+	for _, t := range e.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package fastcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.SetWithTags("order:42:summary", "s", []string{"order#42"})
+	_ = cache.SetWithTags("order:42:items", "i", []string{"order#42", "items"})
+	_ = cache.SetWithTags("invoice:7", "inv", []string{"order#42", "customer#9"})
+	_ = cache.SetWithTags("order:43:summary", "s", []string{"order#43"})
+	_ = cache.Set("untagged", "u")
+
+	if removed := cache.InvalidateTag("order#42"); removed != 3 {
+		t.Errorf("Expected 3 entries removed, got %d", removed)
+	}
+
+	for _, key := range []string{"order:42:summary", "order:42:items", "invoice:7"} {
+		if _, exists := cache.Get(key); exists {
+			t.Errorf("%s should have been invalidated", key)
+		}
+	}
+	for _, key := range []string{"order:43:summary", "untagged"} {
+		if _, exists := cache.Get(key); !exists {
+			t.Errorf("%s should not have been invalidated", key)
+		}
+	}
+
+	// Other tags of invalidated entries are cleaned up too
+	if removed := cache.InvalidateTag("customer#9"); removed != 0 {
+		t.Errorf("Expected no entries left for customer#9, got %d", removed)
+	}
+	if removed := cache.InvalidateTag("unknown"); removed != 0 {
+		t.Errorf("Expected 0 entries for unknown tag, got %d", removed)
+	}
+}
+
+func TestTagIndexCleanup(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTTL = 0
+	cache := New(config)
+	defer cache.Close()
+
+	_ = cache.SetWithTags("deleted", 1, []string{"a"})
+	_ = cache.SetWithTags("expired", 2, []string{"a"}, time.Millisecond)
+	_ = cache.SetWithTags("retagged", 3, []string{"a"})
+
+	cache.Delete("deleted")
+	time.Sleep(5 * time.Millisecond)
+	cache.DeleteExpired()
+
+	// Overwriting without tags drops the entry's tags
+	_ = cache.Set("retagged", 4)
+
+	if len(cache.tags.keys) != 0 {
+		t.Errorf("Expected empty tag index, got %v", cache.tags.keys)
+	}
+	if removed := cache.InvalidateTag("a"); removed != 0 {
+		t.Errorf("Expected no entries for tag a, got %d", removed)
+	}
+	if _, exists := cache.Get("retagged"); !exists {
+		t.Error("retagged should not be removed by its old tag")
+	}
+}
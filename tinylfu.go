@@ -0,0 +1,134 @@
+package fastcache
+
+import "sync/atomic"
+
+const (
+	// sketchDepth is the number of rows in the frequency sketch
+	sketchDepth = 4
+
+	// sketchCountersPerCache is the number of counters per sketch row across all shards
+	sketchCountersPerCache = 1 << 16
+
+	// sketchMinWidth is the smallest per-shard sketch row
+	sketchMinWidth = 64
+
+	// sketchMaxCount is the saturation value of a single counter
+	sketchMaxCount = 15
+
+	// sketchResetMultiplier sets how many increments a sketch accepts, relative
+	// to its width, before all counters are halved
+	sketchResetMultiplier = 10
+)
+
+// frequencySketch is a count-min sketch of recent key access frequency used by
+// PolicyTinyLFU. Counters saturate at sketchMaxCount and are periodically
+// halved so keys that were once hot but are now cold lose their advantage.
+// All updates are atomic, so it can be used under a shard's read lock.
+type frequencySketch struct {
+	counters  []uint32
+	width     uint32
+	additions int64
+	resetAt   int64
+}
+
+// newFrequencySketch creates a sketch sized for one of shardCount shards
+func newFrequencySketch(shardCount int) *frequencySketch {
+	width := uint32(sketchMinWidth)
+	for int(width)*shardCount < sketchCountersPerCache {
+		width <<= 1
+	}
+	return &frequencySketch{
+		counters: make([]uint32, sketchDepth*width),
+		width:    width,
+		resetAt:  int64(width) * sketchResetMultiplier,
+	}
+}
+
+// sketchHash is a 64-bit FNV-1a hash of key that does not allocate
+func sketchHash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// index returns the counter index for key in row i
+func (s *frequencySketch) index(h uint64, i int) int {
+	h1, h2 := uint32(h), uint32(h>>32)|1
+	return i*int(s.width) + int((h1+uint32(i)*h2)&(s.width-1))
+}
+
+// increment records an access to key
+func (s *frequencySketch) increment(key string) {
+	h := sketchHash(key)
+	for i := 0; i < sketchDepth; i++ {
+		counter := &s.counters[s.index(h, i)]
+		if n := atomic.LoadUint32(counter); n < sketchMaxCount {
+			// A lost race only drops one increment
+			atomic.CompareAndSwapUint32(counter, n, n+1)
+		}
+	}
+
+	if atomic.AddInt64(&s.additions, 1) == s.resetAt {
+		s.age()
+	}
+}
+
+// estimate returns the approximate access frequency of key
+func (s *frequencySketch) estimate(key string) uint32 {
+	h := sketchHash(key)
+	min := uint32(sketchMaxCount)
+	for i := 0; i < sketchDepth; i++ {
+		if n := atomic.LoadUint32(&s.counters[s.index(h, i)]); n < min {
+			min = n
+		}
+	}
+	return min
+}
+
+// age halves every counter so old frequencies decay
+func (s *frequencySketch) age() {
+	for i := range s.counters {
+		atomic.StoreUint32(&s.counters[i], atomic.LoadUint32(&s.counters[i])/2)
+	}
+	atomic.StoreInt64(&s.additions, s.resetAt/2)
+}
+
+// reset zeroes every counter
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		atomic.StoreUint32(&s.counters[i], 0)
+	}
+	atomic.StoreInt64(&s.additions, 0)
+}
+
+// touch records an access to key when the shard uses PolicyTinyLFU
+func (s *Shard) touch(key string) {
+	if s.sketch != nil {
+		s.sketch.increment(key)
+	}
+}
+
+// admit picks the eviction victim under PolicyTinyLFU: the oldest entry on
+// probation must have been accessed more often than the oldest LRU entry to
+// keep its place. The caller must hold the shard's write lock.
+func (s *Shard) admit() *Entry {
+	newest, oldest := s.probation.Back(), s.lruList.Back()
+	if newest == nil {
+		if oldest == nil {
+			return nil
+		}
+		return oldest.Value.(*Entry)
+	}
+	if oldest == nil {
+		return newest.Value.(*Entry)
+	}
+
+	candidate, tail := newest.Value.(*Entry), oldest.Value.(*Entry)
+	if s.sketch.estimate(candidate.key) <= s.sketch.estimate(tail.key) {
+		return candidate
+	}
+	return tail
+}
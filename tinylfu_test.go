@@ -0,0 +1,83 @@
+package fastcache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestFrequencySketchAging(t *testing.T) {
+	sketch := newFrequencySketch(1024)
+
+	for i := 0; i < 10; i++ {
+		sketch.increment("hot")
+	}
+	if n := sketch.estimate("hot"); n != 10 {
+		t.Fatalf("Expected estimate 10, got %d", n)
+	}
+	if n := sketch.estimate("cold"); n != 0 {
+		t.Errorf("Expected estimate 0 for an unseen key, got %d", n)
+	}
+
+	for i := 0; i < 100; i++ {
+		sketch.increment("hot")
+	}
+	if n := sketch.estimate("hot"); n != sketchMaxCount {
+		t.Errorf("Expected counter to saturate at %d, got %d", sketchMaxCount, n)
+	}
+
+	// Other traffic eventually triggers aging, halving the stale key's count
+	for i := 0; int64(i) < sketch.resetAt; i++ {
+		sketch.increment(fmt.Sprintf("other_%d", i))
+	}
+	if n := sketch.estimate("hot"); n > sketchMaxCount/2 {
+		t.Errorf("Expected hot key to decay after aging, got %d", n)
+	}
+}
+
+func TestTinyLFUHitRatio(t *testing.T) {
+	hitRatio := func(policy EvictionPolicy) float64 {
+		// A single shard makes the comparison measure the policy alone
+		config := &Config{
+			MaxMemoryBytes:  64 * 1024, // 64KB
+			ShardCount:      1,
+			DefaultTTL:      0,
+			CleanupInterval: time.Minute,
+			EvictionPolicy:  policy,
+		}
+		cache := New(config)
+		defer cache.Close()
+
+		rng := rand.New(rand.NewSource(1))
+		zipf := rand.NewZipf(rng, 1.1, 1, 10000)
+		value := make([]byte, 64)
+
+		hits, total := 0, 0
+		for i := 0; i < 100000; i++ {
+			// Periodic one-off scans pollute a pure recency policy
+			if i%1000 == 0 {
+				for j := 0; j < 200; j++ {
+					cache.Set(fmt.Sprintf("scan_%d_%d", i, j), value)
+				}
+			}
+
+			key := fmt.Sprintf("key_%d", zipf.Uint64())
+			total++
+			if _, exists := cache.Get(key); exists {
+				hits++
+			} else {
+				cache.Set(key, value)
+			}
+		}
+		return float64(hits) / float64(total)
+	}
+
+	lru := hitRatio(PolicyLRU)
+	tinyLFU := hitRatio(PolicyTinyLFU)
+	t.Logf("hit ratio: lru=%.3f tinylfu=%.3f", lru, tinyLFU)
+
+	if tinyLFU <= lru {
+		t.Errorf("Expected TinyLFU hit ratio (%.3f) to beat LRU (%.3f)", tinyLFU, lru)
+	}
+}
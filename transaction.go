@@ -0,0 +1,284 @@
+package fastcache
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// txUndo records a key's state before a transaction wrote it
+type txUndo struct {
+	shard    *Shard
+	key      string
+	prev     *Entry // nil when the key was absent or expired
+	payload  payload
+	size     int64
+	expiry   int64
+	lifetime int64
+	written  int64
+	dirty    uint64
+	tags     []string
+	indexes  map[string]string
+}
+
+// SetTransaction stores all items or none of them. Keys and the combined size
+// are checked before anything is written, and every shard involved is locked
+// for the duration of the write, so readers never observe a partial set. If
+// an item cannot be written (the cache closing mid-batch, or RejectOnFull
+// refusing it), items already written are rolled back to their previous
+// values and the error is returned.
+//
+// Atomicity is best-effort with respect to eviction: once the transaction
+// commits, its entries are ordinary entries and may be evicted individually
+// under memory pressure like any other.
+func (c *Cache) SetTransaction(items map[string]interface{}, ttl ...time.Duration) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("set_transaction", "", ErrCacheClosed)
+	}
+
+	keys := make([]string, 0, len(items))
+	values := make(map[string]interface{}, len(items))
+	var total int64
+	for key, value := range items {
+		if err := c.validateKey(key); err != nil {
+			return opError("set_transaction", key, err)
+		}
+		prepared, err := c.prepareValue(value)
+		if err != nil {
+			return opError("set_transaction", key, err)
+		}
+		keys = append(keys, key)
+		values[key] = prepared
+		total += c.entrySize(key, prepared)
+	}
+	if total > c.config.MaxMemoryBytes {
+		return opError("set_transaction", "", ErrMemoryLimitExceeded)
+	}
+
+	sizeDiff, err := c.applyTransaction(keys, values, c.expiryFor(firstTTL(ttl)))
+	if err != nil {
+		return err
+	}
+
+	// Trigger eviction if needed (outside of locks to avoid deadlock)
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
+
+// applyTransaction writes prepared values with every involved shard locked and
+// returns the change in size, rolling back on failure
+func (c *Cache) applyTransaction(keys []string, values map[string]interface{}, expiry int64) (int64, error) {
+	shardOf, locked := c.lockTransaction(keys)
+	defer unlockShards(locked)
+
+	return c.writeTransaction("set_transaction", keys, shardOf, values, func(*Entry) int64 { return expiry })
+}
+
+// writeTransaction writes the prepared values of the keys that have one, in
+// key order, with the expiry expiryOf returns given the key's live entry (nil
+// if there is none), and returns the change in size. On failure every key
+// already written is rolled back. The caller must hold the write lock of
+// every shard in shardOf.
+func (c *Cache) writeTransaction(op string, keys []string, shardOf []*Shard, values map[string]interface{}, expiryOf func(prev *Entry) int64) (int64, error) {
+	undo := make([]txUndo, 0, len(keys))
+	var sizeDiff int64
+	for k, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if atomic.LoadInt32(&c.closed) == 1 {
+			c.rollback(undo)
+			return 0, opError(op, key, ErrCacheClosed)
+		}
+
+		shard := shardOf[k]
+		u := txUndo{shard: shard, key: key}
+		if existing, exists := shard.data[key]; exists && !existing.isExpired(c.now()) {
+			u.prev = existing
+			u.payload = existing.payload
+			u.size = existing.size
+			u.expiry = existing.expiry
+			u.lifetime = existing.lifetime
+			u.written = existing.written
+			u.dirty = existing.dirty
+			u.tags = existing.tags
+			u.indexes = existing.indexes
+		}
+		undo = append(undo, u)
+
+		diff, err := c.setLocked(shard, key, value, expiryOf(u.prev))
+		if err != nil {
+			c.rollback(undo)
+			return 0, opError(op, key, err)
+		}
+		sizeDiff += diff
+	}
+	return sizeDiff, nil
+}
+
+// lockTransaction sorts keys into shard order and write-locks each shard
+// involved once, so concurrent transactions cannot deadlock. It returns the
+// shard of each key and the locked shards, retrying if ReshardTo changes the
+// layout while locking.
+func (c *Cache) lockTransaction(keys []string) (shardOf []*Shard, locked []*Shard) {
+	for {
+		layout := c.currentLayout()
+		n := len(layout.shards)
+		sort.Slice(keys, func(i, j int) bool {
+			si, sj := c.shardIndexIn(keys[i], n), c.shardIndexIn(keys[j], n)
+			if si != sj {
+				return si < sj
+			}
+			return keys[i] < keys[j]
+		})
+
+		shardOf = make([]*Shard, len(keys))
+		locked = locked[:0]
+		for i, key := range keys {
+			shard := layout.shards[c.shardIndexIn(key, n)]
+			shardOf[i] = shard
+			if len(locked) == 0 || locked[len(locked)-1] != shard {
+				shard.mu.Lock()
+				locked = append(locked, shard)
+			}
+		}
+
+		if c.currentLayout() == layout {
+			return shardOf, locked
+		}
+		unlockShards(locked)
+	}
+}
+
+// unlockShards releases shards locked by lockTransaction
+func unlockShards(locked []*Shard) {
+	for _, shard := range locked {
+		shard.mu.Unlock()
+	}
+}
+
+// rollback restores keys written by a failed transaction, newest first.
+// The caller must hold the write lock of every shard involved.
+func (c *Cache) rollback(undo []txUndo) {
+	for i := len(undo) - 1; i >= 0; i-- {
+		u := undo[i]
+		current, exists := u.shard.data[u.key]
+		if !exists {
+			continue
+		}
+		if u.prev == nil {
+			c.removeLocked(u.shard, current)
+			continue
+		}
+
+		// Restore in place rather than through putLocked, so a rollback is
+		// never refused by RejectOnFull
+		sizeDiff := u.size - current.size
+		c.untag(current)
+		c.unindex(current)
+		current.payload = u.payload
+		current.size = u.size
+		current.expiry = u.expiry
+		current.lifetime = u.lifetime
+		current.written = u.written
+		current.dirty = u.dirty
+		atomic.AddInt64(&c.totalSize, sizeDiff)
+		atomic.AddInt64(&u.shard.size, sizeDiff)
+
+		if len(u.tags) > 0 {
+			current.tags = u.tags
+			c.tags.add(u.key, u.tags)
+		}
+		if len(u.indexes) > 0 {
+			current.indexes = u.indexes
+			c.indexes.add(u.key, u.indexes)
+		}
+	}
+}
+
+// MultiUpdate atomically reads and rewrites several keys, for example to move
+// a count from one key to another. Every shard holding one of keys is locked
+// in shard order, so concurrent MultiUpdate and SetTransaction calls cannot
+// deadlock, and fn is called with the live values of keys; missing or expired
+// keys are absent from the map. The keys of the map fn returns are written,
+// keeping the expiry of existing entries and using DefaultTTL for new ones;
+// keys it leaves out are unchanged. If fn returns an error, or a value cannot
+// be written, nothing is changed and the error is returned.
+//
+// fn runs with the shards locked and must not call the cache.
+func (c *Cache) MultiUpdate(keys []string, fn func(vals map[string]interface{}) (map[string]interface{}, error)) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return opError("multi_update", "", ErrCacheClosed)
+	}
+
+	// Deduplicate into a copy, since lockTransaction sorts the keys in place
+	unique := make([]string, 0, len(keys))
+	involved := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := c.validateKey(key); err != nil {
+			return opError("multi_update", key, err)
+		}
+		if !involved[key] {
+			involved[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	sizeDiff, err := c.applyUpdate(unique, involved, fn)
+	if err != nil {
+		return err
+	}
+
+	// Trigger eviction if needed (outside of locks to avoid deadlock)
+	if sizeDiff > 0 {
+		c.triggerEviction()
+	}
+	return nil
+}
+
+// applyUpdate implements MultiUpdate with every involved shard locked and
+// returns the change in size
+func (c *Cache) applyUpdate(keys []string, involved map[string]bool, fn func(vals map[string]interface{}) (map[string]interface{}, error)) (int64, error) {
+	shardOf, locked := c.lockTransaction(keys)
+	defer unlockShards(locked)
+
+	now := c.now()
+	vals := make(map[string]interface{}, len(keys))
+	for k, key := range keys {
+		existing, exists := shardOf[k].data[key]
+		if !exists || existing.isExpired(now) {
+			continue
+		}
+		if value, ok := c.loadValue(existing.payload.get()); ok {
+			vals[key] = value
+		}
+	}
+
+	updates, err := fn(vals)
+	if err != nil {
+		return 0, opError("multi_update", "", err)
+	}
+	values := make(map[string]interface{}, len(updates))
+	for key, value := range updates {
+		if !involved[key] {
+			return 0, opError("multi_update", key, fmt.Errorf("%w: not among the locked keys", ErrInvalidKey))
+		}
+		prepared, err := c.prepareValue(value)
+		if err != nil {
+			return 0, opError("multi_update", key, err)
+		}
+		values[key] = prepared
+	}
+
+	defaultExpiry := c.expiryFor(0)
+	return c.writeTransaction("multi_update", keys, shardOf, values, func(prev *Entry) int64 {
+		if prev != nil {
+			return prev.expiry
+		}
+		return defaultExpiry
+	})
+}
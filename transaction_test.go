@@ -0,0 +1,211 @@
+package fastcache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetTransaction(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	items := map[string]interface{}{
+		"user:1":       "alice",
+		"index:alice":  "user:1",
+		"user:1:perms": []string{"read"},
+	}
+	if err := cache.SetTransaction(items); err != nil {
+		t.Fatalf("SetTransaction failed: %v", err)
+	}
+
+	for key := range items {
+		if _, exists := cache.Get(key); !exists {
+			t.Errorf("Expected %s to be stored", key)
+		}
+	}
+}
+
+func TestSetTransactionRollback(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 4096
+	config.RejectOnFull = true
+	config.HashSeed = 1 // a placement where the large key is not in the first shard
+	cache := New(config)
+	defer cache.Close()
+
+	// Keys are written in shard order, so picking keys that sort before the
+	// large one guarantees RejectOnFull refuses it mid-batch
+	large := "tx_large"
+	largeShard := cache.ShardIndex(large)
+	if largeShard == 0 {
+		t.Fatal("large key maps to the first shard; pick another HashSeed")
+	}
+	items := map[string]interface{}{large: string(make([]byte, 1800))}
+	var written []string
+	for i := 0; len(written) < 3; i++ {
+		key := fmt.Sprintf("tx_%d", i)
+		if cache.ShardIndex(key) < largeShard {
+			items[key] = i
+			written = append(written, key)
+		}
+	}
+
+	// Rolled back writes must not be flushed by write-behind
+	var flushed []string
+	record := func(entries []KeyValue) error {
+		for _, kv := range entries {
+			flushed = append(flushed, kv.Key)
+		}
+		return nil
+	}
+	stop := cache.StartWriteBehind(time.Hour, record)
+
+	_ = cache.Set("filler", string(make([]byte, 2000)))
+	cache.SetWithTags(written[0], "original", []string{"group"})
+	cache.flushDirty(record)
+	flushed = nil
+
+	err := cache.SetTransaction(items)
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("Expected ErrMemoryLimitExceeded, got %v", err)
+	}
+
+	if value, exists := cache.Get(written[0]); !exists || value.(string) != "original" {
+		t.Errorf("Expected %s to be restored, got %v, %v", written[0], value, exists)
+	}
+	for _, key := range written[1:] {
+		if _, exists := cache.Get(key); exists {
+			t.Errorf("Expected %s to be rolled back", key)
+		}
+	}
+	if stats := cache.GetStats(); stats.TotalEntries != 2 {
+		t.Errorf("Expected 2 entries after rollback, got %d", stats.TotalEntries)
+	}
+	stop()
+	if len(flushed) != 0 {
+		t.Errorf("Expected nothing dirty after rollback, flushed %v", flushed)
+	}
+	if removed := cache.InvalidateTag("group"); removed != 1 {
+		t.Errorf("Expected restored entry to keep its tags, removed %d", removed)
+	}
+}
+
+func TestSetTransactionTooLarge(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMemoryBytes = 1024
+	cache := New(config)
+	defer cache.Close()
+
+	items := map[string]interface{}{
+		"small": "value",
+		"large": make([]byte, 2048),
+	}
+	if err := cache.SetTransaction(items); !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("Expected ErrMemoryLimitExceeded, got %v", err)
+	}
+	if _, exists := cache.Get("small"); exists {
+		t.Error("No items should be written when the batch does not fit")
+	}
+}
+
+func TestMultiUpdateTransfers(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 8
+	cache := New(config)
+	defer cache.Close()
+
+	const accounts, initial = 16, 100
+	keys := make([]string, accounts)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("account_%d", i)
+		if err := cache.Set(keys[i], initial); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	errInsufficient := errors.New("insufficient funds")
+	transfer := func(from, to string, amount int) error {
+		return cache.MultiUpdate([]string{from, to}, func(vals map[string]interface{}) (map[string]interface{}, error) {
+			balance := vals[from].(int)
+			if balance < amount {
+				return nil, errInsufficient
+			}
+			return map[string]interface{}{from: balance - amount, to: vals[to].(int) + amount}, nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				// Opposite directions between the same accounts would deadlock
+				// without a canonical lock order
+				from, to := keys[(g+i)%accounts], keys[(g*7+i*3+1)%accounts]
+				if from == to {
+					continue
+				}
+				if err := transfer(from, to, 1+i%5); err != nil && !errors.Is(err, errInsufficient) {
+					t.Errorf("MultiUpdate failed: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, key := range keys {
+		value, _ := cache.Get(key)
+		if value.(int) < 0 {
+			t.Errorf("Expected %s not to go negative, got %d", key, value)
+		}
+		total += value.(int)
+	}
+	if total != accounts*initial {
+		t.Errorf("Expected total %d to be conserved, got %d", accounts*initial, total)
+	}
+}
+
+func TestMultiUpdateErrors(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	_ = cache.Set("a", 1)
+	err := cache.MultiUpdate([]string{"a", "b", "a"}, func(vals map[string]interface{}) (map[string]interface{}, error) {
+		if len(vals) != 1 || vals["a"] != 1 {
+			t.Errorf("Expected only the live key, got %v", vals)
+		}
+		return map[string]interface{}{"a": 2, "c": 3}, nil
+	})
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for a key outside the update, got %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = cache.MultiUpdate([]string{"a", "b"}, func(map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"a": 2, "b": 2}, errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Expected fn's error, got %v", err)
+	}
+	if value, _ := cache.Get("a"); value != 1 {
+		t.Errorf("Expected a unchanged after failed updates, got %v", value)
+	}
+	if _, exists := cache.Get("b"); exists {
+		t.Error("Expected b not to be created by a failed update")
+	}
+
+	if err := cache.MultiUpdate([]string{"a", "b"}, func(vals map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"b": vals["a"]}, nil
+	}); err != nil {
+		t.Fatalf("MultiUpdate failed: %v", err)
+	}
+	if value, _ := cache.Get("b"); value != 1 {
+		t.Errorf("Expected b to be created, got %v", value)
+	}
+}
@@ -0,0 +1,132 @@
+package fastcache
+
+import (
+	"container/list"
+	"fmt"
+	"sync/atomic"
+)
+
+// Verify checks the cache's internal invariants and returns every
+// discrepancy found, or nil if the cache is consistent. For each shard it
+// checks that entry sizes sum to the shard size and that the recency lists,
+// or PolicyGDSF's priority queue, hold exactly the shard's unpinned entries;
+// across shards it checks that shard sizes sum to the total size. All shards
+// are locked while checking, so Verify is a diagnostic tool rather than
+// something to call on a hot path.
+// Shard-level discrepancies are ErrShardError values; all wrap ErrInconsistent.
+func (c *Cache) Verify() []error {
+	c.reshardMu.Lock()
+	defer c.reshardMu.Unlock()
+
+	shards := c.allShards()
+	for _, shard := range shards {
+		shard.mu.RLock()
+	}
+	defer func() {
+		for _, shard := range shards {
+			shard.mu.RUnlock()
+		}
+	}()
+
+	var errs []error
+	var shardTotal int64
+	for i, shard := range shards {
+		for _, err := range shard.verify() {
+			errs = append(errs, ErrShardError{ShardID: i, Err: err})
+		}
+		shardTotal += atomic.LoadInt64(&shard.size)
+	}
+
+	if total := atomic.LoadInt64(&c.totalSize); total != shardTotal {
+		errs = append(errs, fmt.Errorf("%w: total size is %d but shard sizes sum to %d", ErrInconsistent, total, shardTotal))
+	}
+	return errs
+}
+
+// verify checks a single shard's invariants. The caller must hold the
+// shard's lock.
+func (s *Shard) verify() []error {
+	var errs []error
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInconsistent}, args...)...))
+	}
+
+	var size int64
+	pinned := 0
+	for key, entry := range s.data {
+		if entry.key != key {
+			report("entry stored under %q has key %q", key, entry.key)
+		}
+		size += entry.size
+		if entry.pinned {
+			pinned++
+		}
+	}
+	if shardSize := atomic.LoadInt64(&s.size); shardSize != size {
+		report("shard size is %d but entry sizes sum to %d", shardSize, size)
+	}
+
+	if s.gdsf != nil {
+		queued := s.verifyQueue(report)
+		if want := len(s.data) - pinned; queued != want {
+			report("priority queue holds %d entries but the shard has %d unpinned entries", queued, want)
+		}
+		return errs
+	}
+	if s.lruList == nil {
+		return errs
+	}
+
+	listed := s.verifyList(s.lruList, false, report)
+	if s.probation != nil {
+		listed += s.verifyList(s.probation, true, report)
+	}
+	if want := len(s.data) - pinned; listed != want {
+		report("recency lists hold %d entries but the shard has %d unpinned entries", listed, want)
+	}
+	return errs
+}
+
+// verifyList checks that every node of l is a live, unpinned entry that
+// points back at the node, and returns the number of nodes
+func (s *Shard) verifyList(l *list.List, probation bool, report func(string, ...interface{})) int {
+	n := 0
+	for elem := l.Front(); elem != nil; elem = elem.Next() {
+		n++
+		entry, ok := elem.Value.(*Entry)
+		if !ok {
+			report("list node holds %T instead of an entry", elem.Value)
+			continue
+		}
+		if s.data[entry.key] != entry {
+			report("list node for %q is orphaned", entry.key)
+		}
+		if entry.listNode != elem {
+			report("entry %q does not point at its list node", entry.key)
+		}
+		if entry.pinned {
+			report("pinned entry %q is in a recency list", entry.key)
+		}
+		if entry.probation != probation {
+			report("entry %q has probation %v but is in the other list", entry.key, entry.probation)
+		}
+	}
+	return n
+}
+
+// verifyQueue checks that every entry in the GDSF priority queue is a live,
+// unpinned entry that knows its position, and returns the number of entries
+func (s *Shard) verifyQueue(report func(string, ...interface{})) int {
+	for i, entry := range s.gdsf.entries {
+		if s.data[entry.key] != entry {
+			report("queued entry %q is orphaned", entry.key)
+		}
+		if entry.heapIndex != i {
+			report("entry %q is at queue position %d but records %d", entry.key, i, entry.heapIndex)
+		}
+		if entry.pinned {
+			report("pinned entry %q is in the priority queue", entry.key)
+		}
+	}
+	return len(s.gdsf.entries)
+}
@@ -0,0 +1,74 @@
+package fastcache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyConsistent(t *testing.T) {
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyRandom, PolicyTinyLFU, PolicySizeAware, PolicyFIFO, PolicyGDSF} {
+		t.Run(policy.String(), func(t *testing.T) {
+			config := DefaultConfig()
+			config.MaxMemoryBytes = 16 * 1024
+			config.ShardCount = 8
+			config.EvictionPolicy = policy
+			cache := New(config)
+			defer cache.Close()
+
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("verify_key_%d", i)
+				_ = cache.Set(key, make([]byte, 64))
+				cache.Get(key)
+				if i%7 == 0 {
+					cache.Delete(key)
+				}
+			}
+			cache.Pin("verify_key_499")
+
+			if errs := cache.Verify(); len(errs) != 0 {
+				t.Errorf("Expected a consistent cache, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsCorruption(t *testing.T) {
+	config := DefaultConfig()
+	config.ShardCount = 4
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("verify_key_%d", i), i)
+	}
+
+	// Drift the size of the shard holding a known key
+	shardID := cache.ShardIndex("verify_key_0")
+	atomic.AddInt64(&cache.allShards()[shardID].size, 10)
+
+	errs := cache.Verify()
+	if len(errs) != 2 {
+		t.Fatalf("Expected shard and total size discrepancies, got %v", errs)
+	}
+	var shardErr ErrShardError
+	if !errors.As(errs[0], &shardErr) || shardErr.ShardID != shardID {
+		t.Errorf("Expected discrepancy in shard %d, got %v", shardID, errs[0])
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrInconsistent) {
+			t.Errorf("Expected %v to wrap ErrInconsistent", err)
+		}
+	}
+
+	// Orphan a list node
+	atomic.AddInt64(&cache.allShards()[shardID].size, -10)
+	shard := cache.allShards()[shardID]
+	entry := shard.data["verify_key_0"]
+	delete(shard.data, "verify_key_0")
+	shard.data["verify_key_0"] = &Entry{key: entry.key, size: entry.size}
+	if errs := cache.Verify(); len(errs) == 0 {
+		t.Error("Expected orphaned list node to be reported")
+	}
+}
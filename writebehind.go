@@ -0,0 +1,124 @@
+package fastcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// nextDirty returns the write sequence number to mark a written entry dirty
+// with, or zero when no write-behind flusher is running
+func (c *Cache) nextDirty() uint64 {
+	if atomic.LoadInt32(&c.writeBehind) == 0 {
+		return 0
+	}
+	return atomic.AddUint64(&c.writeSeq, 1)
+}
+
+// dirtyEntry is an entry collected for a write-behind flush
+type dirtyEntry struct {
+	entry *Entry
+	seq   uint64
+}
+
+// StartWriteBehind marks every entry written from now on as dirty and calls
+// flush every interval with the live dirty entries, for write-behind
+// persistence to a slower store. Entries are marked clean when flush returns
+// nil; on error they stay dirty and are retried on the next interval. An entry
+// rewritten while its flush is in progress stays dirty, so the newer value is
+// flushed too. Deletes are not reported, and dirty entries that expire or are
+// evicted before being flushed are dropped.
+//
+// The returned stop function ends the flusher after a final flush and waits
+// for it; Close does the same. It is a no-op when interval is not positive,
+// flush is nil or the cache is already closed. Only one flusher should run at
+// a time, since concurrent flushers would each receive the same dirty entries.
+func (c *Cache) StartWriteBehind(interval time.Duration, flush func(entries []KeyValue) error) (stop func()) {
+	if interval <= 0 || flush == nil {
+		return func() {}
+	}
+
+	// Checking closed under startMu keeps Close from waiting on c.wg before
+	// the flusher is counted in it
+	c.startMu.Lock()
+	if atomic.LoadInt32(&c.closed) == 1 {
+		c.startMu.Unlock()
+		return func() {}
+	}
+	atomic.AddInt32(&c.writeBehind, 1)
+	c.wg.Add(1)
+	c.startMu.Unlock()
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer c.wg.Done()
+		defer close(exited)
+		defer atomic.AddInt32(&c.writeBehind, -1)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				c.flushDirty(flush)
+				return
+			case <-c.stopCh:
+				c.flushDirty(flush)
+				return
+			case <-ticker.C:
+				c.flushDirty(flush)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+// flushDirty passes the live dirty entries to flush and marks them clean if it
+// succeeds
+func (c *Cache) flushDirty(flush func(entries []KeyValue) error) {
+	var batch []KeyValue
+	var flushed []dirtyEntry
+	for _, shard := range c.allShards() {
+		now := c.now()
+		var dirty []dirtyEntry
+		var payloads []payload
+
+		shard.mu.RLock()
+		for _, entry := range shard.data {
+			if entry.dirty == 0 || entry.isExpired(now) {
+				continue
+			}
+			dirty = append(dirty, dirtyEntry{entry: entry, seq: entry.dirty})
+			payloads = append(payloads, entry.payload)
+		}
+		shard.mu.RUnlock()
+
+		// Decode outside the shard lock
+		for i, p := range payloads {
+			value, ok := c.loadValue(p.get())
+			if !ok {
+				continue
+			}
+			batch = append(batch, KeyValue{Key: dirty[i].entry.key, Value: value})
+			flushed = append(flushed, dirty[i])
+		}
+	}
+	if len(batch) == 0 || flush(batch) != nil {
+		return
+	}
+
+	for _, d := range flushed {
+		shard := c.lockShard(d.entry.key)
+		if shard.data[d.entry.key] == d.entry && d.entry.dirty == d.seq {
+			d.entry.dirty = 0
+		}
+		shard.mu.Unlock()
+	}
+}
@@ -0,0 +1,109 @@
+package fastcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartWriteBehind(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	// Written before write-behind starts, so never dirty
+	_ = cache.Set("clean", "c")
+
+	var mu sync.Mutex
+	var flushes [][]KeyValue
+	stop := cache.StartWriteBehind(time.Hour, func(entries []KeyValue) error {
+		mu.Lock()
+		defer mu.Unlock()
+		flushes = append(flushes, entries)
+		return nil
+	})
+
+	_ = cache.Set("user:1", "alice")
+	_ = cache.Set("user:2", "bob")
+
+	// A failed flush keeps the entries dirty for retry
+	cache.flushDirty(func(entries []KeyValue) error {
+		if len(entries) != 2 {
+			t.Errorf("Expected 2 dirty entries, got %v", entries)
+		}
+		return errors.New("store unavailable")
+	})
+
+	flushed := make(map[string]interface{})
+	cache.flushDirty(func(entries []KeyValue) error {
+		for _, kv := range entries {
+			flushed[kv.Key] = kv.Value
+		}
+		return nil
+	})
+	if len(flushed) != 2 || flushed["user:1"] != "alice" || flushed["user:2"] != "bob" {
+		t.Errorf("Expected both dirty entries to be retried, got %v", flushed)
+	}
+	if _, ok := flushed["clean"]; ok {
+		t.Error("Expected the clean entry not to be flushed")
+	}
+
+	// Flushed entries are clean until written again
+	_ = cache.Set("user:2", "bobby")
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushes) != 1 {
+		t.Fatalf("Expected one final flush on stop, got %d", len(flushes))
+	}
+	if entries := flushes[0]; len(entries) != 1 || entries[0].Key != "user:2" || entries[0].Value != "bobby" {
+		t.Errorf("Expected only the rewritten entry in the final flush, got %v", entries)
+	}
+}
+
+func TestWriteBehindInterval(t *testing.T) {
+	cache := New(DefaultConfig())
+	defer cache.Close()
+
+	flushed := make(chan []KeyValue, 10)
+	stop := cache.StartWriteBehind(10*time.Millisecond, func(entries []KeyValue) error {
+		flushed <- entries
+		return nil
+	})
+	defer stop()
+
+	_ = cache.Set("key", "value")
+
+	select {
+	case entries := <-flushed:
+		if len(entries) != 1 || entries[0].Key != "key" {
+			t.Errorf("Expected the written entry to be flushed, got %v", entries)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a flush within the interval")
+	}
+}
+
+func TestWriteBehindInvalidArgs(t *testing.T) {
+	cache := New(DefaultConfig())
+
+	// Neither may panic in the background goroutine
+	cache.StartWriteBehind(0, func([]KeyValue) error { return nil })()
+	cache.StartWriteBehind(-time.Second, func([]KeyValue) error { return nil })()
+	cache.StartWriteBehind(time.Millisecond, nil)()
+
+	_ = cache.Set("key", "value")
+	time.Sleep(10 * time.Millisecond)
+	cache.Close()
+
+	// Nothing is started on a closed cache
+	flushed := false
+	cache.StartWriteBehind(time.Millisecond, func([]KeyValue) error {
+		flushed = true
+		return nil
+	})()
+	if flushed {
+		t.Error("Expected no flush from a closed cache")
+	}
+}